@@ -0,0 +1,159 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"dbh-go-srv/internal/database"
+	"dbh-go-srv/internal/export"
+)
+
+/* =========================
+   Export Handler
+   ========================= */
+
+// handleExport serves GET /api/v1/jobs/{id}/export?format=m3u8|xspf|jspf|csv&include_unmatched=1.
+// Jobs are only exported to the DAB user that ran them.
+func handleExport(db *sql.DB, debugMode bool, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Access-Control-Allow-Headers", "X-DAB-Token")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	_, userID, err := authenticate(r, debugMode)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = export.FormatM3U8
+	}
+
+	contentType, ext, err := export.ContentType(format)
+	if err != nil {
+		http.Error(w, "Unsupported export format", http.StatusBadRequest)
+		return
+	}
+
+	includeUnmatched, _ := strconv.ParseBool(r.URL.Query().Get("include_unmatched"))
+
+	job, err := database.GetJob(db, r.PathValue("id"))
+	// Other users' jobs look the same as missing ones
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && job.UserID != userID) {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Job lookup failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", exportFilename(job, ext)))
+
+	if err := export.Render(w, format, jobPlaylist(job), export.Options{IncludeUnmatched: includeUnmatched}); err != nil {
+		log.Printf("job %s: export %s failed: %v", job.ID, format, err)
+	}
+}
+
+func jobPlaylist(job *database.Job) export.Playlist {
+	return export.Playlist{
+		Title:   job.SourceName,
+		Creator: "DBH-GO-SRV",
+		Date:    job.CreatedAt,
+		Tracks:  job.Results,
+		Columns: job.SourceColumns,
+	}
+}
+
+var unsafeFilenameChars = regexp.MustCompile(`[^\p{L}\p{N}._ -]+`)
+
+func exportFilename(job *database.Job, ext string) string {
+	name := strings.TrimSpace(unsafeFilenameChars.ReplaceAllString(job.SourceName, "_"))
+	if name == "" {
+		name = job.ID
+	}
+	return name + "." + ext
+}
+
+/* =========================
+   Export CLI
+   ========================= */
+
+// runExportCommand implements `srv export [-format f] [-o file] [-include-unmatched] <job-id>`.
+func runExportCommand(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", export.FormatM3U8, "output format: m3u8, xspf, jspf or csv")
+	output := fs.String("o", "", "output file (default stdout)")
+	includeUnmatched := fs.Bool("include-unmatched", false, "include unmatched tracks as comments")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: srv export [flags] <job-id>")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	if _, _, err := export.ContentType(*format); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	db, err := openDatabase()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer db.Close()
+
+	job, err := database.GetJob(db, fs.Arg(0))
+	if errors.Is(err, sql.ErrNoRows) {
+		fmt.Fprintf(os.Stderr, "job %s not found\n", fs.Arg(0))
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if err := export.Render(out, *format, jobPlaylist(job), export.Options{IncludeUnmatched: *includeUnmatched}); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
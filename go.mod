@@ -9,6 +9,7 @@ require (
 	github.com/mattn/go-sqlite3 v1.14.33
 	github.com/pquerna/otp v1.5.0
 	github.com/zmb3/spotify/v2 v2.4.3
+	golang.org/x/oauth2 v0.34.0
 	golang.org/x/time v0.14.0
 )
 
@@ -19,6 +20,5 @@ require (
 	github.com/dop251/goja v0.0.0-20250125213203-5ef83b82af17 // indirect
 	github.com/go-sourcemap/sourcemap v2.1.4+incompatible // indirect
 	github.com/google/pprof v0.0.0-20250208200701-d0013a598941 // indirect
-	golang.org/x/oauth2 v0.34.0 // indirect
 	golang.org/x/text v0.22.0 // indirect
 )
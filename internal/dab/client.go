@@ -41,6 +41,8 @@ type DabTrack struct {
 type Client struct {
 	HTTPClient    *http.Client
 	Limiter       *rate.Limiter
+	APIBase       string // DAB API root, DABAPIBase unless pointed at a stand-in server
+	QobuzAPIBase  string
 	Token         string
 	QobuzID       string
 	QobuzUserAuth string
@@ -68,6 +70,8 @@ func GetClient(token string, debugMode bool) *Client {
 	c := &Client{
 		HTTPClient:    &http.Client{Timeout: 30 * time.Second},
 		Limiter:       rate.NewLimiter(rate.Every(666*time.Millisecond), 1),
+		APIBase:       DABAPIBase,
+		QobuzAPIBase:  QobuzAPIBase,
 		Token:         token,
 		QobuzID:       qobuzID,
 		QobuzUserAuth: userAuth,
@@ -98,24 +102,38 @@ func (c *Client) Do(req *http.Request) (*http.Response, error) {
 	return c.HTTPClient.Do(req)
 }
 
-// Search: Qobuz first, DAB fallback
-func (c *Client) Search(query string) []DabTrack {
+// Search: Qobuz first, DAB fallback. The error is only set when both backends failed,
+// so a nil error with no tracks is a genuine miss.
+func (c *Client) Search(query string) ([]DabTrack, error) {
 	c.dbg("Search query=%q", query)
 
-	qTracks, err := c.searchQobuz(query)
-	if err == nil && len(qTracks) > 0 {
+	qTracks, qErr := c.searchQobuz(query)
+	if qErr == nil && len(qTracks) > 0 {
 		c.dbg("Qobuz hit: %d tracks", len(qTracks))
-		return qTracks
+		return qTracks, nil
 	}
 
 	c.dbg("Qobuz miss, falling back to DAB")
-	return c.searchDab(query)
+	dTracks, dErr := c.searchDab(query)
+	if dErr != nil && qErr != nil {
+		return nil, fmt.Errorf("qobuz: %v; dab: %w", qErr, dErr)
+	}
+	return dTracks, nil
+}
+
+// Pace is the minimum interval between rate-limited DAB requests.
+func (c *Client) Pace() time.Duration {
+	limit := c.Limiter.Limit()
+	if limit <= 0 || limit == rate.Inf {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / float64(limit))
 }
 
 func (c *Client) searchQobuz(query string) ([]DabTrack, error) {
 	searchURL := fmt.Sprintf(
 		"%s/track/search?query=%s&limit=5&app_id=%s&user_auth_token=%s",
-		QobuzAPIBase,
+		c.QobuzAPIBase,
 		url.QueryEscape(query),
 		c.QobuzID,
 		url.QueryEscape(c.QobuzUserAuth),
@@ -163,21 +181,21 @@ func (c *Client) searchQobuz(query string) ([]DabTrack, error) {
 	return out, nil
 }
 
-func (c *Client) searchDab(query string) []DabTrack {
-	searchURL := fmt.Sprintf("%s/search?q=%s&type=track", DABAPIBase, url.QueryEscape(query))
+func (c *Client) searchDab(query string) ([]DabTrack, error) {
+	searchURL := fmt.Sprintf("%s/search?q=%s&type=track", c.APIBase, url.QueryEscape(query))
 	c.dbg("DAB URL=%s", searchURL)
 
 	req, _ := http.NewRequest("GET", searchURL, nil)
 	resp, err := c.Do(req)
 	if err != nil {
 		c.dbg("DAB error: %v", err)
-		return nil
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		c.dbg("DAB status=%s", resp.Status)
-		return nil
+		return nil, fmt.Errorf("dab status %s", resp.Status)
 	}
 
 	var result struct {
@@ -185,14 +203,14 @@ func (c *Client) searchDab(query string) []DabTrack {
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		c.dbg("DAB decode error: %v", err)
-		return nil
+		return nil, err
 	}
 
-	return result.Tracks
+	return result.Tracks, nil
 }
 
 func (c *Client) ValidateToken() (string, error) {
-	req, _ := http.NewRequest("GET", DABAPIBase+"/auth/me", nil)
+	req, _ := http.NewRequest("GET", c.APIBase+"/auth/me", nil)
 	resp, err := c.Do(req)
 	if err != nil {
 		return "", err
@@ -0,0 +1,210 @@
+package dab
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"dbh-go-srv/internal/models"
+)
+
+// LibraryBatchSize is how many tracks AddTracks sends per request.
+const LibraryBatchSize = 25
+
+// Library is a user-owned DAB track collection.
+type Library struct {
+	ID          FlexID `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	IsPublic    bool   `json:"isPublic"`
+	TrackCount  int    `json:"trackCount,omitempty"`
+}
+
+// FlexID accepts both numeric and string IDs from the DAB API.
+type FlexID string
+
+func (id *FlexID) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if v == nil {
+		*id = ""
+		return nil
+	}
+	if f, ok := v.(float64); ok {
+		*id = FlexID(fmt.Sprintf("%.0f", f))
+		return nil
+	}
+	*id = FlexID(fmt.Sprintf("%v", v))
+	return nil
+}
+
+// LibraryAddResult reports the outcome of adding one track to a library.
+type LibraryAddResult struct {
+	TrackID int    `json:"dab_track_id"`
+	Title   string `json:"title"`
+	Artist  string `json:"artist"`
+	Error   string `json:"error,omitempty"`
+}
+
+// TrackFromResult returns the DAB track behind a FOUND match result. Registry hits
+// carry only the ID, so the track is rebuilt from the source metadata in that case.
+func TrackFromResult(res models.MatchResult) (DabTrack, bool) {
+	if res.MatchStatus != "FOUND" || res.DabTrackID == nil {
+		return DabTrack{}, false
+	}
+	if raw, ok := res.RawTrack.(*DabTrack); ok && raw != nil {
+		return *raw, true
+	}
+
+	id, err := strconv.Atoi(*res.DabTrackID)
+	if err != nil {
+		return DabTrack{}, false
+	}
+	return DabTrack{ID: id, Title: res.Title, Artist: res.Artist, AlbumTitle: res.Album}, true
+}
+
+// ListLibraries returns the libraries owned by the authenticated user.
+func (c *Client) ListLibraries() ([]Library, error) {
+	req, _ := http.NewRequest("GET", c.APIBase+"/libraries", nil)
+
+	var result struct {
+		Libraries []Library `json:"libraries"`
+	}
+	if err := c.doJSON(req, &result); err != nil {
+		return nil, fmt.Errorf("list libraries: %w", err)
+	}
+	return result.Libraries, nil
+}
+
+// CreateLibrary creates a new library and returns it.
+func (c *Client) CreateLibrary(name, description string, isPublic bool) (*Library, error) {
+	body, err := json.Marshal(map[string]any{
+		"name":        name,
+		"description": description,
+		"isPublic":    isPublic,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, _ := http.NewRequest("POST", c.APIBase+"/libraries", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	var result struct {
+		Library *Library `json:"library"`
+	}
+	if err := c.doJSON(req, &result); err != nil {
+		return nil, fmt.Errorf("create library: %w", err)
+	}
+	if result.Library == nil || result.Library.ID == "" {
+		return nil, fmt.Errorf("create library: response has no library id")
+	}
+	return result.Library, nil
+}
+
+// AddTracks adds tracks to a library in batches of LibraryBatchSize. When a batch
+// is rejected, its tracks are retried one by one so that failures are reported per
+// track. onResult, if non-nil, is called once for every track.
+func (c *Client) AddTracks(libraryID string, tracks []DabTrack, onResult func(LibraryAddResult)) (added int, failed int) {
+	report := func(t DabTrack, err error) {
+		if err != nil {
+			failed++
+		} else {
+			added++
+		}
+		if onResult != nil {
+			res := LibraryAddResult{TrackID: t.ID, Title: t.Title, Artist: t.Artist}
+			if err != nil {
+				res.Error = err.Error()
+			}
+			onResult(res)
+		}
+	}
+
+	for i := 0; i < len(tracks); i += LibraryBatchSize {
+		end := i + LibraryBatchSize
+		if end > len(tracks) {
+			end = len(tracks)
+		}
+		batch := tracks[i:end]
+
+		err := c.addTrackBatch(libraryID, batch)
+		if err == nil {
+			for _, t := range batch {
+				report(t, nil)
+			}
+			continue
+		}
+
+		c.dbg("library batch %d-%d rejected (%v), retrying per track", i, end, err)
+		for _, t := range batch {
+			report(t, c.AddTrack(libraryID, t))
+		}
+	}
+
+	return added, failed
+}
+
+// AddTrack adds a single track to a library.
+func (c *Client) AddTrack(libraryID string, t DabTrack) error {
+	body, err := json.Marshal(map[string]any{"track": t})
+	if err != nil {
+		return err
+	}
+
+	req, _ := http.NewRequest("POST", c.libraryTracksURL(libraryID), bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return c.doJSON(req, nil)
+}
+
+// RemoveTrack removes a single track from a library.
+func (c *Client) RemoveTrack(libraryID string, trackID int) error {
+	req, _ := http.NewRequest("DELETE", fmt.Sprintf("%s/%d", c.libraryTracksURL(libraryID), trackID), nil)
+	return c.doJSON(req, nil)
+}
+
+func (c *Client) addTrackBatch(libraryID string, batch []DabTrack) error {
+	body, err := json.Marshal(map[string]any{"tracks": batch})
+	if err != nil {
+		return err
+	}
+
+	req, _ := http.NewRequest("POST", c.libraryTracksURL(libraryID), bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return c.doJSON(req, nil)
+}
+
+func (c *Client) libraryTracksURL(libraryID string) string {
+	return fmt.Sprintf("%s/libraries/%s/tracks", c.APIBase, url.PathEscape(libraryID))
+}
+
+// doJSON sends req through Do, turns non-2xx responses into errors and decodes
+// the body into out when out is non-nil.
+func (c *Client) doJSON(req *http.Request, out any) error {
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+		c.dbg("DAB %s %s status=%s body=%q", req.Method, req.URL.Path, resp.Status, msg)
+		if text := strings.TrimSpace(string(msg)); text != "" {
+			return fmt.Errorf("dab status %s: %s", resp.Status, text)
+		}
+		return fmt.Errorf("dab status %s", resp.Status)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,193 @@
+package dab
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/time/rate"
+)
+
+// fakeDAB is a stand-in for the DAB library API. Tracks whose ID is in reject
+// make any request that contains them fail with 422.
+type fakeDAB struct {
+	mu        sync.Mutex
+	libraries []map[string]any
+	tracks    map[string][]int
+	batches   []int // size of every batch request, in order
+	singles   []int // track ID of every single-track request, in order
+	reject    map[int]bool
+}
+
+func newFakeDAB(t *testing.T) (*fakeDAB, *Client) {
+	t.Helper()
+	f := &fakeDAB{tracks: make(map[string][]int), reject: make(map[int]bool)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /libraries", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]any{"libraries": f.libraries})
+	})
+	mux.HandleFunc("POST /libraries", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Name     string `json:"name"`
+			IsPublic bool   `json:"isPublic"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Name == "" {
+			http.Error(w, "name is required", http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		// DAB returns numeric IDs; FlexID has to cope
+		lib := map[string]any{"id": 100 + len(f.libraries), "name": body.Name, "isPublic": body.IsPublic}
+		f.libraries = append(f.libraries, lib)
+		json.NewEncoder(w).Encode(map[string]any{"library": lib})
+	})
+	mux.HandleFunc("POST /libraries/{id}/tracks", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Track  *DabTrack  `json:"track"`
+			Tracks []DabTrack `json:"tracks"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "bad body", http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		batch := body.Tracks
+		if body.Track != nil {
+			batch = []DabTrack{*body.Track}
+			f.singles = append(f.singles, body.Track.ID)
+		} else {
+			f.batches = append(f.batches, len(batch))
+		}
+		for _, tr := range batch {
+			if f.reject[tr.ID] {
+				http.Error(w, "track not available", http.StatusUnprocessableEntity)
+				return
+			}
+		}
+		id := r.PathValue("id")
+		for _, tr := range batch {
+			f.tracks[id] = append(f.tracks[id], tr.ID)
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	return f, &Client{
+		HTTPClient: srv.Client(),
+		Limiter:    rate.NewLimiter(rate.Inf, 1),
+		APIBase:    srv.URL,
+		Token:      "test-token",
+	}
+}
+
+func testTracks(n int) []DabTrack {
+	tracks := make([]DabTrack, n)
+	for i := range tracks {
+		tracks[i] = DabTrack{ID: i + 1, Title: "Song", Artist: "Artist"}
+	}
+	return tracks
+}
+
+func TestCreateAndListLibraries(t *testing.T) {
+	_, c := newFakeDAB(t)
+
+	lib, err := c.CreateLibrary("My Imports", "", false)
+	if err != nil {
+		t.Fatalf("CreateLibrary: %v", err)
+	}
+	if lib.ID != "100" || lib.Name != "My Imports" {
+		t.Errorf("CreateLibrary = %+v, want ID 100 named My Imports", lib)
+	}
+
+	libs, err := c.ListLibraries()
+	if err != nil {
+		t.Fatalf("ListLibraries: %v", err)
+	}
+	if len(libs) != 1 || libs[0].ID != lib.ID {
+		t.Errorf("ListLibraries = %+v, want the created library", libs)
+	}
+}
+
+func TestCreateLibraryError(t *testing.T) {
+	_, c := newFakeDAB(t)
+
+	_, err := c.CreateLibrary("", "", false)
+	if err == nil || !strings.Contains(err.Error(), "name is required") {
+		t.Errorf("CreateLibrary with no name: err = %v, want the server's message", err)
+	}
+}
+
+func TestAddTracksBatches(t *testing.T) {
+	f, c := newFakeDAB(t)
+
+	var reported int
+	added, failed := c.AddTracks("100", testTracks(60), func(LibraryAddResult) { reported++ })
+	if added != 60 || failed != 0 || reported != 60 {
+		t.Errorf("AddTracks = %d added, %d failed, %d reported; want 60, 0, 60", added, failed, reported)
+	}
+	if want := []int{25, 25, 10}; !slices.Equal(f.batches, want) {
+		t.Errorf("batch sizes = %v, want %v", f.batches, want)
+	}
+	if len(f.singles) != 0 {
+		t.Errorf("single-track requests = %v, want none", f.singles)
+	}
+	if len(f.tracks["100"]) != 60 {
+		t.Errorf("library holds %d tracks, want 60", len(f.tracks["100"]))
+	}
+}
+
+func TestAddTracksRetriesRejectedBatch(t *testing.T) {
+	f, c := newFakeDAB(t)
+	f.reject[7] = true
+
+	var results []LibraryAddResult
+	added, failed := c.AddTracks("100", testTracks(30), func(r LibraryAddResult) { results = append(results, r) })
+	if added != 29 || failed != 1 {
+		t.Errorf("AddTracks = %d added, %d failed; want 29, 1", added, failed)
+	}
+
+	// The first batch fails as a whole and is retried track by track; the
+	// second goes through in one request
+	if want := []int{25, 5}; !slices.Equal(f.batches, want) {
+		t.Errorf("batch sizes = %v, want %v", f.batches, want)
+	}
+	if len(f.singles) != 25 {
+		t.Errorf("%d single-track retries, want 25", len(f.singles))
+	}
+
+	if len(results) != 30 {
+		t.Fatalf("%d results reported, want 30", len(results))
+	}
+	for i, r := range results {
+		if r.TrackID != i+1 {
+			t.Errorf("result %d is track %d; results should keep the input order", i, r.TrackID)
+		}
+		if wantErr := r.TrackID == 7; (r.Error != "") != wantErr {
+			t.Errorf("track %d: error = %q", r.TrackID, r.Error)
+		}
+	}
+	if !strings.Contains(results[6].Error, "track not available") {
+		t.Errorf("track 7 error = %q, want the server's message", results[6].Error)
+	}
+	if len(f.tracks["100"]) != 29 {
+		t.Errorf("library holds %d tracks, want 29", len(f.tracks["100"]))
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	_ "embed"
 	"fmt"
+	"strings"
+
 	_ "github.com/mattn/go-sqlite3"
 )
 
@@ -11,10 +13,31 @@ import (
 var schema string
 
 type TrackMapping struct {
-	DabID     string
-	ISRC      string
-	SpotifyID string
-	YoutubeID string
+	DabID        string
+	ISRC         string
+	SpotifyID    string
+	YoutubeID    string
+	DeezerID     string
+	TidalID      string
+	SoundcloudID string
+	BandcampID   string
+}
+
+// column is a column added to a table after the table's first release.
+type column struct{ name, def string }
+
+// registryColumns are the platform ID columns added to track_registry after
+// its first release. Older databases get them, and their index, on startup.
+var registryColumns = []string{"deezer_id", "tidal_id", "soundcloud_id", "bandcamp_id"}
+
+// laterColumns are the other columns added to existing tables, by table.
+var laterColumns = map[string][]column{
+	"conversion_jobs": {{"source_columns", "TEXT"}},
+	"playlist_syncs": {
+		{"last_attempt_at", "DATETIME"},
+		{"failed_runs", "INTEGER NOT NULL DEFAULT 0"},
+	},
+	"playlist_sync_tracks": {{"pending_removal", "INTEGER NOT NULL DEFAULT 0"}},
 }
 
 // InitDatabase runs the embedded schema and sets performance PRAGMAs
@@ -24,32 +47,99 @@ func InitDatabase(db *sql.DB) error {
 	if err != nil {
 		return err
 	}
-	_, err = db.Exec(schema)
-	return err
+	if _, err = db.Exec(schema); err != nil {
+		return err
+	}
+	for table, cols := range laterColumns {
+		if err := addColumns(db, table, cols); err != nil {
+			return err
+		}
+	}
+	return addRegistryColumns(db)
+}
+
+func addRegistryColumns(db *sql.DB) error {
+	cols := make([]column, 0, len(registryColumns))
+	for _, name := range registryColumns {
+		cols = append(cols, column{name, "TEXT"})
+	}
+	if err := addColumns(db, "track_registry", cols); err != nil {
+		return err
+	}
+	for _, col := range registryColumns {
+		idx := fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s ON track_registry(%s) WHERE %s IS NOT NULL", strings.TrimSuffix(col, "_id"), col, col)
+		if _, err := db.Exec(idx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addColumns adds the columns a table created by an older schema lacks.
+func addColumns(db *sql.DB, table string, cols []column) error {
+	rows, err := db.Query("PRAGMA table_info(" + table + ")")
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var (
+			cid, notNull, pk int
+			name, typ        string
+			dflt             sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &pk); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, col := range cols {
+		if existing[col.name] {
+			continue
+		}
+		if _, err := db.Exec("ALTER TABLE " + table + " ADD COLUMN " + col.name + " " + col.def); err != nil {
+			return fmt.Errorf("add column %s.%s: %w", table, col.name, err)
+		}
+	}
+	return nil
 }
 
-// UpsertMapping inserts or updates the registry. 
+// UpsertMapping inserts or updates the registry.
 // It uses COALESCE to ensure we don't wipe out existing IDs from other platforms.
 func UpsertMapping(db *sql.DB, m TrackMapping) error {
-	if db == nil { return nil }
-	
+	if db == nil {
+		return nil
+	}
+
 	query := `
-	INSERT INTO track_registry (dab_id, isrc, spotify_id, youtube_id, last_updated)
-	VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+	INSERT INTO track_registry (dab_id, isrc, spotify_id, youtube_id, deezer_id, tidal_id, soundcloud_id, bandcamp_id, last_updated)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
 	ON CONFLICT(dab_id) DO UPDATE SET
 		isrc = COALESCE(NULLIF(excluded.isrc, ''), track_registry.isrc),
 		spotify_id = COALESCE(NULLIF(excluded.spotify_id, ''), track_registry.spotify_id),
 		youtube_id = COALESCE(NULLIF(excluded.youtube_id, ''), track_registry.youtube_id),
+		deezer_id = COALESCE(NULLIF(excluded.deezer_id, ''), track_registry.deezer_id),
+		tidal_id = COALESCE(NULLIF(excluded.tidal_id, ''), track_registry.tidal_id),
+		soundcloud_id = COALESCE(NULLIF(excluded.soundcloud_id, ''), track_registry.soundcloud_id),
+		bandcamp_id = COALESCE(NULLIF(excluded.bandcamp_id, ''), track_registry.bandcamp_id),
 		last_updated = CURRENT_TIMESTAMP;`
 
-	_, err := db.Exec(query, m.DabID, m.ISRC, m.SpotifyID, m.YoutubeID)
+	_, err := db.Exec(query, m.DabID, m.ISRC, m.SpotifyID, m.YoutubeID, m.DeezerID, m.TidalID, m.SoundcloudID, m.BandcampID)
 	return err
 }
 
 // GetDabIDFromSource looks up a DAB ID based on platform-specific IDs
 func GetDabIDFromSource(db *sql.DB, sourceType, sourceID string) (string, error) {
-	if db == nil || sourceID == "" { return "", fmt.Errorf("invalid lookup") }
-	
+	if db == nil || sourceID == "" {
+		return "", fmt.Errorf("invalid lookup")
+	}
+
 	var dabID string
 	var query string
 
@@ -58,6 +148,14 @@ func GetDabIDFromSource(db *sql.DB, sourceType, sourceID string) (string, error)
 		query = "SELECT dab_id FROM track_registry WHERE spotify_id = ?"
 	case "youtube":
 		query = "SELECT dab_id FROM track_registry WHERE youtube_id = ?"
+	case "deezer":
+		query = "SELECT dab_id FROM track_registry WHERE deezer_id = ?"
+	case "tidal":
+		query = "SELECT dab_id FROM track_registry WHERE tidal_id = ?"
+	case "soundcloud":
+		query = "SELECT dab_id FROM track_registry WHERE soundcloud_id = ?"
+	case "bandcamp":
+		query = "SELECT dab_id FROM track_registry WHERE bandcamp_id = ?"
 	case "isrc":
 		query = "SELECT dab_id FROM track_registry WHERE isrc = ?"
 	default:
@@ -67,3 +165,60 @@ func GetDabIDFromSource(db *sql.DB, sourceType, sourceID string) (string, error)
 	err := db.QueryRow(query, sourceID).Scan(&dabID)
 	return dabID, err
 }
+
+// GetCachedISRCs returns the cached ISRCs for the given Spotify track IDs.
+// IDs that have never been resolved are simply absent from the map.
+func GetCachedISRCs(db *sql.DB, spotifyIDs []string) (map[string]string, error) {
+	out := make(map[string]string)
+	if db == nil || len(spotifyIDs) == 0 {
+		return out, nil
+	}
+
+	stmt, err := db.Prepare("SELECT isrc FROM spotify_isrc_cache WHERE spotify_id = ?")
+	if err != nil {
+		return out, err
+	}
+	defer stmt.Close()
+
+	for _, id := range spotifyIDs {
+		var isrc string
+		err := stmt.QueryRow(id).Scan(&isrc)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return out, err
+		}
+		out[id] = isrc
+	}
+	return out, nil
+}
+
+// CacheISRCs stores resolved Spotify ID -> ISRC pairs.
+func CacheISRCs(db *sql.DB, isrcs map[string]string) error {
+	if db == nil || len(isrcs) == 0 {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+	INSERT INTO spotify_isrc_cache (spotify_id, isrc, fetched_at)
+	VALUES (?, ?, CURRENT_TIMESTAMP)
+	ON CONFLICT(spotify_id) DO UPDATE SET isrc = excluded.isrc, fetched_at = CURRENT_TIMESTAMP;`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for id, isrc := range isrcs {
+		if _, err := stmt.Exec(id, isrc); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
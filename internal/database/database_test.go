@@ -0,0 +1,32 @@
+package database
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+// openTestDB returns a fresh database with the current schema.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "registry.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := InitDatabase(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// withEncryptionKey sets a key for the test and clears it afterwards.
+func withEncryptionKey(t *testing.T, first byte) {
+	t.Helper()
+	key := make([]byte, 32)
+	key[0] = first
+	if err := SetEncryptionKey(key); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { tokenAEAD = nil })
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"dbh-go-srv/internal/models"
+)
+
+// Job is a finished conversion run as stored in conversion_jobs.
+type Job struct {
+	ID         string
+	UserID     string
+	SourceType string
+	SourceName string
+	Status     string
+	Results    []models.MatchResult
+	// SourceColumns is the header row of an uploaded file, which each
+	// result's SourceRow follows; empty for URL sources.
+	SourceColumns []string
+	CreatedAt     time.Time
+}
+
+// SaveJob inserts or replaces a conversion job together with its results.
+func SaveJob(db *sql.DB, j Job) error {
+	if db == nil {
+		return nil
+	}
+
+	results, err := json.Marshal(j.Results)
+	if err != nil {
+		return fmt.Errorf("marshal job results: %w", err)
+	}
+
+	var columns sql.NullString
+	if len(j.SourceColumns) > 0 {
+		b, err := json.Marshal(j.SourceColumns)
+		if err != nil {
+			return fmt.Errorf("marshal job columns: %w", err)
+		}
+		columns = sql.NullString{String: string(b), Valid: true}
+	}
+
+	query := `
+	INSERT INTO conversion_jobs (id, user_id, source_type, source_name, status, results, source_columns, created_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+	ON CONFLICT(id) DO UPDATE SET
+		status = excluded.status,
+		source_name = excluded.source_name,
+		results = excluded.results,
+		source_columns = excluded.source_columns;`
+
+	_, err = db.Exec(query, j.ID, j.UserID, j.SourceType, j.SourceName, j.Status, string(results), columns)
+	return err
+}
+
+// GetJob loads a conversion job by ID. It returns sql.ErrNoRows if the job does not exist.
+func GetJob(db *sql.DB, id string) (*Job, error) {
+	if db == nil || id == "" {
+		return nil, fmt.Errorf("invalid lookup")
+	}
+
+	var (
+		j                Job
+		results, columns sql.NullString
+	)
+	err := db.QueryRow(
+		"SELECT id, user_id, source_type, source_name, status, results, source_columns, created_at FROM conversion_jobs WHERE id = ?",
+		id,
+	).Scan(&j.ID, &j.UserID, &j.SourceType, &j.SourceName, &j.Status, &results, &columns, &j.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if results.Valid && results.String != "" {
+		if err := json.Unmarshal([]byte(results.String), &j.Results); err != nil {
+			return nil, fmt.Errorf("decode job results: %w", err)
+		}
+	}
+	if columns.Valid && columns.String != "" {
+		if err := json.Unmarshal([]byte(columns.String), &j.SourceColumns); err != nil {
+			return nil, fmt.Errorf("decode job columns: %w", err)
+		}
+	}
+
+	return &j, nil
+}
@@ -4,6 +4,10 @@ CREATE TABLE IF NOT EXISTS track_registry (
     isrc TEXT,
     spotify_id TEXT,
     youtube_id TEXT,
+    deezer_id TEXT,
+    tidal_id TEXT,
+    soundcloud_id TEXT,
+    bandcamp_id TEXT,
     last_updated DATETIME DEFAULT CURRENT_TIMESTAMP
 );
 
@@ -11,3 +15,71 @@ CREATE TABLE IF NOT EXISTS track_registry (
 CREATE INDEX IF NOT EXISTS idx_isrc ON track_registry(isrc) WHERE isrc IS NOT NULL;
 CREATE INDEX IF NOT EXISTS idx_spotify ON track_registry(spotify_id) WHERE spotify_id IS NOT NULL;
 CREATE INDEX IF NOT EXISTS idx_youtube ON track_registry(youtube_id) WHERE youtube_id IS NOT NULL;
+-- Indices on platform columns added later (deezer_id, tidal_id, ...) are created by
+-- InitDatabase once it has added the column to older databases
+
+-- Conversion jobs: the final results of every /convert run, kept so they can be exported later
+CREATE TABLE IF NOT EXISTS conversion_jobs (
+    id TEXT PRIMARY KEY,
+    user_id TEXT,
+    source_type TEXT,
+    source_name TEXT,
+    status TEXT NOT NULL,
+    results TEXT,
+    source_columns TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Playlist syncs: a source playlist linked to a DAB library and re-applied periodically
+CREATE TABLE IF NOT EXISTS playlist_syncs (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    dab_token TEXT NOT NULL,
+    source_type TEXT NOT NULL,
+    source_url TEXT NOT NULL,
+    source_name TEXT,
+    library_id TEXT,
+    library_name TEXT,
+    matching_mode TEXT,
+    interval_hours INTEGER NOT NULL DEFAULT 168,
+    last_synced_at DATETIME,
+    last_error TEXT,
+    last_attempt_at DATETIME,
+    failed_runs INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, source_url)
+);
+
+-- The track set seen on the last sync run, used to diff the next one
+CREATE TABLE IF NOT EXISTS playlist_sync_tracks (
+    sync_id TEXT NOT NULL,
+    track_key TEXT NOT NULL,
+    source_id TEXT,
+    title TEXT,
+    artist TEXT,
+    dab_track_id TEXT,
+    match_status TEXT,
+    in_library INTEGER NOT NULL DEFAULT 0,
+    pending_removal INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (sync_id, track_key)
+);
+
+-- Spotify track ID -> ISRC, filled in when spotifetch results are enriched via the Web API
+CREATE TABLE IF NOT EXISTS spotify_isrc_cache (
+    spotify_id TEXT PRIMARY KEY,
+    isrc TEXT NOT NULL,
+    fetched_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Spotify accounts linked through the OAuth authorization-code flow, keyed by DAB user
+CREATE TABLE IF NOT EXISTS spotify_user_tokens (
+    user_id TEXT PRIMARY KEY,
+    spotify_user_id TEXT,
+    display_name TEXT,
+    access_token TEXT NOT NULL,
+    refresh_token TEXT NOT NULL,
+    token_type TEXT,
+    expiry DATETIME,
+    scope TEXT,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,136 @@
+package database
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Stored tokens (the DAB tokens kept with syncs for scheduled runs and the
+// OAuth tokens of linked Spotify accounts) are sealed with AES-GCM once an
+// encryption key is set. Tokens stored without a key stay
+// readable as plaintext, and SealStoredTokens encrypts them later.
+
+const sealedPrefix = "enc:v1:"
+
+// tokenAEAD is set once at startup, before any request is served.
+var tokenAEAD cipher.AEAD
+
+// SetEncryptionKey enables sealing of stored tokens with a 32-byte AES key.
+func SetEncryptionKey(key []byte) error {
+	if len(key) != 32 {
+		return fmt.Errorf("encryption key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	tokenAEAD = gcm
+	return nil
+}
+
+func sealToken(plain string) (string, error) {
+	if tokenAEAD == nil || plain == "" {
+		return plain, nil
+	}
+	nonce := make([]byte, tokenAEAD.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := tokenAEAD.Seal(nonce, nonce, []byte(plain), nil)
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func openToken(stored string) (string, error) {
+	if !strings.HasPrefix(stored, sealedPrefix) {
+		return stored, nil
+	}
+	if tokenAEAD == nil {
+		return "", errors.New("token is encrypted but no encryption key is set")
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(stored, sealedPrefix))
+	if err != nil || len(raw) < tokenAEAD.NonceSize() {
+		return "", errors.New("stored token is malformed")
+	}
+	ns := tokenAEAD.NonceSize()
+	plain, err := tokenAEAD.Open(nil, raw[:ns], raw[ns:], nil)
+	if err != nil {
+		return "", errors.New("stored token does not decrypt with the encryption key")
+	}
+	return string(plain), nil
+}
+
+// ErrUnreadableToken is returned for a stored token that does not decrypt
+// with the current encryption key.
+var ErrUnreadableToken = errors.New("stored token cannot be decrypted")
+
+// sealedColumns are the token columns kept encrypted, by table, with the
+// table's key column first.
+var sealedColumns = []struct {
+	table, key string
+	columns    []string
+}{
+	{"playlist_syncs", "id", []string{"dab_token"}},
+	{"spotify_user_tokens", "user_id", []string{"access_token", "refresh_token"}},
+}
+
+// SealStoredTokens encrypts the stored tokens (sync DAB tokens and linked
+// Spotify accounts) still in plaintext and returns how many it sealed. It does
+// nothing without an encryption key.
+func SealStoredTokens(db *sql.DB) (int, error) {
+	if db == nil || tokenAEAD == nil {
+		return 0, nil
+	}
+	total := 0
+	for _, t := range sealedColumns {
+		for _, col := range t.columns {
+			n, err := sealColumn(db, t.table, t.key, col)
+			if err != nil {
+				return total, fmt.Errorf("%s.%s: %w", t.table, col, err)
+			}
+			total += n
+		}
+	}
+	return total, nil
+}
+
+func sealColumn(db *sql.DB, table, key, col string) (int, error) {
+	rows, err := db.Query(fmt.Sprintf("SELECT %s, %s FROM %s WHERE %s <> '' AND %s NOT LIKE ?", key, col, table, col, col), sealedPrefix+"%")
+	if err != nil {
+		return 0, err
+	}
+	plain := make(map[string]string)
+	for rows.Next() {
+		var id, token string
+		if err := rows.Scan(&id, &token); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		plain[id] = token
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	update := fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s = ?", table, col, key)
+	for id, token := range plain {
+		sealed, err := sealToken(token)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := db.Exec(update, sealed, id); err != nil {
+			return 0, err
+		}
+	}
+	return len(plain), nil
+}
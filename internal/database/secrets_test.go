@@ -0,0 +1,84 @@
+package database
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSpotifyTokensSealed(t *testing.T) {
+	db := openTestDB(t)
+	withEncryptionKey(t, 1)
+
+	err := SaveSpotifyToken(db, SpotifyToken{UserID: "u1", AccessToken: "access-1", RefreshToken: "refresh-1", Expiry: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var access, refresh string
+	db.QueryRow("SELECT access_token, refresh_token FROM spotify_user_tokens WHERE user_id = 'u1'").Scan(&access, &refresh)
+	if !strings.HasPrefix(access, sealedPrefix) || !strings.HasPrefix(refresh, sealedPrefix) {
+		t.Errorf("stored tokens %q, %q are not sealed", access, refresh)
+	}
+
+	// A refresh that returns no refresh token keeps the stored one
+	if err := SaveSpotifyToken(db, SpotifyToken{UserID: "u1", AccessToken: "access-2"}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := GetSpotifyToken(db, "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.AccessToken != "access-2" || got.RefreshToken != "refresh-1" {
+		t.Errorf("tokens = %q, %q; want access-2, refresh-1", got.AccessToken, got.RefreshToken)
+	}
+
+	withEncryptionKey(t, 2)
+	if _, err := GetSpotifyToken(db, "u1"); !errors.Is(err, ErrUnreadableToken) {
+		t.Errorf("with another key: err = %v, want ErrUnreadableToken", err)
+	}
+}
+
+func TestSealStoredTokens(t *testing.T) {
+	db := openTestDB(t)
+
+	// Stored before a key was configured
+	if err := SaveSpotifyToken(db, SpotifyToken{UserID: "u1", AccessToken: "access", RefreshToken: "refresh"}); err != nil {
+		t.Fatal(err)
+	}
+	id, err := UpsertSync(db, Sync{ID: "s1", UserID: "u1", DabToken: "dab", SourceType: "spotify", SourceURL: "https://open.spotify.com/playlist/x", IntervalHours: 168})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withEncryptionKey(t, 1)
+	n, err := SealStoredTokens(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("sealed %d tokens, want 3", n)
+	}
+	if n, _ := SealStoredTokens(db); n != 0 {
+		t.Errorf("second run sealed %d tokens, want 0", n)
+	}
+
+	var dab, access, refresh string
+	db.QueryRow("SELECT dab_token FROM playlist_syncs").Scan(&dab)
+	db.QueryRow("SELECT access_token, refresh_token FROM spotify_user_tokens").Scan(&access, &refresh)
+	for _, v := range []string{dab, access, refresh} {
+		if !strings.HasPrefix(v, sealedPrefix) {
+			t.Errorf("stored token %q is not sealed", v)
+		}
+	}
+
+	st, err := GetSync(db, id)
+	if err != nil || st.DabToken != "dab" {
+		t.Errorf("sync token = %q, %v; want dab", st.DabToken, err)
+	}
+	tok, err := GetSpotifyToken(db, "u1")
+	if err != nil || tok.AccessToken != "access" || tok.RefreshToken != "refresh" {
+		t.Errorf("spotify token = %+v, %v", tok, err)
+	}
+}
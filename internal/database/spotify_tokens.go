@@ -0,0 +1,105 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SpotifyToken is the OAuth token of a Spotify account linked to a DAB user.
+type SpotifyToken struct {
+	UserID        string
+	SpotifyUserID string
+	DisplayName   string
+	AccessToken   string
+	RefreshToken  string
+	TokenType     string
+	Expiry        time.Time
+	Scope         string
+	UpdatedAt     time.Time
+}
+
+// SaveSpotifyToken links a Spotify account to a DAB user, replacing any earlier
+// link. Empty profile fields and refresh tokens keep their stored values, since
+// token refreshes do not always return them. Both tokens are sealed when an
+// encryption key is set.
+func SaveSpotifyToken(db *sql.DB, t SpotifyToken) error {
+	if db == nil {
+		return fmt.Errorf("no database")
+	}
+
+	access, err := sealToken(t.AccessToken)
+	if err != nil {
+		return err
+	}
+	refresh, err := sealToken(t.RefreshToken)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO spotify_user_tokens
+		(user_id, spotify_user_id, display_name, access_token, refresh_token, token_type, expiry, scope, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+	ON CONFLICT(user_id) DO UPDATE SET
+		spotify_user_id = COALESCE(NULLIF(excluded.spotify_user_id, ''), spotify_user_tokens.spotify_user_id),
+		display_name = COALESCE(NULLIF(excluded.display_name, ''), spotify_user_tokens.display_name),
+		access_token = excluded.access_token,
+		refresh_token = COALESCE(NULLIF(excluded.refresh_token, ''), spotify_user_tokens.refresh_token),
+		token_type = excluded.token_type,
+		expiry = excluded.expiry,
+		scope = COALESCE(NULLIF(excluded.scope, ''), spotify_user_tokens.scope),
+		updated_at = CURRENT_TIMESTAMP;`
+
+	_, err = db.Exec(query, t.UserID, t.SpotifyUserID, t.DisplayName, access, refresh,
+		t.TokenType, t.Expiry.UTC(), t.Scope)
+	return err
+}
+
+// GetSpotifyToken loads the linked Spotify account of a DAB user. It returns
+// sql.ErrNoRows if the user has not linked one, and ErrUnreadableToken if the
+// stored tokens do not decrypt with the current key.
+func GetSpotifyToken(db *sql.DB, userID string) (*SpotifyToken, error) {
+	if db == nil || userID == "" {
+		return nil, fmt.Errorf("invalid lookup")
+	}
+
+	var (
+		t                                          SpotifyToken
+		spotifyUserID, displayName, tokType, scope sql.NullString
+		expiry                                     sql.NullTime
+	)
+	err := db.QueryRow(`
+	SELECT user_id, spotify_user_id, display_name, access_token, refresh_token, token_type, expiry, scope, updated_at
+	FROM spotify_user_tokens WHERE user_id = ?`, userID).Scan(
+		&t.UserID, &spotifyUserID, &displayName, &t.AccessToken, &t.RefreshToken, &tokType, &expiry, &scope, &t.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.AccessToken, err = openToken(t.AccessToken); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnreadableToken, err)
+	}
+	if t.RefreshToken, err = openToken(t.RefreshToken); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnreadableToken, err)
+	}
+
+	t.SpotifyUserID = spotifyUserID.String
+	t.DisplayName = displayName.String
+	t.TokenType = tokType.String
+	t.Scope = scope.String
+	if expiry.Valid {
+		t.Expiry = expiry.Time
+	}
+	return &t, nil
+}
+
+// DeleteSpotifyToken unlinks the Spotify account of a DAB user.
+func DeleteSpotifyToken(db *sql.DB, userID string) error {
+	if db == nil {
+		return nil
+	}
+	_, err := db.Exec("DELETE FROM spotify_user_tokens WHERE user_id = ?", userID)
+	return err
+}
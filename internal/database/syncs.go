@@ -0,0 +1,293 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// syncRetryBase is the wait after a first failed run; each further failure
+// doubles it, up to the sync's interval.
+const syncRetryBase = 15 * time.Minute
+
+// Sync is a source playlist linked to a DAB library.
+type Sync struct {
+	ID     string
+	UserID string
+	// DabToken is empty when the stored token cannot be decrypted.
+	DabToken      string
+	SourceType    string
+	SourceURL     string
+	SourceName    string
+	LibraryID     string
+	LibraryName   string
+	MatchingMode  string
+	IntervalHours int
+	LastSyncedAt  time.Time
+	LastError     string
+	// LastAttemptAt and FailedRuns track the runs that failed since the last
+	// successful one.
+	LastAttemptAt time.Time
+	FailedRuns    int
+	CreatedAt     time.Time
+}
+
+// NextRun is when the sync is due: an interval after the last successful run,
+// or sooner after a failed one. It is zero for a sync that never ran.
+func (s Sync) NextRun() time.Time {
+	interval := time.Duration(s.IntervalHours) * time.Hour
+	if s.FailedRuns > 0 && !s.LastAttemptAt.IsZero() {
+		wait := syncRetryBase << min(s.FailedRuns-1, 16)
+		return s.LastAttemptAt.Add(min(wait, interval))
+	}
+	if s.LastSyncedAt.IsZero() {
+		return time.Time{}
+	}
+	return s.LastSyncedAt.Add(interval)
+}
+
+// Due reports whether the sync should run again at now.
+func (s Sync) Due(now time.Time) bool {
+	return !now.Before(s.NextRun())
+}
+
+// SyncTrack is one source track as seen on the last sync run.
+type SyncTrack struct {
+	Key         string
+	SourceID    string
+	Title       string
+	Artist      string
+	DabTrackID  string
+	MatchStatus string
+	InLibrary   bool
+	// PendingRemoval marks a track dropped from the source whose removal from
+	// the library failed; the next run retries it.
+	PendingRemoval bool
+}
+
+const syncColumns = `id, user_id, dab_token, source_type, source_url, source_name, library_id, library_name,
+	matching_mode, interval_hours, last_synced_at, last_error, last_attempt_at, failed_runs, created_at`
+
+// UpsertSync registers a sync, or refreshes the token and settings of the existing
+// sync for the same user and source URL. It returns the stored sync ID.
+func UpsertSync(db *sql.DB, s Sync) (string, error) {
+	if db == nil {
+		return "", fmt.Errorf("no database")
+	}
+
+	query := `
+	INSERT INTO playlist_syncs (id, user_id, dab_token, source_type, source_url, library_name, matching_mode, interval_hours)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT(user_id, source_url) DO UPDATE SET
+		dab_token = excluded.dab_token,
+		source_type = excluded.source_type,
+		library_name = COALESCE(NULLIF(excluded.library_name, ''), playlist_syncs.library_name),
+		matching_mode = excluded.matching_mode,
+		interval_hours = excluded.interval_hours;`
+
+	token, err := sealToken(s.DabToken)
+	if err != nil {
+		return "", fmt.Errorf("seal token: %w", err)
+	}
+	if _, err := db.Exec(query, s.ID, s.UserID, token, s.SourceType, s.SourceURL, s.LibraryName, s.MatchingMode, s.IntervalHours); err != nil {
+		return "", err
+	}
+
+	var id string
+	err = db.QueryRow("SELECT id FROM playlist_syncs WHERE user_id = ? AND source_url = ?", s.UserID, s.SourceURL).Scan(&id)
+	return id, err
+}
+
+// GetSync loads a sync by ID. It returns sql.ErrNoRows if it does not exist.
+func GetSync(db *sql.DB, id string) (*Sync, error) {
+	if db == nil || id == "" {
+		return nil, fmt.Errorf("invalid lookup")
+	}
+	return scanSync(db.QueryRow("SELECT "+syncColumns+" FROM playlist_syncs WHERE id = ?", id))
+}
+
+// ListSyncs returns every sync owned by userID, or all syncs when userID is empty.
+func ListSyncs(db *sql.DB, userID string) ([]Sync, error) {
+	if db == nil {
+		return nil, nil
+	}
+
+	query := "SELECT " + syncColumns + " FROM playlist_syncs"
+	var args []any
+	if userID != "" {
+		query += " WHERE user_id = ?"
+		args = append(args, userID)
+	}
+	query += " ORDER BY created_at"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Sync
+	for rows.Next() {
+		s, err := scanSync(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *s)
+	}
+	return out, rows.Err()
+}
+
+// DeleteSync removes a sync and its remembered track set.
+func DeleteSync(db *sql.DB, id string) error {
+	if db == nil {
+		return nil
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM playlist_sync_tracks WHERE sync_id = ?", id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM playlist_syncs WHERE id = ?", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RecordSyncRun stores the outcome of a sync run. Only a successful run moves
+// last_synced_at; a failed one counts towards the retry backoff (see NextRun).
+func RecordSyncRun(db *sql.DB, id, sourceName, libraryID string, at time.Time, runErr error) error {
+	if db == nil {
+		return nil
+	}
+
+	if runErr != nil {
+		_, err := db.Exec(`
+		UPDATE playlist_syncs SET
+			source_name = COALESCE(NULLIF(?, ''), source_name),
+			library_id = COALESCE(NULLIF(?, ''), library_id),
+			last_attempt_at = ?,
+			last_error = ?,
+			failed_runs = failed_runs + 1
+		WHERE id = ?;`, sourceName, libraryID, at.UTC(), runErr.Error(), id)
+		return err
+	}
+
+	_, err := db.Exec(`
+	UPDATE playlist_syncs SET
+		source_name = COALESCE(NULLIF(?, ''), source_name),
+		library_id = COALESCE(NULLIF(?, ''), library_id),
+		last_synced_at = ?,
+		last_attempt_at = ?,
+		last_error = '',
+		failed_runs = 0
+	WHERE id = ?;`, sourceName, libraryID, at.UTC(), at.UTC(), id)
+	return err
+}
+
+// GetSyncTracks returns the remembered track set of a sync keyed by track key.
+func GetSyncTracks(db *sql.DB, syncID string) (map[string]SyncTrack, error) {
+	if db == nil {
+		return nil, nil
+	}
+
+	rows, err := db.Query(`
+	SELECT track_key, source_id, title, artist, dab_track_id, match_status, in_library, pending_removal
+	FROM playlist_sync_tracks WHERE sync_id = ?`, syncID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[string]SyncTrack)
+	for rows.Next() {
+		var (
+			t                                      SyncTrack
+			sourceID, title, artist, dabID, status sql.NullString
+		)
+		if err := rows.Scan(&t.Key, &sourceID, &title, &artist, &dabID, &status, &t.InLibrary, &t.PendingRemoval); err != nil {
+			return nil, err
+		}
+		t.SourceID = sourceID.String
+		t.Title = title.String
+		t.Artist = artist.String
+		t.DabTrackID = dabID.String
+		t.MatchStatus = status.String
+		out[t.Key] = t
+	}
+	return out, rows.Err()
+}
+
+// ReplaceSyncTracks swaps the remembered track set of a sync in one transaction.
+func ReplaceSyncTracks(db *sql.DB, syncID string, tracks []SyncTrack) error {
+	if db == nil {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM playlist_sync_tracks WHERE sync_id = ?", syncID); err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`
+	INSERT OR REPLACE INTO playlist_sync_tracks
+		(sync_id, track_key, source_id, title, artist, dab_track_id, match_status, in_library, pending_removal)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, t := range tracks {
+		if _, err := stmt.Exec(syncID, t.Key, t.SourceID, t.Title, t.Artist, t.DabTrackID, t.MatchStatus, t.InLibrary, t.PendingRemoval); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSync(row rowScanner) (*Sync, error) {
+	var (
+		s                                                   Sync
+		sourceName, libraryID, libraryName, mode, lastError sql.NullString
+		lastSynced, lastAttempt                             sql.NullTime
+	)
+	err := row.Scan(
+		&s.ID, &s.UserID, &s.DabToken, &s.SourceType, &s.SourceURL, &sourceName, &libraryID, &libraryName,
+		&mode, &s.IntervalHours, &lastSynced, &lastError, &lastAttempt, &s.FailedRuns, &s.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	s.SourceName = sourceName.String
+	s.LibraryID = libraryID.String
+	s.LibraryName = libraryName.String
+	s.MatchingMode = mode.String
+	s.LastError = lastError.String
+	if lastSynced.Valid {
+		s.LastSyncedAt = lastSynced.Time
+	}
+	if lastAttempt.Valid {
+		s.LastAttemptAt = lastAttempt.Time
+	}
+	// A token sealed under another key is unusable, but the sync can still be
+	// listed and deleted
+	if s.DabToken, err = openToken(s.DabToken); err != nil {
+		s.DabToken = ""
+	}
+	return &s, nil
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSyncNextRun(t *testing.T) {
+	synced := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	failed := synced.Add(30 * time.Hour)
+
+	tests := []struct {
+		name string
+		sync Sync
+		want time.Time
+	}{
+		{"never ran", Sync{IntervalHours: 24}, time.Time{}},
+		{"after a good run", Sync{IntervalHours: 24, LastSyncedAt: synced, LastAttemptAt: synced}, synced.Add(24 * time.Hour)},
+		// Failures back off from the last attempt: 15m, 30m, 1h, ...
+		{"one failure", Sync{IntervalHours: 24, LastSyncedAt: synced, LastAttemptAt: failed, FailedRuns: 1}, failed.Add(15 * time.Minute)},
+		{"two failures", Sync{IntervalHours: 24, LastSyncedAt: synced, LastAttemptAt: failed, FailedRuns: 2}, failed.Add(30 * time.Minute)},
+		{"four failures", Sync{IntervalHours: 24, LastAttemptAt: failed, FailedRuns: 4}, failed.Add(2 * time.Hour)},
+		// ...but never wait longer than the interval
+		{"capped at the interval", Sync{IntervalHours: 6, LastAttemptAt: failed, FailedRuns: 8}, failed.Add(6 * time.Hour)},
+		{"many failures", Sync{IntervalHours: 24, LastAttemptAt: failed, FailedRuns: 500}, failed.Add(24 * time.Hour)},
+	}
+	for _, tt := range tests {
+		if got := tt.sync.NextRun(); !got.Equal(tt.want) {
+			t.Errorf("%s: NextRun = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	s := Sync{IntervalHours: 24, LastAttemptAt: failed, FailedRuns: 1}
+	if s.Due(failed.Add(14*time.Minute)) || !s.Due(failed.Add(15*time.Minute)) {
+		t.Error("Due does not follow NextRun")
+	}
+}
+
+func TestRecordSyncRun(t *testing.T) {
+	db := openTestDB(t)
+	id, err := UpsertSync(db, Sync{ID: "sync-1", UserID: "u1", DabToken: "tok", SourceURL: "https://example.com/list", IntervalHours: 24})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i := range 2 {
+		if err := RecordSyncRun(db, id, "", "", at.Add(time.Duration(i)*time.Hour), errors.New("source is down")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s, err := GetSync(db, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.FailedRuns != 2 || s.LastError != "source is down" || !s.LastSyncedAt.IsZero() {
+		t.Errorf("after two failures: %+v", s)
+	}
+	if want := at.Add(time.Hour + 30*time.Minute); !s.NextRun().Equal(want) {
+		t.Errorf("NextRun = %v, want %v", s.NextRun(), want)
+	}
+
+	if err := RecordSyncRun(db, id, "Mix", "lib-1", at.Add(3*time.Hour), nil); err != nil {
+		t.Fatal(err)
+	}
+	if s, err = GetSync(db, id); err != nil {
+		t.Fatal(err)
+	}
+	if s.FailedRuns != 0 || s.LastError != "" || s.LibraryID != "lib-1" || !s.NextRun().Equal(at.Add(27*time.Hour)) {
+		t.Errorf("after a good run: %+v, next %v", s, s.NextRun())
+	}
+}
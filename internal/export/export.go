@@ -0,0 +1,114 @@
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"dbh-go-srv/internal/dab"
+	"dbh-go-srv/internal/models"
+)
+
+const (
+	FormatM3U8 = "m3u8"
+	FormatXSPF = "xspf"
+	FormatJSPF = "jspf"
+	FormatCSV  = "csv"
+)
+
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// Playlist is the input for every renderer: a converted job's results plus its header info.
+type Playlist struct {
+	Title   string
+	Creator string
+	Date    time.Time
+	Tracks  []models.MatchResult
+	// Columns is the header row of an uploaded file; when set, the CSV export
+	// mirrors it using each track's SourceRow.
+	Columns []string
+}
+
+type Options struct {
+	// IncludeUnmatched keeps NOT_FOUND tracks in the output, as comments where the format allows it.
+	IncludeUnmatched bool
+}
+
+// Render writes the playlist to w in the requested format.
+func Render(w io.Writer, format string, p Playlist, opts Options) error {
+	switch strings.ToLower(format) {
+	case FormatM3U8:
+		return renderM3U8(w, p, opts)
+	case FormatXSPF:
+		return renderXSPF(w, p, opts)
+	case FormatJSPF:
+		return renderJSPF(w, p, opts)
+	case FormatCSV:
+		return renderCSV(w, p, opts)
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+}
+
+// ContentType returns the MIME type and file extension for a format.
+func ContentType(format string) (string, string, error) {
+	switch strings.ToLower(format) {
+	case FormatM3U8:
+		return "audio/x-mpegurl; charset=utf-8", "m3u8", nil
+	case FormatXSPF:
+		return "application/xspf+xml", "xspf", nil
+	case FormatJSPF:
+		return "application/json", "jspf", nil
+	case FormatCSV:
+		return "text/csv; charset=utf-8", "csv", nil
+	default:
+		return "", "", fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+}
+
+// TrackLocation is the URI written for a matched DAB track.
+func TrackLocation(dabID string) string {
+	return "dab:track:" + dabID
+}
+
+func isMatched(res models.MatchResult) bool {
+	return res.MatchStatus == "FOUND" && res.DabTrackID != nil && *res.DabTrackID != ""
+}
+
+// durationSeconds reads the DAB track duration from RawTrack, or the source
+// duration for unmatched tracks. Results loaded back from the database carry
+// RawTrack as a generic map, so fall back to a JSON round trip.
+func durationSeconds(res models.MatchResult) int {
+	switch v := res.RawTrack.(type) {
+	case nil:
+		return res.DurationMs / 1000
+	case *dab.DabTrack:
+		return v.Duration
+	case dab.DabTrack:
+		return v.Duration
+	}
+
+	b, err := json.Marshal(res.RawTrack)
+	if err != nil {
+		return 0
+	}
+	var dt dab.DabTrack
+	if err := json.Unmarshal(b, &dt); err != nil {
+		return 0
+	}
+	return dt.Duration
+}
+
+func displayName(t models.Track) string {
+	switch {
+	case t.Artist != "" && t.Title != "":
+		return t.Artist + " - " + t.Title
+	case t.Title != "":
+		return t.Title
+	default:
+		return t.Artist
+	}
+}
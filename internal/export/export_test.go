@@ -0,0 +1,165 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"dbh-go-srv/internal/dab"
+	"dbh-go-srv/internal/models"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func dabID(id string) *string { return &id }
+
+// testPlaylist has titles that need escaping in every format, a matched track
+// as it comes back from the database (RawTrack a generic map), one fresh from
+// the matcher, one unmatched, and one matched without a known duration.
+func testPlaylist(t *testing.T) Playlist {
+	t.Helper()
+	var reloaded any
+	if err := json.Unmarshal([]byte(`{"id":101,"title":"Rock & Roll","duration":215}`), &reloaded); err != nil {
+		t.Fatal(err)
+	}
+
+	return Playlist{
+		Title:   `Rock & Roll <Classics> "Live"`,
+		Creator: "alice",
+		Date:    time.Date(2026, 5, 1, 12, 30, 0, 0, time.FixedZone("CEST", 2*60*60)),
+		Tracks: []models.MatchResult{
+			{
+				Track: models.Track{Title: "Rock & Roll", Artist: `Led "Zep" <Zeppelin>`, Album: "Led Zeppelin\nIV",
+					ISRC: "USAT29900609", SourceID: "row-1", Type: "csv", SourceRow: []string{"Rock & Roll", "Led Zeppelin", "x"}},
+				MatchStatus: "FOUND", DabTrackID: dabID("101"), RawTrack: reloaded, Confidence: 1,
+			},
+			{
+				Track:       models.Track{Title: "Kashmir", Artist: "Led Zeppelin", DurationMs: 999000, SourceRow: []string{"Kashmir", "Led Zeppelin"}},
+				MatchStatus: "FOUND", DabTrackID: dabID("102"), RawTrack: &dab.DabTrack{ID: 102, Duration: 508}, Confidence: 0.875,
+			},
+			{
+				Track:       models.Track{Title: "Lost -- Song", Artist: "Nobody", DurationMs: 200000, SourceRow: []string{"Lost -- Song", "Nobody", "y", "extra"}},
+				MatchStatus: "NOT_FOUND",
+			},
+			{
+				Track:       models.Track{Title: "Unknown Length", DurationMs: 0},
+				MatchStatus: "FOUND", DabTrackID: dabID("104"),
+			},
+		},
+	}
+}
+
+func TestRenderGolden(t *testing.T) {
+	tests := []struct {
+		golden  string
+		format  string
+		opts    Options
+		columns []string
+	}{
+		{"playlist.m3u8", FormatM3U8, Options{}, nil},
+		{"playlist_unmatched.m3u8", FormatM3U8, Options{IncludeUnmatched: true}, nil},
+		{"playlist.xspf", FormatXSPF, Options{}, nil},
+		{"playlist_unmatched.xspf", FormatXSPF, Options{IncludeUnmatched: true}, nil},
+		{"playlist.jspf", FormatJSPF, Options{}, nil},
+		{"playlist_unmatched.jspf", FormatJSPF, Options{IncludeUnmatched: true}, nil},
+		{"playlist.csv", FormatCSV, Options{}, nil},
+		// An uploaded file's header and rows are mirrored, padded or cut to the header
+		{"playlist_columns.csv", FormatCSV, Options{IncludeUnmatched: true}, []string{"Song", "Band", "Notes"}},
+	}
+	for _, tt := range tests {
+		p := testPlaylist(t)
+		p.Columns = tt.columns
+
+		var buf bytes.Buffer
+		if err := Render(&buf, tt.format, p, tt.opts); err != nil {
+			t.Errorf("%s: %v", tt.golden, err)
+			continue
+		}
+
+		path := filepath.Join("testdata", tt.golden)
+		if *update {
+			if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		want, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), want) {
+			t.Errorf("%s differs from the golden file:\n%s", tt.golden, buf.String())
+		}
+	}
+}
+
+func TestRenderOutputParses(t *testing.T) {
+	p := testPlaylist(t)
+	opts := Options{IncludeUnmatched: true}
+
+	var buf bytes.Buffer
+	if err := Render(&buf, FormatJSPF, p, opts); err != nil {
+		t.Fatal(err)
+	}
+	var doc jspfDocument
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("JSPF is not valid JSON: %v", err)
+	}
+	if doc.Playlist.Title != p.Title || len(doc.Playlist.Track) != 4 || doc.Playlist.Track[0].Creator != p.Tracks[0].Artist {
+		t.Errorf("JSPF round trip = %+v", doc.Playlist)
+	}
+
+	buf.Reset()
+	if err := Render(&buf, FormatXSPF, p, opts); err != nil {
+		t.Fatal(err)
+	}
+	var xdoc struct {
+		Title  string `xml:"title"`
+		Tracks []struct {
+			Creator string `xml:"creator"`
+		} `xml:"trackList>track"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &xdoc); err != nil {
+		t.Fatalf("XSPF is not valid XML: %v", err)
+	}
+	if xdoc.Title != p.Title || len(xdoc.Tracks) != 3 || xdoc.Tracks[0].Creator != p.Tracks[0].Artist {
+		t.Errorf("XSPF round trip = %+v", xdoc)
+	}
+}
+
+func TestDurationSeconds(t *testing.T) {
+	var reloaded any
+	if err := json.Unmarshal([]byte(`{"id":1,"duration":215}`), &reloaded); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		res  models.MatchResult
+		want int
+	}{
+		{"source duration", models.MatchResult{Track: models.Track{DurationMs: 200999}}, 200},
+		{"DAB track", models.MatchResult{RawTrack: &dab.DabTrack{Duration: 180}}, 180},
+		{"DAB track value", models.MatchResult{RawTrack: dab.DabTrack{Duration: 181}}, 181},
+		{"reloaded from the database", models.MatchResult{Track: models.Track{DurationMs: 999000}, RawTrack: reloaded}, 215},
+		{"unreadable", models.MatchResult{RawTrack: "not a track"}, 0},
+	}
+	for _, tt := range tests {
+		if got := durationSeconds(tt.res); got != tt.want {
+			t.Errorf("%s: durationSeconds = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRenderUnknownFormat(t *testing.T) {
+	if err := Render(&bytes.Buffer{}, "wpl", Playlist{}, Options{}); err == nil {
+		t.Error("Render accepted an unknown format")
+	}
+	if _, _, err := ContentType("wpl"); err == nil {
+		t.Error("ContentType accepted an unknown format")
+	}
+}
@@ -0,0 +1,256 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/* ---------- M3U8 ---------- */
+
+func renderM3U8(w io.Writer, p Playlist, opts Options) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "#EXTM3U")
+	if p.Title != "" {
+		fmt.Fprintf(bw, "#PLAYLIST:%s\n", oneLine(p.Title))
+	}
+
+	for _, res := range p.Tracks {
+		if !isMatched(res) {
+			if opts.IncludeUnmatched {
+				fmt.Fprintf(bw, "# %s: %s\n", res.MatchStatus, oneLine(displayName(res.Track)))
+			}
+			continue
+		}
+
+		duration := durationSeconds(res)
+		if duration == 0 {
+			duration = -1
+		}
+		fmt.Fprintf(bw, "#EXTINF:%d,%s\n", duration, oneLine(displayName(res.Track)))
+		if res.Album != "" {
+			fmt.Fprintf(bw, "#EXTALB:%s\n", oneLine(res.Album))
+		}
+		fmt.Fprintln(bw, TrackLocation(*res.DabTrackID))
+	}
+
+	return bw.Flush()
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+/* ---------- XSPF ---------- */
+
+type xspfPlaylist struct {
+	XMLName   xml.Name      `xml:"playlist"`
+	Version   string        `xml:"version,attr"`
+	Namespace string        `xml:"xmlns,attr"`
+	Title     string        `xml:"title,omitempty"`
+	Creator   string        `xml:"creator,omitempty"`
+	Date      string        `xml:"date,omitempty"`
+	TrackList xspfTrackList `xml:"trackList"`
+}
+
+type xspfTrack struct {
+	Location   string   `xml:"location,omitempty"`
+	Identifier []string `xml:"identifier,omitempty"`
+	Title      string   `xml:"title,omitempty"`
+	Creator    string   `xml:"creator,omitempty"`
+	Album      string   `xml:"album,omitempty"`
+	Duration   int      `xml:"duration,omitempty"`
+}
+
+// xspfEntry is either a matched track or, for unmatched tracks, a bare XML comment.
+type xspfEntry struct {
+	Comment string
+	Track   *xspfTrack
+}
+
+type xspfTrackList []xspfEntry
+
+func (l xspfTrackList) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, entry := range l {
+		var err error
+		if entry.Track != nil {
+			err = e.EncodeElement(entry.Track, xml.StartElement{Name: xml.Name{Local: "track"}})
+		} else {
+			err = e.EncodeToken(xml.Comment(entry.Comment))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+func renderXSPF(w io.Writer, p Playlist, opts Options) error {
+	doc := xspfPlaylist{
+		Version:   "1",
+		Namespace: "http://xspf.org/ns/0/",
+		Title:     p.Title,
+		Creator:   p.Creator,
+		Date:      formatDate(p.Date),
+	}
+
+	for _, res := range p.Tracks {
+		if !isMatched(res) {
+			if opts.IncludeUnmatched {
+				// "--" is not allowed inside XML comments
+				text := strings.ReplaceAll(displayName(res.Track), "--", "- -")
+				doc.TrackList = append(doc.TrackList, xspfEntry{Comment: fmt.Sprintf(" %s: %s ", res.MatchStatus, text)})
+			}
+			continue
+		}
+
+		track := &xspfTrack{
+			Location: TrackLocation(*res.DabTrackID),
+			Title:    res.Title,
+			Creator:  res.Artist,
+			Album:    res.Album,
+			Duration: durationSeconds(res) * 1000,
+		}
+		if res.ISRC != "" {
+			track.Identifier = append(track.Identifier, "isrc:"+res.ISRC)
+		}
+		doc.TrackList = append(doc.TrackList, xspfEntry{Track: track})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+/* ---------- JSPF ---------- */
+
+type jspfDocument struct {
+	Playlist jspfPlaylist `json:"playlist"`
+}
+
+type jspfPlaylist struct {
+	Title   string      `json:"title,omitempty"`
+	Creator string      `json:"creator,omitempty"`
+	Date    string      `json:"date,omitempty"`
+	Track   []jspfTrack `json:"track"`
+}
+
+type jspfTrack struct {
+	Location   []string `json:"location,omitempty"`
+	Identifier []string `json:"identifier,omitempty"`
+	Title      string   `json:"title,omitempty"`
+	Creator    string   `json:"creator,omitempty"`
+	Album      string   `json:"album,omitempty"`
+	Annotation string   `json:"annotation,omitempty"`
+	Duration   int      `json:"duration,omitempty"`
+}
+
+// JSON has no comments, so unmatched tracks are emitted without a location
+// and with their match status in the annotation.
+func renderJSPF(w io.Writer, p Playlist, opts Options) error {
+	doc := jspfDocument{Playlist: jspfPlaylist{
+		Title:   p.Title,
+		Creator: p.Creator,
+		Date:    formatDate(p.Date),
+		Track:   []jspfTrack{},
+	}}
+
+	for _, res := range p.Tracks {
+		matched := isMatched(res)
+		if !matched && !opts.IncludeUnmatched {
+			continue
+		}
+
+		t := jspfTrack{
+			Title:   res.Title,
+			Creator: res.Artist,
+			Album:   res.Album,
+		}
+		if res.ISRC != "" {
+			t.Identifier = append(t.Identifier, "isrc:"+res.ISRC)
+		}
+		if matched {
+			t.Location = []string{TrackLocation(*res.DabTrackID)}
+			t.Duration = durationSeconds(res) * 1000
+		} else {
+			t.Annotation = res.MatchStatus
+		}
+		doc.Playlist.Track = append(doc.Playlist.Track, t)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+/* ---------- CSV ---------- */
+
+// csvSourceHeader describes tracks from URL sources, which have no input columns.
+var csvSourceHeader = []string{"title", "artist", "album", "isrc", "source_id", "type"}
+
+var csvMatchHeader = []string{"dab_track_id", "match_status", "confidence"}
+
+// renderCSV writes the input columns of each track followed by its match. An
+// uploaded file's own header and rows are repeated as they were read.
+func renderCSV(w io.Writer, p Playlist, opts Options) error {
+	cw := csv.NewWriter(w)
+	header := csvSourceHeader
+	if len(p.Columns) > 0 {
+		header = p.Columns
+	}
+	if err := cw.Write(append(append([]string(nil), header...), csvMatchHeader...)); err != nil {
+		return err
+	}
+
+	for _, res := range p.Tracks {
+		matched := isMatched(res)
+		if !matched && !opts.IncludeUnmatched {
+			continue
+		}
+
+		dabID := ""
+		if res.DabTrackID != nil {
+			dabID = *res.DabTrackID
+		}
+
+		var row []string
+		if len(p.Columns) > 0 {
+			// Rows may be ragged; pad or cut them to the header
+			row = make([]string, len(p.Columns))
+			copy(row, res.SourceRow)
+		} else {
+			row = []string{res.Title, res.Artist, res.Album, res.ISRC, res.SourceID, res.Type}
+		}
+		row = append(row, dabID, res.MatchStatus, strconv.FormatFloat(res.Confidence, 'f', 4, 64))
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
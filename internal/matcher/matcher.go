@@ -1,10 +1,12 @@
 package matcher
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"log"
 	"strings"
-    "log"
+	"time"
 
 	"dbh-go-srv/internal/dab"
 	"dbh-go-srv/internal/database"
@@ -14,7 +16,20 @@ import (
 	"github.com/adrg/strutil/metrics"
 )
 
-func MatchTrack(db *sql.DB, client *dab.Client, t models.Track, mode string, debugMode bool) *models.MatchResult {
+// How a FOUND result was obtained, reported in MatchResult.MatchSource
+const (
+	SourceRegistry = "registry"
+	SourceISRC     = "isrc"
+	SourceFuzzy    = "fuzzy"
+)
+
+// How close a candidate's album title must be to the track's to count as the
+// same album
+const albumSimilarity = 0.90
+
+// MatchTrack finds the DAB track for t. ctx is the job's context; it bounds the
+// network lookups of the enrichment stages.
+func MatchTrack(ctx context.Context, db *sql.DB, client *dab.Client, t models.Track, mode string, debugMode bool) *models.MatchResult {
 	// 1. Check SQLite Registry first
 	if db != nil {
 		cachedID, err := database.GetDabIDFromSource(db, t.Type, t.SourceID)
@@ -23,6 +38,30 @@ func MatchTrack(db *sql.DB, client *dab.Client, t models.Track, mode string, deb
 				Track:       t,
 				MatchStatus: "FOUND",
 				DabTrackID:  &cachedID,
+				MatchSource: SourceRegistry,
+			}
+		}
+	}
+
+	// Everything below goes to the network; a cancelled job stops here
+	if err := ctx.Err(); err != nil {
+		return &models.MatchResult{Track: t, MatchStatus: "NOT_FOUND", Error: err.Error()}
+	}
+
+	// 2a. Sources that carry the MusicBrainz recording resolve its ISRC directly
+	if t.ISRC == "" && t.MBID != "" {
+		if mbISRC := GetISRCFromRecording(ctx, t.MBID); mbISRC != "" {
+			if debugMode {
+				log.Printf("[MATCH] musicbrainz recording sourceID=%s mbid=%s isrc=%q", t.SourceID, t.MBID, mbISRC)
+			}
+			t.ISRC = mbISRC
+			if cachedID, err := database.GetDabIDFromSource(db, "isrc", mbISRC); err == nil && cachedID != "" {
+				return &models.MatchResult{
+					Track:       t,
+					MatchStatus: "FOUND",
+					DabTrackID:  &cachedID,
+					MatchSource: SourceRegistry,
+				}
 			}
 		}
 	}
@@ -37,6 +76,34 @@ func MatchTrack(db *sql.DB, client *dab.Client, t models.Track, mode string, deb
 					Track:       t,
 					MatchStatus: "FOUND",
 					DabTrackID:  &cachedID,
+					MatchSource: SourceRegistry,
+				}
+			}
+		}
+	}
+
+	// 2b. Second stage: MusicBrainz misses much regional catalog, so try a
+	// validated Spotify search hit for its ISRC and album
+	if t.Type == "youtube" && t.ISRC == "" {
+		spotifyCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+		hit, ok := EnrichFromSpotify(spotifyCtx, t, debugMode)
+		cancel()
+		if ok {
+			if debugMode {
+				log.Printf("[MATCH] spotify enrichment sourceID=%s spotifyID=%s isrc=%q album=%q score=%.3f", t.SourceID, hit.SpotifyID, hit.ISRC, hit.Album, hit.Score)
+			}
+			if t.Album == "" {
+				t.Album = hit.Album
+			}
+			if hit.ISRC != "" {
+				t.ISRC = hit.ISRC
+				if cachedID, err := database.GetDabIDFromSource(db, "isrc", hit.ISRC); err == nil && cachedID != "" {
+					return &models.MatchResult{
+						Track:       t,
+						MatchStatus: "FOUND",
+						DabTrackID:  &cachedID,
+						MatchSource: SourceRegistry,
+					}
 				}
 			}
 		}
@@ -64,13 +131,17 @@ func MatchTrack(db *sql.DB, client *dab.Client, t models.Track, mode string, deb
 			useISRC,
 			queryType,
 			query,
-	)
+		)
 	}
 
-	results := client.Search(query)
+	results, err := client.Search(query)
 
 	if debugMode {
-		log.Printf("[MATCH] search results=%d for queryType=%s query=%q", len(results), queryType, query)
+		log.Printf("[MATCH] search results=%d for queryType=%s query=%q err=%v", len(results), queryType, query, err)
+	}
+
+	if err != nil {
+		return &models.MatchResult{Track: t, MatchStatus: "NOT_FOUND", Error: err.Error()}
 	}
 
 	if len(results) == 0 {
@@ -82,50 +153,68 @@ func MatchTrack(db *sql.DB, client *dab.Client, t models.Track, mode string, deb
 	var highestScore float64
 
 	target := strings.ToLower(t.Artist + " " + t.Title)
+	album := normalizeForSearch(t.Album)
+	var bestOnAlbum bool
 
 	for _, cand := range results {
 		candStr := strings.ToLower(cand.Artist + " " + cand.Title)
 		score := strutil.Similarity(target, candStr, metrics.NewJaroWinkler())
-		
+
 		threshold := 0.85
-		if mode == "strict" { threshold = 0.95 }
+		if mode == "strict" {
+			threshold = 0.95
+		}
 
 		// If we have an ISRC match, we treat it as a perfect 1.0
 		if useISRC {
-        	score = 1.0
-        }
+			score = 1.0
+		}
+		if score < threshold {
+			continue
+		}
 
-		if score >= threshold && score > highestScore {
+		// The same recording is often on a single, the album and compilations;
+		// a known album picks its own release over a closer-scoring other one
+		onAlbum := album != "" && strutil.Similarity(album, normalizeForSearch(cand.AlbumTitle), metrics.NewJaroWinkler()) >= albumSimilarity
+		if (onAlbum && !bestOnAlbum) || (onAlbum == bestOnAlbum && score > highestScore) {
 			highestScore = score
+			bestOnAlbum = onAlbum
 			copyCand := cand
 			bestMatch = &copyCand
 		}
 	}
 
 	if bestMatch != nil {
-	idStr := fmt.Sprintf("%d", bestMatch.ID)
+		idStr := fmt.Sprintf("%d", bestMatch.ID)
 		// 5. Update Registry Async for future speed
 		go database.UpsertMapping(db, database.TrackMapping{
-    		DabID:     idStr,
-    		ISRC:      t.ISRC,
-	    	SpotifyID: iif(t.Type == "spotify", t.SourceID, ""),
-	     	YoutubeID: iif(t.Type == "youtube", t.SourceID, ""),
-    	})
-
-    	return &models.MatchResult{
-	    	Track:       t,
-	    	MatchStatus: "FOUND",
-	    	DabTrackID:  &idStr,
-	    	RawTrack:    bestMatch,
-	    	Confidence:  highestScore,
-    	}
-    }
+			DabID:        idStr,
+			ISRC:         t.ISRC,
+			SpotifyID:    iif(t.Type == "spotify", t.SourceID, ""),
+			YoutubeID:    iif(t.Type == "youtube", t.SourceID, ""),
+			DeezerID:     iif(t.Type == "deezer", t.SourceID, ""),
+			TidalID:      iif(t.Type == "tidal", t.SourceID, ""),
+			SoundcloudID: iif(t.Type == "soundcloud", t.SourceID, ""),
+			BandcampID:   iif(t.Type == "bandcamp", t.SourceID, ""),
+		})
+
+		return &models.MatchResult{
+			Track:       t,
+			MatchStatus: "FOUND",
+			DabTrackID:  &idStr,
+			RawTrack:    bestMatch,
+			Confidence:  highestScore,
+			MatchSource: iif(useISRC, SourceISRC, SourceFuzzy),
+		}
+	}
 
 	return &models.MatchResult{Track: t, MatchStatus: "NOT_FOUND"}
 }
 
 func iif(condition bool, a, b string) string {
-	if condition { return a }
+	if condition {
+		return a
+	}
 	return b
 }
 
@@ -137,7 +226,8 @@ func isValidISRC(s string) bool {
 		switch {
 		case i < 2 && (r < 'A' || r > 'Z'):
 			return false
-		case i >= 2 && i < 5 && (r < 'A' || r > 'Z'):
+		case i >= 2 && i < 5 && (r < 'A' || r > 'Z') && (r < '0' || r > '9'):
+			// Registrant codes are alphanumeric (e.g. USUM7...)
 			return false
 		case i >= 5 && (r < '0' || r > '9'):
 			return false
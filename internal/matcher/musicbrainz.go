@@ -1,6 +1,7 @@
 package matcher
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -8,11 +9,12 @@ import (
 	"time"
 
 	"golang.org/x/time/rate"
-	"context"
 )
 
 var mbLimiter = rate.NewLimiter(rate.Every(time.Second), 1) // 1 req/s per MB guidelines
 
+const mbUserAgent = "DBH-GO-SRV-Matcher/1.0 (https://github.com/sherlockholmesat221b/dbh-go-srv; sherlockholmesat221b@proton.me)"
+
 // MusicBrainzResponse simplified for ISRC extraction
 type MusicBrainzResponse struct {
 	Recordings []struct {
@@ -31,7 +33,7 @@ func GetISRCFromMetadata(artist, title string) string {
 
 	req, _ := http.NewRequest("GET", searchURL, nil)
 	// MusicBrainz requires a descriptive User-Agent
-	req.Header.Set("User-Agent", "DBH-GO-SRV-Matcher/1.0 (https://github.com/sherlockholmesat221b/dbh-go-srv; sherlockholmesat221b@proton.me)")
+	req.Header.Set("User-Agent", mbUserAgent)
 
 	client := &http.Client{Timeout: 5 * time.Second}
 	resp, err := client.Do(req)
@@ -53,3 +55,43 @@ func GetISRCFromMetadata(artist, title string) string {
 	}
 	return ""
 }
+
+// GetISRCFromRecording looks up the ISRCs of a known MusicBrainz recording,
+// as carried by ListenBrainz and Last.fm tracks. A recording can have several
+// ISRCs; the first valid one is returned. A cancelled ctx gives up the turn at
+// the shared rate limiter, so an abandoned job does not hold up other jobs.
+func GetISRCFromRecording(ctx context.Context, mbid string) string {
+	if err := mbLimiter.Wait(ctx); err != nil {
+		return ""
+	}
+
+	lookupURL := fmt.Sprintf("https://musicbrainz.org/ws/2/recording/%s?inc=isrcs&fmt=json", url.PathEscape(mbid))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, lookupURL, nil)
+	if err != nil {
+		return ""
+	}
+	req.Header.Set("User-Agent", mbUserAgent)
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return ""
+	}
+
+	var rec struct {
+		ISRCs []string `json:"isrcs"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rec); err != nil {
+		return ""
+	}
+	for _, isrc := range rec.ISRCs {
+		if isValidISRC(isrc) {
+			return isrc
+		}
+	}
+	return ""
+}
@@ -0,0 +1,139 @@
+package matcher
+
+import (
+	"context"
+	"log"
+	"math"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"dbh-go-srv/internal/models"
+	"dbh-go-srv/internal/spotifetch"
+
+	"github.com/adrg/strutil"
+	"github.com/adrg/strutil/metrics"
+	"golang.org/x/time/rate"
+)
+
+// ISRCResolver returns the ISRC of a Spotify track, or "" when it has none.
+type ISRCResolver func(ctx context.Context, spotifyID string) (string, error)
+
+var spotifyISRCs atomic.Pointer[ISRCResolver]
+
+// SetSpotifyISRCResolver enables the Spotify enrichment stage for YouTube
+// tracks. Search results carry no ISRCs, so the stage needs a way to resolve
+// the ISRC of the track it picked; without one it is skipped.
+func SetSpotifyISRCResolver(r ISRCResolver) {
+	spotifyISRCs.Store(&r)
+}
+
+var spotifyLimiter = rate.NewLimiter(rate.Every(250*time.Millisecond), 1)
+
+// Bounds a Spotify search hit must meet to be adopted
+const (
+	spotifyTitleSimilarity  = 0.90
+	spotifyArtistSimilarity = 0.85
+	// Without durations to compare, the title must match almost exactly
+	spotifyTitleOnlySimilarity = 0.97
+	spotifyDurationTolerance   = 5 * time.Second
+	spotifySearchLimit         = 5
+)
+
+// SpotifyHit is the Spotify track adopted for a YouTube track.
+type SpotifyHit struct {
+	SpotifyID string
+	ISRC      string
+	Album     string
+	Score     float64
+}
+
+// EnrichFromSpotify searches Spotify for the track by its normalized artist and
+// title and returns the best hit whose title and artist are similar enough and,
+// when both durations are known, whose length is within a few seconds. ok is
+// false when nothing qualifies or the stage is not configured.
+func EnrichFromSpotify(ctx context.Context, t models.Track, debugMode bool) (hit SpotifyHit, ok bool) {
+	resolve := spotifyISRCs.Load()
+	if resolve == nil || t.Title == "" || t.Artist == "" {
+		return hit, false
+	}
+
+	artist, title := normalizeForSearch(t.Artist), normalizeForSearch(t.Title)
+	if err := spotifyLimiter.Wait(ctx); err != nil {
+		return hit, false
+	}
+	results, err := spotifetch.SearchSpotifyByType(ctx, artist+" "+title, "track", spotifySearchLimit, 0)
+	if err != nil {
+		if debugMode {
+			log.Printf("[MATCH] spotify search failed for %q %q: %v", artist, title, err)
+		}
+		return hit, false
+	}
+
+	var best *spotifetch.SearchResult
+	for i, r := range results {
+		score, accepted := scoreSpotifyResult(t, artist, title, r)
+		if debugMode {
+			log.Printf("[MATCH] spotify candidate %q by %q (%dms) score=%.3f accepted=%v", r.Name, r.Artists, r.Duration, score, accepted)
+		}
+		if accepted && score > hit.Score {
+			hit.Score = score
+			best = &results[i]
+		}
+	}
+	if best == nil {
+		return hit, false
+	}
+
+	isrc, err := (*resolve)(ctx, best.ID)
+	if err != nil && debugMode {
+		log.Printf("[MATCH] spotify isrc lookup failed for %s: %v", best.ID, err)
+	}
+	hit.SpotifyID = best.ID
+	hit.ISRC = strings.ToUpper(strings.TrimSpace(isrc))
+	hit.Album = best.AlbumName
+	return hit, true
+}
+
+// scoreSpotifyResult rates a search hit against the normalized artist and title.
+func scoreSpotifyResult(t models.Track, artist, title string, r spotifetch.SearchResult) (float64, bool) {
+	jw := metrics.NewJaroWinkler()
+	titleScore := strutil.Similarity(title, normalizeForSearch(r.Name), jw)
+
+	// Either side may list several artists; the best pairing counts
+	artistScore := 0.0
+	for _, a := range strings.Split(r.Artists, ",") {
+		for _, b := range strings.Split(artist, ",") {
+			if s := strutil.Similarity(normalizeForSearch(b), normalizeForSearch(a), jw); s > artistScore {
+				artistScore = s
+			}
+		}
+	}
+
+	score := (titleScore + artistScore) / 2
+	if artistScore < spotifyArtistSimilarity {
+		return score, false
+	}
+	if t.DurationMs <= 0 || r.Duration <= 0 {
+		return score, titleScore >= spotifyTitleOnlySimilarity
+	}
+	diff := time.Duration(math.Abs(float64(t.DurationMs-r.Duration))) * time.Millisecond
+	return score, titleScore >= spotifyTitleSimilarity && diff <= spotifyDurationTolerance
+}
+
+var (
+	bracketedRegex = regexp.MustCompile(`[(\[][^)\]]*[)\]]`)
+	featTailRegex  = regexp.MustCompile(`(?i)\s(?:feat\.?|ft\.?|featuring)\s.*$`)
+	punctRegex     = regexp.MustCompile(`[^\p{L}\p{N}\s,]+`)
+	spacesRegex    = regexp.MustCompile(`\s+`)
+)
+
+// normalizeForSearch lowercases s and drops bracketed parts, featured artists
+// and punctuation, so "Song (From \"Movie\") ft. X" searches as "song".
+func normalizeForSearch(s string) string {
+	s = bracketedRegex.ReplaceAllString(s, " ")
+	s = featTailRegex.ReplaceAllString(s, "")
+	s = punctRegex.ReplaceAllString(s, " ")
+	return strings.TrimSpace(spacesRegex.ReplaceAllString(strings.ToLower(s), " "))
+}
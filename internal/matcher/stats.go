@@ -0,0 +1,134 @@
+package matcher
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"dbh-go-srv/internal/models"
+)
+
+// Summary is the running tally of a conversion, streamed with every progress event
+// and sent once more, complete, with the final event.
+type Summary struct {
+	Total           int     `json:"total"`
+	Processed       int     `json:"processed"`
+	Found           int     `json:"found"`
+	NotFound        int     `json:"not_found"`
+	FromRegistry    int     `json:"from_registry"`
+	FromISRC        int     `json:"from_isrc"`
+	Fuzzy           int     `json:"fuzzy"`
+	Errors          int     `json:"errors"`
+	AvgConfidence   float64 `json:"avg_confidence"`
+	ElapsedSeconds  float64 `json:"elapsed_seconds"`
+	TracksPerSecond float64 `json:"tracks_per_second"`
+	ETASeconds      float64 `json:"eta_seconds"`
+}
+
+// Tracker accumulates a Summary as results come in.
+type Tracker struct {
+	mu      sync.Mutex
+	start   time.Time
+	pace    time.Duration
+	sum     Summary
+	confSum float64
+	scored  int
+}
+
+// NewTracker starts tracking a run of total tracks. pace is the minimum time one
+// match can take under the current rate limit (see dab.Client.Pace); the ETA never
+// assumes the remaining tracks can go faster than that.
+func NewTracker(total int, pace time.Duration) *Tracker {
+	return &Tracker{
+		start: time.Now(),
+		pace:  pace,
+		sum:   Summary{Total: total},
+	}
+}
+
+// Add records one result and returns the updated summary.
+func (t *Tracker) Add(res *models.MatchResult) Summary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sum.Processed++
+	if res.Error != "" {
+		t.sum.Errors++
+	}
+
+	if res.MatchStatus == "FOUND" {
+		t.sum.Found++
+		switch res.MatchSource {
+		case SourceRegistry:
+			t.sum.FromRegistry++
+		case SourceISRC:
+			t.sum.FromISRC++
+		case SourceFuzzy:
+			t.sum.Fuzzy++
+		}
+		// Registry hits carry no score, so they don't count towards the average
+		if res.Confidence > 0 {
+			t.confSum += res.Confidence
+			t.scored++
+		}
+	} else {
+		t.sum.NotFound++
+	}
+
+	return t.snapshot()
+}
+
+// SetTotal corrects the expected track count, e.g. once a streamed source turns
+// out to have fewer usable rows than its pre-count.
+func (t *Tracker) SetTotal(total int) {
+	t.mu.Lock()
+	t.sum.Total = total
+	t.mu.Unlock()
+}
+
+// Summary returns the current summary.
+func (t *Tracker) Summary() Summary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.snapshot()
+}
+
+func (t *Tracker) snapshot() Summary {
+	s := t.sum
+
+	if t.scored > 0 {
+		s.AvgConfidence = round(t.confSum/float64(t.scored), 4)
+	}
+
+	elapsed := time.Since(t.start).Seconds()
+	s.ElapsedSeconds = round(elapsed, 2)
+	if elapsed > 0 && s.Processed > 0 {
+		s.TracksPerSecond = round(float64(s.Processed)/elapsed, 3)
+	}
+
+	if remaining := s.Total - s.Processed; remaining > 0 {
+		perTrack := t.pace.Seconds()
+		if s.Processed > 0 {
+			perTrack = math.Max(perTrack, elapsed/float64(s.Processed))
+		}
+		s.ETASeconds = round(perTrack*float64(remaining), 1)
+	}
+
+	return s
+}
+
+// Summarize builds a summary from finished results, without timing information.
+func Summarize(results []models.MatchResult) Summary {
+	t := NewTracker(len(results), 0)
+	for i := range results {
+		t.Add(&results[i])
+	}
+	s := t.Summary()
+	s.ElapsedSeconds, s.TracksPerSecond = 0, 0
+	return s
+}
+
+func round(v float64, places int) float64 {
+	p := math.Pow(10, float64(places))
+	return math.Round(v*p) / p
+}
@@ -3,10 +3,19 @@ package models
 type Track struct {
 	Title      string  `json:"title"`
 	Artist     string  `json:"artist"`
+	Featuring  string  `json:"featuring,omitempty"` // featured artists, when the source lists them apart
 	Album      string  `json:"album,omitempty"`
 	ISRC       string  `json:"isrc,omitempty"`
+	MBID       string  `json:"mbid,omitempty"` // MusicBrainz recording ID, when the source knows it
 	SourceID   string  `json:"source_id"`
-	Type       string  `json:"type"` // "spotify" or "youtube"
+	DurationMs int     `json:"duration_ms,omitempty"`
+	// Type is the source: "spotify", "youtube", "apple_music", "deezer", "tidal",
+	// "soundcloud", "bandcamp", "listenbrainz" or "lastfm". CSV rows are
+	// "spotify" when they carry a Spotify track ID and empty otherwise.
+	Type       string  `json:"type"`
+	// SourceRow is the input record a file source read the track from, kept
+	// so exports can mirror the file's columns
+	SourceRow  []string `json:"source_row,omitempty"`
 }
 
 type MatchResult struct {
@@ -15,4 +24,6 @@ type MatchResult struct {
 	DabTrackID  *string     `json:"dab_track_id"`
 	RawTrack    interface{} `json:"raw_track"` // Exported for JSON streaming
     Confidence float64 `json:"confidence"`
+	MatchSource string  `json:"match_source,omitempty"` // "registry", "isrc" or "fuzzy"
+	Error       string  `json:"error,omitempty"`        // set when the search itself failed
 }
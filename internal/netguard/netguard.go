@@ -0,0 +1,79 @@
+// Package netguard keeps requests to user-supplied URLs (webhook callbacks,
+// scraped pages) away from the server's own network.
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+var ErrBlockedAddress = errors.New("address is not publicly routable")
+
+// Allowed reports whether ip may be contacted: anything but loopback, private,
+// link-local, multicast and unspecified addresses.
+func Allowed(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsValid() &&
+		!ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!ip.IsUnspecified() &&
+		!sharedAddressSpace.Contains(ip)
+}
+
+// 100.64.0.0/10 (carrier-grade NAT) is not covered by IsPrivate
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// CheckHost resolves host and fails if any of its addresses is not Allowed.
+func CheckHost(ctx context.Context, host string) error {
+	if ip, err := netip.ParseAddr(host); err == nil {
+		if !Allowed(ip) {
+			return fmt.Errorf("%s: %w", host, ErrBlockedAddress)
+		}
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("resolve %s: %w", host, err)
+	}
+	for _, ip := range addrs {
+		if !Allowed(ip) {
+			return fmt.Errorf("%s resolves to %s: %w", host, ip, ErrBlockedAddress)
+		}
+	}
+	return nil
+}
+
+// control runs after DNS resolution, on the address actually dialed, so a
+// host that resolved to a public address at validation time cannot be
+// rebound to an internal one.
+func control(network, address string, _ syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !Allowed(ap.Addr()) {
+		return fmt.Errorf("dial %s: %w", address, ErrBlockedAddress)
+	}
+	return nil
+}
+
+// NewClient returns an HTTP client that refuses to connect to addresses that
+// are not Allowed, including after redirects. Proxies from the environment
+// are not used, since they would be dialed instead of the target.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, Control: control}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
@@ -0,0 +1,277 @@
+package parser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"dbh-go-srv/internal/models"
+)
+
+// Apple Music pages are server-rendered with their data serialized into a
+// script tag; the tracks are read from there, so no developer token is needed.
+// Very long playlists can come back cut short: the page embeds only the first
+// part of the list and loads the rest through the token-protected API. Track
+// rows carry no ISRCs, so Apple tracks are always matched by text.
+
+var (
+	appleServerDataRegex = regexp.MustCompile(`(?s)<script[^>]*id="serialized-server-data"[^>]*>(.*?)</script>`)
+	appleTitleRegex      = regexp.MustCompile(`(?s)<title>(.*?)</title>`)
+	// The description meta tag counts the whole list ("... 2024. 250 Songs. ...")
+	appleSongCountRegex = regexp.MustCompile(`<meta[^>]+name="description"[^>]+content="[^"]*?\b(\d[\d,]*) Songs?\b`)
+)
+
+// AppleMusicSource imports public Apple Music playlist, album and song URLs.
+type AppleMusicSource struct{}
+
+func (AppleMusicSource) Name() string { return "apple_music" }
+
+func (AppleMusicSource) Detect(req Request) bool {
+	return hostMatches(req.URL, "music.apple.com")
+}
+
+func (s AppleMusicSource) Extract(ctx context.Context, req Request) (*Result, error) {
+	if !s.Detect(req) {
+		return nil, inputErrorf("Invalid Apple Music URL")
+	}
+	kind, songID, err := appleURLKind(req.URL)
+	if err != nil {
+		return nil, &InputError{err.Error()}
+	}
+
+	page, err := fetchApplePage(ctx, req.URL)
+	if err != nil {
+		return nil, err
+	}
+	tracks, name, err := parseApplePage(page, kind)
+	if err != nil {
+		return nil, err
+	}
+
+	// Song links are album pages narrowed to one track (album/...?i=<id>)
+	if songID != "" {
+		song, err := appleSong(tracks, songID)
+		if err != nil {
+			return nil, err
+		}
+		tracks, name, kind = []models.Track{song}, song.Title, "song"
+	}
+
+	res := &Result{Tracks: tracks, Name: name, Meta: map[string]any{"kind": kind}}
+	if songID == "" {
+		if w, ok := appleTruncated(page, len(tracks)); ok {
+			res.Warnings = append(res.Warnings, w)
+		}
+	}
+	return res, nil
+}
+
+// appleTruncated reports a list cut short: the page counts more songs than it
+// embeds. Pages without a count are taken as complete.
+func appleTruncated(page []byte, embedded int) (Warning, bool) {
+	m := appleSongCountRegex.FindSubmatch(page)
+	if m == nil {
+		return Warning{}, false
+	}
+	total, err := strconv.Atoi(strings.ReplaceAll(string(m[1]), ",", ""))
+	if err != nil || total <= embedded {
+		return Warning{}, false
+	}
+	return Warning{
+		Row:     embedded + 1,
+		Message: fmt.Sprintf("the page embeds only the first %d of %d songs; the rest were not imported", embedded, total),
+	}, true
+}
+
+// appleSong picks the linked song out of its album's tracks.
+func appleSong(tracks []models.Track, songID string) (models.Track, error) {
+	for _, t := range tracks {
+		if t.SourceID == songID {
+			return t, nil
+		}
+	}
+	return models.Track{}, fmt.Errorf("song %s not found on its album page", songID)
+}
+
+// appleURLKind reads the kind of page from an Apple Music URL path
+// (/{storefront}/{playlist|album|song}/{slug}/{id}). For song links, songID
+// is the track to keep.
+func appleURLKind(rawURL string) (kind, songID string, err error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", "", err
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for _, p := range parts {
+		switch p {
+		case "playlist":
+			return "playlist", "", nil
+		case "album":
+			return "album", u.Query().Get("i"), nil
+		case "song":
+			return "album", parts[len(parts)-1], nil
+		}
+	}
+	return "", "", errors.New("Apple Music URL must point to a playlist, album or song")
+}
+
+func fetchApplePage(ctx context.Context, pageURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0 Safari/537.36")
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch Apple Music page: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, &InputError{"Apple Music page not found; is the playlist public?"}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch Apple Music page: %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 32<<20))
+}
+
+// parseApplePage reads the tracks and the page name from the serialized
+// server data. Only the page's own track list is read: other shelves (an
+// artist's top songs, say) use the same lockups under a different section ID.
+func parseApplePage(page []byte, kind string) ([]models.Track, string, error) {
+	m := appleServerDataRegex.FindSubmatch(page)
+	if m == nil {
+		return nil, "", errors.New("Apple Music page has no embedded data")
+	}
+	var data any
+	if err := json.Unmarshal(m[1], &data); err != nil {
+		return nil, "", fmt.Errorf("parse Apple Music page data: %w", err)
+	}
+
+	name := ""
+	var items, otherItems []map[string]any
+	walkJSON(data, func(obj map[string]any) {
+		switch jsonStr(obj["itemKind"]) {
+		case "containerDetailHeaderLockup":
+			if list, _ := obj["items"].([]any); len(list) > 0 && name == "" {
+				if header, ok := list[0].(map[string]any); ok {
+					name = jsonStr(header["title"])
+				}
+			}
+		case "trackLockup":
+			list, _ := obj["items"].([]any)
+			for _, it := range list {
+				item, ok := it.(map[string]any)
+				if !ok {
+					continue
+				}
+				if strings.HasPrefix(jsonStr(obj["id"]), "track-list") {
+					items = append(items, item)
+				} else {
+					otherItems = append(otherItems, item)
+				}
+			}
+		}
+	})
+	// Pages without section IDs have nothing else to tell the list apart by
+	if len(items) == 0 {
+		items = otherItems
+	}
+	if name == "" {
+		if t := appleTitleRegex.FindSubmatch(page); t != nil {
+			name = strings.TrimSuffix(html.UnescapeString(strings.TrimSpace(string(t[1]))), " - Apple Music")
+		}
+	}
+
+	seen := make(map[string]bool)
+	var tracks []models.Track
+	for _, item := range items {
+		t, ok := appleTrack(item)
+		if !ok || seen[t.SourceID] {
+			continue
+		}
+		seen[t.SourceID] = true
+		if t.Album == "" && kind == "album" {
+			t.Album = name
+		}
+		tracks = append(tracks, t)
+	}
+	if len(tracks) == 0 {
+		return nil, name, errors.New("no tracks found on the Apple Music page")
+	}
+	return tracks, name, nil
+}
+
+// appleTrack converts one track lockup. Playlist rows name the album in their
+// tertiary links; album rows leave it to the page.
+func appleTrack(item map[string]any) (models.Track, bool) {
+	desc, _ := item["contentDescriptor"].(map[string]any)
+	if desc == nil || jsonStr(desc["kind"]) != "song" {
+		return models.Track{}, false
+	}
+	ids, _ := desc["identifiers"].(map[string]any)
+
+	t := models.Track{
+		Title:    jsonStr(item["title"]),
+		Artist:   jsonStr(item["artistName"]),
+		SourceID: jsonStr(ids["storeAdamID"]),
+		Type:     "apple_music",
+	}
+	if t.SourceID == "" {
+		t.SourceID = jsonStr(item["id"])
+	}
+	if ms, ok := item["duration"].(float64); ok && ms > 0 {
+		t.DurationMs = int(ms)
+	}
+	if links, _ := item["tertiaryLinks"].([]any); len(links) > 0 {
+		if link, ok := links[0].(map[string]any); ok {
+			t.Album = jsonStr(link["title"])
+		}
+	}
+	return t, t.Title != "" && t.SourceID != ""
+}
+
+// walkJSON calls fn for every object in a decoded JSON value, parents first.
+// Keys are visited in sorted order so the walk is deterministic.
+func walkJSON(v any, fn func(map[string]any)) {
+	switch x := v.(type) {
+	case map[string]any:
+		fn(x)
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			walkJSON(x[k], fn)
+		}
+	case []any:
+		for _, child := range x {
+			walkJSON(child, fn)
+		}
+	}
+}
+
+// jsonStr returns a JSON string or number as a string, and "" for anything else.
+func jsonStr(v any) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	}
+	return ""
+}
@@ -0,0 +1,143 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dbh-go-srv/internal/models"
+)
+
+func readApplePage(t *testing.T, name string) []byte {
+	t.Helper()
+	page, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return page
+}
+
+func TestAppleURLKind(t *testing.T) {
+	tests := []struct {
+		url          string
+		kind, songID string
+		wantErr      bool
+	}{
+		{url: "https://music.apple.com/us/playlist/todays-hits/pl.f4d106fed2bd41149aaacabb233eb5eb", kind: "playlist"},
+		{url: "https://music.apple.com/us/album/discovery/697194953", kind: "album"},
+		{url: "https://music.apple.com/us/album/blinding-lights/1499378108?i=1499378615", kind: "album", songID: "1499378615"},
+		{url: "https://music.apple.com/gb/song/blinding-lights/1499378615", kind: "album", songID: "1499378615"},
+		{url: "https://music.apple.com/us/artist/daft-punk/5468295", wantErr: true},
+	}
+	for _, tt := range tests {
+		kind, songID, err := appleURLKind(tt.url)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("appleURLKind(%q) err = %v, want error %v", tt.url, err, tt.wantErr)
+			continue
+		}
+		if kind != tt.kind || songID != tt.songID {
+			t.Errorf("appleURLKind(%q) = %q, %q; want %q, %q", tt.url, kind, songID, tt.kind, tt.songID)
+		}
+	}
+}
+
+func TestParseApplePlaylist(t *testing.T) {
+	tracks, name, err := parseApplePage(readApplePage(t, "applemusic_playlist.html"), "playlist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "Today's Hits" {
+		t.Errorf("name = %q", name)
+	}
+	// The music video and the repeated row are dropped, and the featured
+	// artists shelf is not part of the playlist
+	want := []models.Track{
+		{Title: "Espresso", Artist: "Sabrina Carpenter", Album: "Espresso - Single", SourceID: "1739659144", DurationMs: 175459, Type: "apple_music"},
+		{Title: "Houdini", Artist: "Dua Lipa", Album: "Houdini - Single", SourceID: "1724488123", DurationMs: 185917, Type: "apple_music"},
+		{Title: "Please Please Please", Artist: "Sabrina Carpenter", Album: "Please Please Please - Single", SourceID: "1741234567", DurationMs: 186365, Type: "apple_music"},
+	}
+	checkAppleTracks(t, tracks, want)
+}
+
+func TestParseAppleAlbum(t *testing.T) {
+	tracks, name, err := parseApplePage(readApplePage(t, "applemusic_album.html"), "album")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "Discovery" {
+		t.Errorf("name = %q", name)
+	}
+	// The artist's top songs shelf is left out, and the album fills in for the
+	// rows' missing album links
+	want := []models.Track{
+		{Title: "One More Time", Artist: "Daft Punk", Album: "Discovery", SourceID: "697195462", DurationMs: 320357, Type: "apple_music"},
+		{Title: "Aerodynamic", Artist: "Daft Punk", Album: "Discovery", SourceID: "697195463", DurationMs: 207000, Type: "apple_music"},
+		{Title: "Digital Love", Artist: "Daft Punk", Album: "Discovery", SourceID: "697195787", DurationMs: 301000, Type: "apple_music"},
+	}
+	checkAppleTracks(t, tracks, want)
+}
+
+func TestParseAppleSongLink(t *testing.T) {
+	kind, songID, err := appleURLKind("https://music.apple.com/us/album/blinding-lights/1499378108?i=1499378615")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracks, _, err := parseApplePage(readApplePage(t, "applemusic_song.html"), kind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	song, err := appleSong(tracks, songID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := models.Track{Title: "Blinding Lights", Artist: "The Weeknd", Album: "After Hours", SourceID: "1499378615", DurationMs: 200040, Type: "apple_music"}
+	checkAppleTracks(t, []models.Track{song}, []models.Track{want})
+
+	if _, err := appleSong(tracks, "1"); err == nil {
+		t.Error("appleSong found a song that is not on the album")
+	}
+}
+
+func TestAppleTruncated(t *testing.T) {
+	w, ok := appleTruncated(readApplePage(t, "applemusic_playlist.html"), 3)
+	if !ok || w.Row != 4 || !strings.Contains(w.Message, "first 3 of 100 songs") {
+		t.Errorf("playlist page: warning %+v, %v; want one for the 97 songs left out", w, ok)
+	}
+
+	tests := []struct {
+		meta     string
+		embedded int
+		want     bool
+	}{
+		{`<meta name="description" content="Listen to Big List on Apple Music. 1,250 Songs. Duration: 80 hours.">`, 300, true},
+		{`<meta name="description" content="Listen to Big List on Apple Music. 300 Songs.">`, 300, false},
+		{`<meta name="description" content="Album · 2020 · 1 Song">`, 1, false},
+		{`<meta property="og:title" content="No Count">`, 3, false},
+	}
+	for _, tt := range tests {
+		if _, ok := appleTruncated([]byte(tt.meta), tt.embedded); ok != tt.want {
+			t.Errorf("appleTruncated(%q, %d) = %v, want %v", tt.meta, tt.embedded, ok, tt.want)
+		}
+	}
+}
+
+func TestParseApplePageWithoutData(t *testing.T) {
+	if _, _, err := parseApplePage([]byte("<html><title>Apple Music</title></html>"), "album"); err == nil {
+		t.Error("parseApplePage accepted a page without embedded data")
+	}
+}
+
+func checkAppleTracks(t *testing.T, got, want []models.Track) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d tracks, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.Title != w.Title || g.Artist != w.Artist || g.Album != w.Album || g.ISRC != w.ISRC ||
+			g.SourceID != w.SourceID || g.DurationMs != w.DurationMs || g.Type != w.Type {
+			t.Errorf("track %d = %+v, want %+v", i, g, w)
+		}
+	}
+}
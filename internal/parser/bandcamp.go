@@ -0,0 +1,127 @@
+package parser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"dbh-go-srv/internal/models"
+	"dbh-go-srv/internal/netguard"
+)
+
+var bandcampTralbumRegex = regexp.MustCompile(`data-tralbum="([^"]*)"`)
+
+// BandcampSource imports public Bandcamp albums and tracks. Their pages carry
+// the release as JSON in a data-tralbum attribute.
+type BandcampSource struct {
+	// Hosts are the custom domains of Bandcamp artists the operator allows on
+	// top of bandcamp.com. Other hosts are never fetched.
+	Hosts []string
+}
+
+// NewBandcampSource returns a source that also accepts the comma-separated
+// custom domains, e.g. "music.example.com, example.org".
+func NewBandcampSource(customDomains string) BandcampSource {
+	var s BandcampSource
+	for _, h := range strings.Split(customDomains, ",") {
+		if h = strings.ToLower(strings.TrimSpace(h)); h != "" {
+			s.Hosts = append(s.Hosts, h)
+		}
+	}
+	return s
+}
+
+func (BandcampSource) Name() string { return "bandcamp" }
+
+func (s BandcampSource) Detect(req Request) bool {
+	return hostMatches(req.URL, append([]string{"bandcamp.com"}, s.Hosts...)...)
+}
+
+func (s BandcampSource) Extract(ctx context.Context, req Request) (*Result, error) {
+	if !s.Detect(req) {
+		return nil, inputErrorf("Invalid Bandcamp URL; custom domains must be listed in BANDCAMP_CUSTOM_DOMAINS")
+	}
+
+	// Allowed hosts can still resolve anywhere; internal addresses are refused
+	// at dial time
+	client := netguard.NewClient(30 * time.Second)
+	page, err := fetchPage(ctx, client, strings.TrimSpace(req.URL))
+	if err != nil {
+		return nil, fmt.Errorf("fetch Bandcamp page: %w", err)
+	}
+	tracks, name, kind, err := parseBandcampPage(page)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Tracks: tracks, Name: name, Meta: map[string]any{"kind": kind}}, nil
+}
+
+type bandcampTralbum struct {
+	Artist   string `json:"artist"`
+	ItemType string `json:"item_type"`
+	Current  struct {
+		Title string `json:"title"`
+	} `json:"current"`
+	TrackInfo []struct {
+		TrackID  int64   `json:"track_id"`
+		ID       int64   `json:"id"`
+		Title    string  `json:"title"`
+		Artist   string  `json:"artist"`
+		Duration float64 `json:"duration"`
+	} `json:"trackinfo"`
+}
+
+func parseBandcampPage(page []byte) ([]models.Track, string, string, error) {
+	m := bandcampTralbumRegex.FindSubmatch(page)
+	if m == nil {
+		return nil, "", "", inputErrorf("Bandcamp URL must point to an album or track page")
+	}
+	var tr bandcampTralbum
+	if err := json.Unmarshal([]byte(html.UnescapeString(string(m[1]))), &tr); err != nil {
+		return nil, "", "", fmt.Errorf("parse Bandcamp page data: %w", err)
+	}
+
+	kind := "album"
+	album := tr.Current.Title
+	if tr.ItemType == "track" {
+		kind = "track"
+		album = ""
+	}
+
+	// Compilations name each track's artist, or put it in the title
+	various := strings.EqualFold(tr.Artist, "Various Artists") || strings.EqualFold(tr.Artist, "Various")
+
+	var tracks []models.Track
+	for _, ti := range tr.TrackInfo {
+		id := ti.TrackID
+		if id == 0 {
+			id = ti.ID
+		}
+		t := models.Track{
+			Title:      ti.Title,
+			Artist:     tr.Artist,
+			Album:      album,
+			SourceID:   strconv.FormatInt(id, 10),
+			DurationMs: int(ti.Duration * 1000),
+			Type:       "bandcamp",
+		}
+		switch {
+		case ti.Artist != "":
+			t.Artist = ti.Artist
+		case various:
+			parsed := ParseYTTitle(ti.Title, "")
+			t.Artist, t.Title, t.Featuring = parsed.Artist, parsed.Title, parsed.Featuring
+		}
+		tracks = append(tracks, t)
+	}
+	if len(tracks) == 0 {
+		return nil, tr.Current.Title, kind, errors.New("no tracks found on the Bandcamp page")
+	}
+	return tracks, tr.Current.Title, kind, nil
+}
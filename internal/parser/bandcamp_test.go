@@ -0,0 +1,75 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dbh-go-srv/internal/netguard"
+)
+
+func TestBandcampDetect(t *testing.T) {
+	s := NewBandcampSource(" Music.Example.com ,, example.org")
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://artist.bandcamp.com/album/some-album", true},
+		{"https://music.example.com/album/some-album", true},
+		{"https://www.example.org/track/some-track", true},
+		{"https://example.net/album/some-album", false},
+		{"http://169.254.169.254/latest/meta-data/", false},
+		{"http://localhost:8080/album/x", false},
+	}
+	for _, tt := range tests {
+		if got := s.Detect(Request{URL: tt.url}); got != tt.want {
+			t.Errorf("Detect(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestBandcampRejectsOtherHosts(t *testing.T) {
+	var hits int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hits++ }))
+	defer srv.Close()
+
+	// Requests that name the type reach Extract without detection
+	_, err := BandcampSource{}.Extract(context.Background(), Request{URL: srv.URL + "/album/x"})
+	var inputErr *InputError
+	if !errors.As(err, &inputErr) {
+		t.Errorf("err = %v, want an InputError", err)
+	}
+
+	// An allowed host that resolves to an internal address is not dialed
+	_, err = BandcampSource{Hosts: []string{"127.0.0.1"}}.Extract(context.Background(), Request{URL: srv.URL + "/album/x"})
+	if !errors.Is(err, netguard.ErrBlockedAddress) {
+		t.Errorf("err = %v, want ErrBlockedAddress", err)
+	}
+	if hits != 0 {
+		t.Errorf("the server was fetched %d times", hits)
+	}
+}
+
+func TestParseBandcampPage(t *testing.T) {
+	page := `<html><script data-tralbum="{&quot;artist&quot;:&quot;Some Artist&quot;,&quot;item_type&quot;:&quot;album&quot;,` +
+		`&quot;current&quot;:{&quot;title&quot;:&quot;Some Album&quot;},&quot;trackinfo&quot;:[` +
+		`{&quot;track_id&quot;:11,&quot;title&quot;:&quot;First&quot;,&quot;duration&quot;:180.5},` +
+		`{&quot;track_id&quot;:12,&quot;title&quot;:&quot;Second&quot;,&quot;duration&quot;:200}]}"></script></html>`
+	tracks, name, kind, err := parseBandcampPage([]byte(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "Some Album" || kind != "album" || len(tracks) != 2 {
+		t.Fatalf("name %q, kind %q, tracks %+v", name, kind, tracks)
+	}
+	if tr := tracks[0]; tr.Title != "First" || tr.Artist != "Some Artist" || tr.Album != "Some Album" || tr.SourceID != "11" {
+		t.Errorf("first track = %+v", tr)
+	}
+
+	if _, _, _, err := parseBandcampPage([]byte("<html>artist page</html>")); !strings.Contains(err.Error(), "album or track") {
+		t.Errorf("page without release data: err = %v", err)
+	}
+}
@@ -1,127 +1,437 @@
 package parser
 
 import (
-	"database/sql"
+	"bufio"
+	"bytes"
+	"context"
 	"encoding/csv"
 	"errors"
-	"net/http"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
 	"strings"
 
-	"dbh-go-srv/internal/dab"
-	"dbh-go-srv/internal/matcher" // Imported the matcher package
 	"dbh-go-srv/internal/models"
 )
 
-// canonical header mapping
-var headerAliases = map[string][]string{
-	"title": {
-		"title", "track", "track name", "track_title", "name",
-	},
-	"artist": {
-		"artist", "artists", "artist name", "artist_name", "performer",
-	},
-	"album": {
-		"album", "album name", "album_title",
-	},
-	"isrc": {
-		"isrc", "isrc code",
-	},
-	"spotify": {
-		"spotify", "spotify uri", "spotify track uri", "uri",
-	},
-}
-
 func normalize(s string) string {
 	return strings.ToLower(strings.TrimSpace(s))
 }
 
-func buildHeaderLookup() map[string]string {  
-	lookup := make(map[string]string)  
-  
-	for canonical, aliases := range headerAliases {  
-		for _, a := range aliases {  
-			n := normalize(a)  
-			if prev, exists := lookup[n]; exists && prev != canonical {  
-				panic("header alias conflict: " + a)  
-			}  
-			lookup[n] = canonical  
-		}  
-	}  
-	return lookup  
-}  
-  
-var headerLookup = buildHeaderLookup()  
-
-// ParseCSV now takes DB, Client, and Match Mode to process tracks through the matcher
-type ProgressCallback func(index, total int, res *models.MatchResult)
-func ParseCSV(r *http.Request, db *sql.DB, client *dab.Client, mode string, debug bool, onProgress ProgressCallback) ([]models.MatchResult, string, error) {
-	file, header, err := r.FormFile("file")
+// CSVOptions tunes how a CSV file is read.
+type CSVOptions struct {
+	// Profile forces an exporter profile (see CSVProfiles); empty detects it
+	// from the header row.
+	Profile string
+	// MaxRows stops reading with an error after that many data rows; 0 is unlimited.
+	MaxRows int
+}
+
+// CSVImport is a parsed CSV file.
+type CSVImport struct {
+	Tracks    []models.Track
+	Profile   string
+	Delimiter rune
+	// Warnings lists the rows that were skipped and why.
+	Warnings []Warning
+}
+
+// ParseCSV reads tracks from a CSV file with a header row, detecting the
+// exporter profile and delimiter.
+func ParseCSV(r io.Reader) ([]models.Track, error) {
+	imp, err := ReadCSV(r, CSVOptions{})
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
-	defer file.Close()
+	return imp.Tracks, nil
+}
 
-	// Read all records first to get the total count for the progress bar
-	reader := csv.NewReader(file)
-	allRecords, err := reader.ReadAll()
+// ReadCSV reads a whole CSV file into memory. See openCSV for the format rules.
+func ReadCSV(r io.Reader, opts CSVOptions) (*CSVImport, error) {
+	rows, err := openCSV(r, opts)
 	if err != nil {
-		return nil, "", err
+		return nil, err
+	}
+
+	imp := &CSVImport{Profile: rows.profile.Name, Delimiter: rows.delim}
+	for {
+		t, warning, err := rows.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if warning != nil {
+			imp.Warnings = append(imp.Warnings, *warning)
+			continue
+		}
+		imp.Tracks = append(imp.Tracks, t)
+	}
+
+	if len(imp.Tracks) == 0 && len(imp.Warnings) == 0 {
+		return nil, errors.New("CSV is empty or missing headers")
 	}
+	return imp, nil
+}
+
+// csvRows reads a CSV file one record at a time, so large files never have to
+// be held in memory.
+type csvRows struct {
+	reader  *csv.Reader
+	profile csvProfile
+	headers []string
+	columns map[int]string
+	delim   rune
+	maxRows int
+	read    int
+}
 
-	if len(allRecords) < 2 {
-		return nil, "", errors.New("CSV is empty or missing headers")
+// openCSV reads the header row. A UTF-8 BOM is stripped, the delimiter (comma,
+// semicolon or tab) is sniffed from the header line, and columns are matched by
+// the profile's header names.
+func openCSV(r io.Reader, opts CSVOptions) (*csvRows, error) {
+	br := bufio.NewReader(r)
+	if b, err := br.Peek(3); err == nil && string(b) == "\ufeff" {
+		_, _ = br.Discard(3)
 	}
 
-	rawHeaders := allRecords[0]
-	rows := allRecords[1:]
-	columnMap := make(map[int]string)
+	delim := sniffDelimiter(br)
+	reader := csv.NewReader(br)
+	reader.Comma = delim
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.ReuseRecord = true
+
+	headers, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("CSV is empty or missing headers")
+	}
+	if err != nil {
+		return nil, err
+	}
 
-	for i, h := range rawHeaders {
-		if canonical, ok := headerLookup[normalize(h)]; ok {
-			columnMap[i] = canonical
+	profile := detectCSVProfile(headers)
+	if opts.Profile != "" {
+		if profile, err = lookupCSVProfile(opts.Profile); err != nil {
+			return nil, err
 		}
 	}
 
-	if len(columnMap) == 0 {
-		return nil, "", errors.New("CSV has no recognizable columns")
+	columns := profile.columnMap(headers)
+	if len(columns) == 0 {
+		return nil, errors.New("CSV has no recognizable columns")
 	}
 
-	var results []models.MatchResult
-	totalRows := len(rows)
+	// ReuseRecord would overwrite the header with the first row
+	headers = append([]string(nil), headers...)
+	return &csvRows{reader: reader, profile: profile, headers: headers, columns: columns, delim: delim, maxRows: opts.MaxRows}, nil
+}
 
-	for i, record := range rows {
-		var t models.Track
-		for colIdx, v := range record {
-			field, ok := columnMap[colIdx]
-			if !ok { continue }
-			val := strings.TrimSpace(v)
-			if val == "" { continue }
+// next returns the next track, or a warning for a skipped row, or io.EOF.
+func (c *csvRows) next() (models.Track, *Warning, error) {
+	record, err := c.reader.Read()
+	if err == io.EOF {
+		return models.Track{}, nil, io.EOF
+	}
 
-			switch field {
-			case "title": t.Title = val
-			case "artist": t.Artist = val
-			case "album": t.Album = val
-			case "isrc": t.ISRC = val
-			case "spotify":
-				if strings.HasPrefix(val, "spotify:track:") {
-					t.SourceID = val
-					t.Type = "spotify"
-				}
-			}
+	c.read++
+	if c.maxRows > 0 && c.read > c.maxRows {
+		return models.Track{}, nil, fmt.Errorf("CSV has more than %d rows", c.maxRows)
+	}
+
+	var perr *csv.ParseError
+	if errors.As(err, &perr) {
+		return models.Track{}, &Warning{Row: perr.StartLine, Message: perr.Err.Error()}, nil
+	}
+	if err != nil {
+		return models.Track{}, nil, err
+	}
+
+	t, warning := c.profile.track(record, c.columns)
+	if warning != "" {
+		row, _ := c.reader.FieldPos(0)
+		return models.Track{}, &Warning{Row: row, Message: warning}, nil
+	}
+	t.SourceRow = append([]string(nil), record...)
+	return t, nil, nil
+}
+
+// countCSVRows counts the data rows of a CSV file without parsing it: newlines
+// outside quoted fields, less the header. Blank lines are counted too, so the
+// result is an upper bound.
+func countCSVRows(r io.Reader) (int, error) {
+	br := bufio.NewReaderSize(r, 64*1024)
+	lines, quoted, last := 0, false, byte('\n')
+	for {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
 		}
+		switch {
+		case b == '"':
+			quoted = !quoted
+		case b == '\n' && !quoted:
+			lines++
+		}
+		last = b
+	}
+	if last != '\n' {
+		lines++
+	}
+	if lines > 0 {
+		lines--
+	}
+	return lines, nil
+}
 
-		if t.Title == "" && t.Artist == "" && t.SourceID == "" {
+// sniffDelimiter picks the separator that occurs most often outside quotes in
+// the header line. Commas win ties.
+func sniffDelimiter(br *bufio.Reader) rune {
+	// Buffer just the header line; waiting for a full buffer would stall
+	// slow uploads before their first row
+	var line []byte
+	for {
+		line, _ = br.Peek(br.Buffered())
+		if i := bytes.IndexByte(line, '\n'); i >= 0 {
+			line = line[:i]
+			break
+		}
+		if br.Buffered() == br.Size() {
+			break
+		}
+		if _, err := br.Peek(br.Buffered() + 1); err != nil {
+			line, _ = br.Peek(br.Buffered())
+			break
+		}
+	}
+
+	counts := map[rune]int{}
+	quoted := false
+	for _, c := range string(line) {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case !quoted && (c == ',' || c == ';' || c == '\t'):
+			counts[c]++
+		}
+	}
+
+	best := ','
+	for _, c := range []rune{';', '\t'} {
+		if counts[c] > counts[best] {
+			best = c
+		}
+	}
+	return best
+}
+
+// track builds a track from one record. A non-empty warning means the row was skipped.
+func (p csvProfile) track(record []string, columnMap map[int]string) (models.Track, string) {
+	var t models.Track
+	for colIdx, v := range record {
+		field, ok := columnMap[colIdx]
+		if !ok {
+			continue
+		}
+		val := strings.TrimSpace(v)
+		if val == "" {
 			continue
 		}
 
-		// Match and trigger SSE callback
-		res := matcher.MatchTrack(db, client, t, mode, debug)
-		results = append(results, *res)
+		switch field {
+		case csvTitle:
+			t.Title = val
+		case csvArtist:
+			t.Artist = p.joinArtists(val)
+		case csvAlbum:
+			t.Album = val
+		case csvISRC:
+			t.ISRC = strings.ToUpper(strings.ReplaceAll(val, "-", ""))
+		case csvSpotify:
+			if id := spotifyTrackID(val); id != "" {
+				t.SourceID = id
+				t.Type = "spotify"
+			}
+		case csvDurationMs:
+			if ms, err := strconv.Atoi(val); err == nil && ms > 0 {
+				t.DurationMs = ms
+			}
+		case csvDuration:
+			t.DurationMs = parseDuration(val)
+		}
+	}
+
+	if t.Title == "" && t.Artist == "" && t.SourceID == "" {
+		return t, "no title, artist or Spotify track ID"
+	}
+	return t, ""
+}
+
+func (p csvProfile) joinArtists(val string) string {
+	if p.ArtistSeparator == "" {
+		return val
+	}
+	var artists []string
+	for _, a := range strings.Split(val, p.ArtistSeparator) {
+		if a = strings.TrimSpace(a); a != "" {
+			artists = append(artists, a)
+		}
+	}
+	return strings.Join(artists, ", ")
+}
+
+var spotifyIDPattern = regexp.MustCompile(`^[0-9A-Za-z]{22}$`)
+
+// spotifyTrackID accepts a spotify:track: URI, an open.spotify.com track URL or
+// a bare track ID, and returns the bare ID.
+func spotifyTrackID(val string) string {
+	switch {
+	case strings.HasPrefix(val, "spotify:track:"):
+		val = strings.TrimPrefix(val, "spotify:track:")
+	case strings.Contains(val, "/track/"):
+		val = strings.SplitN(val, "/track/", 2)[1]
+		val = strings.SplitN(val, "?", 2)[0]
+	}
+	if spotifyIDPattern.MatchString(val) {
+		return val
+	}
+	return ""
+}
+
+// parseDuration reads "m:ss", "h:mm:ss" or a plain number of seconds (or of
+// milliseconds, when it is too large to be seconds) and returns milliseconds.
+// Unreadable values give 0.
+func parseDuration(val string) int {
+	if strings.Contains(val, ":") {
+		total := 0
+		for _, part := range strings.Split(val, ":") {
+			n, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || n < 0 {
+				return 0
+			}
+			total = total*60 + n
+		}
+		return total * 1000
+	}
+
+	f, err := strconv.ParseFloat(val, 64)
+	if err != nil || f <= 0 {
+		return 0
+	}
+	if f > 36000 {
+		return int(f)
+	}
+	return int(f * 1000)
+}
+
+// CSVSource imports an uploaded CSV file, streaming its rows.
+type CSVSource struct {
+	// MaxRows and MaxBytes reject larger files; 0 is unlimited.
+	MaxRows  int
+	MaxBytes int64
+}
+
+func (CSVSource) Name() string { return "csv" }
 
-		if onProgress != nil {
-			onProgress(i+1, totalRows, res)
+func (CSVSource) Detect(req Request) bool { return req.Upload != nil }
+
+func (s CSVSource) Extract(ctx context.Context, req Request) (*Result, error) {
+	stream, err := s.Stream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Result{Name: stream.Name, Meta: stream.Meta}
+	for t := range stream.Tracks {
+		res.Tracks = append(res.Tracks, t)
+	}
+	if err := stream.Err(); err != nil {
+		return nil, err
+	}
+	res.Warnings = stream.Warnings()
+	return res, nil
+}
+
+// Stream checks the limits, counts the rows when the upload can be rewound, and
+// reads the file in the background.
+func (s CSVSource) Stream(ctx context.Context, req Request) (*Stream, error) {
+	up := req.Upload
+	if up == nil {
+		return nil, inputErrorf("CSV import needs an uploaded file")
+	}
+	if s.MaxBytes > 0 && up.Size > s.MaxBytes {
+		return nil, inputErrorf("CSV file is %s; the limit is %s", formatBytes(up.Size), formatBytes(s.MaxBytes))
+	}
+
+	total := 0
+	if seeker, ok := up.Body.(io.Seeker); ok {
+		n, err := countCSVRows(up.Body)
+		if err == nil {
+			_, err = seeker.Seek(0, io.SeekStart)
 		}
+		if err != nil {
+			return nil, fmt.Errorf("read CSV: %w", err)
+		}
+		if s.MaxRows > 0 && n > s.MaxRows {
+			return nil, inputErrorf("CSV has %d rows; the limit is %d", n, s.MaxRows)
+		}
+		total = n
 	}
 
-	return results, header.Filename, nil
+	rows, err := openCSV(up.Body, CSVOptions{Profile: up.Profile, MaxRows: s.MaxRows})
+	if err != nil {
+		// The file is the request, so a bad file is the caller's problem
+		return nil, inputErrorf("CSV parse failed: %v", err)
+	}
+
+	ch := make(chan models.Track, 64)
+	stream := &Stream{
+		Name: up.Filename,
+		Meta: map[string]any{
+			"profile":   rows.profile.Name,
+			"delimiter": string(rows.delim),
+		},
+		Columns: rows.headers,
+		Total:   total,
+		Tracks:  ch,
+	}
+
+	go func() {
+		defer close(ch)
+		for {
+			t, warning, err := rows.next()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				stream.fail(fmt.Errorf("CSV parse failed: %w", err))
+				return
+			}
+			if warning != nil {
+				stream.warn(*warning)
+				continue
+			}
+			select {
+			case ch <- t:
+			case <-ctx.Done():
+				stream.fail(ctx.Err())
+				return
+			}
+		}
+	}()
+
+	return stream, nil
+}
+
+func formatBytes(n int64) string {
+	const mb = 1 << 20
+	if n >= mb {
+		return fmt.Sprintf("%.1f MB", float64(n)/mb)
+	}
+	return fmt.Sprintf("%d KB", (n+1023)/1024)
 }
@@ -0,0 +1,152 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// csvProfile describes the CSV files written by one exporter tool.
+type csvProfile struct {
+	Name string
+	// Signature lists headers that must all be present for the profile to be
+	// picked by detection. The generic profile has none and is the fallback.
+	Signature []string
+	// Columns maps canonical fields to the header names that carry them.
+	Columns map[string][]string
+	// ArtistSeparator splits multi-artist cells; the artists are re-joined with
+	// ", " like the other sources do.
+	ArtistSeparator string
+}
+
+// Canonical CSV fields. "duration" holds clock time or seconds, "duration_ms"
+// milliseconds.
+const (
+	csvTitle      = "title"
+	csvArtist     = "artist"
+	csvAlbum      = "album"
+	csvISRC       = "isrc"
+	csvSpotify    = "spotify"
+	csvDuration   = "duration"
+	csvDurationMs = "duration_ms"
+)
+
+// csvProfiles are tried in order; the first whose signature matches wins.
+var csvProfiles = []csvProfile{
+	{
+		// Both the classic ("Track Name", "Artist Name(s)") and the current
+		// ("Track", "Artist") Exportify layouts
+		Name:      "exportify",
+		Signature: []string{"track uri", "added by"},
+		Columns: map[string][]string{
+			csvTitle:      {"track name", "track"},
+			csvArtist:     {"artist name(s)", "artist"},
+			csvAlbum:      {"album name", "album"},
+			csvISRC:       {"isrc"},
+			csvSpotify:    {"track uri"},
+			csvDurationMs: {"track duration (ms)", "duration (ms)"},
+		},
+		ArtistSeparator: ";",
+	},
+	{
+		Name:      "tunemymusic",
+		Signature: []string{"track name", "artist name", "playlist name"},
+		Columns: map[string][]string{
+			csvTitle:   {"track name"},
+			csvArtist:  {"artist name"},
+			csvAlbum:   {"album"},
+			csvISRC:    {"isrc"},
+			csvSpotify: {"spotify - id"},
+		},
+	},
+	{
+		Name:      "soundiiz",
+		Signature: []string{"title", "artist", "album", "isrc", "duration"},
+		Columns: map[string][]string{
+			csvTitle:    {"title"},
+			csvArtist:   {"artist"},
+			csvAlbum:    {"album"},
+			csvISRC:     {"isrc"},
+			csvDuration: {"duration"},
+		},
+	},
+	{
+		Name: "generic",
+		Columns: map[string][]string{
+			csvTitle:      {"title", "track", "track name", "track_title", "name"},
+			csvArtist:     {"artist", "artists", "artist name", "artist_name", "performer"},
+			csvAlbum:      {"album", "album name", "album_title"},
+			csvISRC:       {"isrc", "isrc code"},
+			csvSpotify:    {"spotify", "spotify uri", "spotify track uri", "uri", "spotify id"},
+			csvDuration:   {"duration", "length", "time"},
+			csvDurationMs: {"duration_ms", "duration (ms)"},
+		},
+	},
+}
+
+// CSVProfiles returns the names of the known profiles, in detection order.
+func CSVProfiles() []string {
+	names := make([]string, len(csvProfiles))
+	for i, p := range csvProfiles {
+		names[i] = p.Name
+	}
+	return names
+}
+
+func lookupCSVProfile(name string) (csvProfile, error) {
+	for _, p := range csvProfiles {
+		if p.Name == strings.ToLower(strings.TrimSpace(name)) {
+			return p, nil
+		}
+	}
+	return csvProfile{}, fmt.Errorf("unknown CSV profile %q (known: %s)", name, strings.Join(CSVProfiles(), ", "))
+}
+
+// detectCSVProfile picks the first profile whose signature headers are all present.
+func detectCSVProfile(headers []string) csvProfile {
+	present := make(map[string]bool, len(headers))
+	for _, h := range headers {
+		present[normalize(h)] = true
+	}
+
+	for _, p := range csvProfiles {
+		if len(p.Signature) == 0 {
+			return p
+		}
+		ok := true
+		for _, h := range p.Signature {
+			if !present[h] {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return p
+		}
+	}
+	return csvProfiles[len(csvProfiles)-1]
+}
+
+// columnMap maps column indexes to canonical fields. When several headers name
+// the same field, the one listed first in the profile wins.
+func (p csvProfile) columnMap(headers []string) map[int]string {
+	index := make(map[string]int, len(headers))
+	for i, h := range headers {
+		n := normalize(h)
+		if _, dup := index[n]; !dup {
+			index[n] = i
+		}
+	}
+
+	out := make(map[int]string)
+	for field, names := range p.Columns {
+		for _, name := range names {
+			if i, ok := index[name]; ok {
+				if _, taken := out[i]; !taken {
+					out[i] = field
+				}
+				break
+			}
+		}
+	}
+	return out
+}
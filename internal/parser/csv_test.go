@@ -0,0 +1,337 @@
+package parser
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"dbh-go-srv/internal/models"
+)
+
+func TestReadCSVExportify(t *testing.T) {
+	// The repository's own Exportify export: BOM, "Track URI" and "Added By"
+	// headers, ";"-joined artists and milliseconds in "Duration (ms)"
+	f, err := os.Open(filepath.Join("..", "..", "My_Playlist_#1.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	imp, err := ReadCSV(f, CSVOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imp.Profile != "exportify" || imp.Delimiter != ',' {
+		t.Errorf("profile %q, delimiter %q; want exportify and ','", imp.Profile, imp.Delimiter)
+	}
+	if len(imp.Tracks) != 13 || len(imp.Warnings) != 0 {
+		t.Fatalf("%d tracks, warnings %+v; want 13 and none", len(imp.Tracks), imp.Warnings)
+	}
+	first := imp.Tracks[0]
+	want := models.Track{
+		Title: `Illuminati - From "Aavesham"`, Artist: "Sushin Shyam, Dabzee, Vinayak Sasikumar",
+		Album: `Illuminati (From "Aavesham")`, SourceID: "1kFNFsAZ4iZy4vjBEtT12I", DurationMs: 212525, Type: "spotify",
+	}
+	if first.Title != want.Title || first.Artist != want.Artist || first.Album != want.Album ||
+		first.SourceID != want.SourceID || first.DurationMs != want.DurationMs || first.Type != want.Type {
+		t.Errorf("first track = %+v, want %+v", first, want)
+	}
+	if len(first.SourceRow) == 0 || first.SourceRow[0] != "spotify:track:1kFNFsAZ4iZy4vjBEtT12I" {
+		t.Errorf("source row = %q", first.SourceRow)
+	}
+}
+
+func TestReadCSVProfiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		profile string
+		want    models.Track
+	}{
+		{
+			name: "tunemymusic",
+			input: "Track name,Artist name,Album,Playlist name,Type,ISRC,Spotify - id\n" +
+				"Get Lucky,Daft Punk,Random Access Memories,Mine,Playlist,usqx-91300108,69kOkLUCkxIZYexIgSG8rq\n",
+			profile: "tunemymusic",
+			want: models.Track{Title: "Get Lucky", Artist: "Daft Punk", Album: "Random Access Memories",
+				ISRC: "USQX91300108", SourceID: "69kOkLUCkxIZYexIgSG8rq", Type: "spotify"},
+		},
+		{
+			name:    "soundiiz",
+			input:   "title,artist,album,isrc,duration\nGet Lucky,Daft Punk,Random Access Memories,USQX91300108,6:09\n",
+			profile: "soundiiz",
+			want: models.Track{Title: "Get Lucky", Artist: "Daft Punk", Album: "Random Access Memories",
+				ISRC: "USQX91300108", DurationMs: 369000},
+		},
+		{
+			name:    "generic",
+			input:   "Name,Performer,Length,Spotify URI\nGet Lucky,Daft Punk,369,https://open.spotify.com/track/69kOkLUCkxIZYexIgSG8rq?si=x\n",
+			profile: "generic",
+			want:    models.Track{Title: "Get Lucky", Artist: "Daft Punk", DurationMs: 369000, SourceID: "69kOkLUCkxIZYexIgSG8rq", Type: "spotify"},
+		},
+		{
+			// Soundiiz headers are also generic ones, but the signature wins
+			name:    "soundiiz before generic",
+			input:   "Title,Artist,Album,ISRC,Duration,Extra\nSong,Band,Record,,215\n",
+			profile: "soundiiz",
+			want:    models.Track{Title: "Song", Artist: "Band", Album: "Record", DurationMs: 215000},
+		},
+	}
+	for _, tt := range tests {
+		imp, err := ReadCSV(strings.NewReader(tt.input), CSVOptions{})
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if imp.Profile != tt.profile {
+			t.Errorf("%s: profile = %q, want %q", tt.name, imp.Profile, tt.profile)
+		}
+		if len(imp.Tracks) != 1 {
+			t.Errorf("%s: tracks = %+v, want one", tt.name, imp.Tracks)
+			continue
+		}
+		got := imp.Tracks[0]
+		if got.Title != tt.want.Title || got.Artist != tt.want.Artist || got.Album != tt.want.Album || got.ISRC != tt.want.ISRC ||
+			got.SourceID != tt.want.SourceID || got.DurationMs != tt.want.DurationMs || got.Type != tt.want.Type {
+			t.Errorf("%s: track = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReadCSVProfileOverride(t *testing.T) {
+	if _, err := ReadCSV(strings.NewReader("title,artist\nSong,Band\n"), CSVOptions{Profile: "nope"}); err == nil {
+		t.Error("ReadCSV accepted an unknown profile")
+	}
+	imp, err := ReadCSV(strings.NewReader("Track Name,Artist Name\nSong,Band\n"), CSVOptions{Profile: " TuneMyMusic "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imp.Profile != "tunemymusic" || len(imp.Tracks) != 1 {
+		t.Errorf("profile %q, tracks %+v", imp.Profile, imp.Tracks)
+	}
+}
+
+func TestSniffDelimiter(t *testing.T) {
+	tests := []struct {
+		input string
+		want  rune
+	}{
+		{"title,artist,album\nSong;Band;Record\n", ','},
+		{"title;artist;album\nSong,Band,Record\n", ';'},
+		{"title\tartist\talbum\nSong,Band,Record\n", '\t'},
+		// Separators inside quotes do not count
+		{`"a;b;c;d",artist,album` + "\n", ','},
+		// Commas win ties, and a header without a newline is still read
+		{"title;artist,album", ','},
+		{"title;artist", ';'},
+	}
+	for _, tt := range tests {
+		if got := sniffDelimiter(bufio.NewReader(strings.NewReader(tt.input))); got != tt.want {
+			t.Errorf("sniffDelimiter(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+
+	imp, err := ReadCSV(strings.NewReader("title;artist\n\"Song, Part 2\";Band\n"), CSVOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imp.Delimiter != ';' || len(imp.Tracks) != 1 || imp.Tracks[0].Title != "Song, Part 2" {
+		t.Errorf("delimiter %q, tracks %+v", imp.Delimiter, imp.Tracks)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		val  string
+		want int
+	}{
+		{"3:45", 225000},
+		{"1:02:03", 3723000},
+		{"0:07", 7000},
+		{"215", 215000},
+		{"215.5", 215500},
+		// Too long for seconds, so milliseconds
+		{"212525", 212525},
+		{"36000", 36000000},
+		{"", 0},
+		{"abc", 0},
+		{"3:xx", 0},
+		{"-5", 0},
+		{"1:-5", 0},
+	}
+	for _, tt := range tests {
+		if got := parseDuration(tt.val); got != tt.want {
+			t.Errorf("parseDuration(%q) = %d, want %d", tt.val, got, tt.want)
+		}
+	}
+}
+
+func TestReadCSVWarnings(t *testing.T) {
+	input := "title,artist,album\n" +
+		"Song,Band,Record\n" +
+		",,Only An Album\n" +
+		" , ,\n" +
+		"Other Song,,\n"
+	imp, err := ReadCSV(strings.NewReader(input), CSVOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imp.Tracks) != 2 {
+		t.Errorf("tracks = %+v, want two", imp.Tracks)
+	}
+	if len(imp.Warnings) != 2 {
+		t.Fatalf("warnings = %+v, want two", imp.Warnings)
+	}
+	for i, row := range []int{3, 4} {
+		w := imp.Warnings[i]
+		if w.Row != row || w.Message != "no title, artist or Spotify track ID" {
+			t.Errorf("warning %d = %+v, want row %d", i, w, row)
+		}
+	}
+
+	if _, err := ReadCSV(strings.NewReader(""), CSVOptions{}); err == nil {
+		t.Error("ReadCSV accepted an empty file")
+	}
+	if _, err := ReadCSV(strings.NewReader("foo,bar\n1,2\n"), CSVOptions{}); err == nil {
+		t.Error("ReadCSV accepted a file without known columns")
+	}
+}
+
+func TestCountCSVRows(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int
+	}{
+		{"", 0},
+		{"title\n", 0},
+		{"title", 0},
+		{"title\nA\nB\n", 2},
+		// Without a trailing newline the last row still counts
+		{"title\nA\nB", 2},
+		// Newlines inside quoted fields do not
+		{"title\n\"A\nstill A\"\nB", 2},
+		{"title\r\nA\r\nB\r\n", 2},
+	}
+	for _, tt := range tests {
+		got, err := countCSVRows(strings.NewReader(tt.input))
+		if err != nil {
+			t.Errorf("countCSVRows(%q): %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("countCSVRows(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
+
+// unseekable hides a reader's Seek method, like a request body does.
+type unseekable struct{ r *strings.Reader }
+
+func (u unseekable) Read(p []byte) (int, error) { return u.r.Read(p) }
+
+func TestCSVSourceLimits(t *testing.T) {
+	const threeRows = "title,artist\nA,Band\nB,Band\nC,Band"
+
+	tests := []struct {
+		name   string
+		source CSVSource
+		upload Upload
+		// wantErr is part of the InputError returned by Stream; empty means
+		// the stream starts
+		wantErr string
+		// readErr is part of the error that stops reading
+		readErr string
+		tracks  int
+	}{
+		{
+			name:    "size over MaxBytes",
+			source:  CSVSource{MaxBytes: 10},
+			upload:  Upload{Body: strings.NewReader(threeRows), Size: int64(len(threeRows))},
+			wantErr: "the limit is 1 KB",
+		},
+		{
+			name:   "size at MaxBytes",
+			source: CSVSource{MaxBytes: int64(len(threeRows))},
+			upload: Upload{Body: strings.NewReader(threeRows), Size: int64(len(threeRows))},
+			tracks: 3,
+		},
+		{
+			// The pre-count sees the last row without a trailing newline
+			name:    "pre-count over MaxRows",
+			source:  CSVSource{MaxRows: 2},
+			upload:  Upload{Body: strings.NewReader(threeRows)},
+			wantErr: "CSV has 3 rows; the limit is 2",
+		},
+		{
+			name:   "pre-count at MaxRows",
+			source: CSVSource{MaxRows: 3},
+			upload: Upload{Body: strings.NewReader(threeRows)},
+			tracks: 3,
+		},
+		{
+			// Without Seek there is no pre-count, so reading stops at the limit
+			name:    "streamed over MaxRows",
+			source:  CSVSource{MaxRows: 2},
+			upload:  Upload{Body: unseekable{strings.NewReader(threeRows)}},
+			readErr: "more than 2 rows",
+			tracks:  2,
+		},
+		{
+			name:    "unreadable header",
+			source:  CSVSource{},
+			upload:  Upload{Body: strings.NewReader("foo,bar\n1,2\n")},
+			wantErr: "no recognizable columns",
+		},
+	}
+	for _, tt := range tests {
+		tt.upload.Filename = "list.csv"
+		stream, err := tt.source.Stream(context.Background(), Request{Upload: &tt.upload})
+		if tt.wantErr != "" {
+			var inputErr *InputError
+			if !errors.As(err, &inputErr) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: err = %v, want an InputError mentioning %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+
+		n := 0
+		for range stream.Tracks {
+			n++
+		}
+		if n != tt.tracks {
+			t.Errorf("%s: %d tracks, want %d", tt.name, n, tt.tracks)
+		}
+		err = stream.Err()
+		if tt.readErr == "" && err != nil || tt.readErr != "" && (err == nil || !strings.Contains(err.Error(), tt.readErr)) {
+			t.Errorf("%s: read err = %v, want %q", tt.name, err, tt.readErr)
+		}
+	}
+}
+
+func TestCSVSourceStreamMeta(t *testing.T) {
+	body := "title;artist;album\nSong;Band;Record\n;;\n"
+	stream, err := CSVSource{}.Stream(context.Background(), Request{Upload: &Upload{Filename: "list.csv", Body: strings.NewReader(body)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range stream.Tracks {
+	}
+	if stream.Name != "list.csv" || stream.Meta["profile"] != "generic" || stream.Meta["delimiter"] != ";" || stream.Total != 2 {
+		t.Errorf("name %q, meta %v, total %d", stream.Name, stream.Meta, stream.Total)
+	}
+	if want := []string{"title", "artist", "album"}; !slices.Equal(stream.Columns, want) {
+		t.Errorf("columns = %q, want %q", stream.Columns, want)
+	}
+	if w := stream.Warnings(); len(w) != 1 || w[0].Row != 3 {
+		t.Errorf("warnings = %+v, want one for row 3", w)
+	}
+}
@@ -0,0 +1,299 @@
+package parser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"dbh-go-srv/internal/models"
+	"golang.org/x/time/rate"
+)
+
+// DefaultDeezerBaseURL is the public Deezer API.
+const DefaultDeezerBaseURL = "https://api.deezer.com"
+
+// Deezer allows 50 API requests per 5 seconds; stay under it with some margin.
+var deezerLimiter = rate.NewLimiter(rate.Every(125*time.Millisecond), 5)
+
+// deezerQuotaWait is how long to back off once the request quota is exceeded.
+var deezerQuotaWait = 5 * time.Second
+
+var deezerPathRegex = regexp.MustCompile(`^/(?:[a-z]{2}(?:-[a-z]{2})?/)?(playlist|album|track)/(\d+)`)
+
+// DeezerSource imports public Deezer playlists, albums and tracks through the
+// public API, including deezer.page.link and link.deezer.com short links.
+// Track listings carry no ISRCs, so every track is also fetched on its own;
+// Deezer's ISRCs then give near-exact matches.
+type DeezerSource struct {
+	// BaseURL is the API root; tests point it at a local fake server.
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// NewDeezerSource returns a source for the public API.
+func NewDeezerSource() *DeezerSource {
+	return &DeezerSource{
+		BaseURL:    DefaultDeezerBaseURL,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *DeezerSource) Name() string { return "deezer" }
+
+func (s *DeezerSource) Detect(req Request) bool {
+	return hostMatches(req.URL, "deezer.com", "deezer.page.link")
+}
+
+func (s *DeezerSource) Extract(ctx context.Context, req Request) (*Result, error) {
+	if !s.Detect(req) {
+		return nil, inputErrorf("Invalid Deezer URL")
+	}
+
+	client := s.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	pageURL := strings.TrimSpace(req.URL)
+	if hostMatches(pageURL, "deezer.page.link", "link.deezer.com") {
+		resolved, err := resolveShortLink(ctx, client, pageURL)
+		if err != nil {
+			return nil, fmt.Errorf("resolve Deezer short link: %w", err)
+		}
+		pageURL = resolved
+	}
+
+	u, err := url.Parse(pageURL)
+	if err != nil || !hostMatches(pageURL, "deezer.com") {
+		return nil, inputErrorf("Invalid Deezer URL")
+	}
+	m := deezerPathRegex.FindStringSubmatch(u.Path)
+	if m == nil {
+		return nil, inputErrorf("Deezer URL must point to a playlist, album or track")
+	}
+	kind, id := m[1], m[2]
+
+	dz := deezerClient{hc: client, base: s.BaseURL}
+	var (
+		list []deezerTrack
+		name string
+	)
+	switch kind {
+	case "playlist", "album":
+		var head struct {
+			Title string `json:"title"`
+		}
+		if err := dz.get(ctx, "/"+kind+"/"+id, &head); err != nil {
+			return nil, err
+		}
+		name = head.Title
+		if list, err = dz.tracks(ctx, "/"+kind+"/"+id+"/tracks"); err != nil {
+			return nil, err
+		}
+	case "track":
+		var t deezerTrack
+		if err := dz.get(ctx, "/track/"+id, &t); err != nil {
+			return nil, err
+		}
+		list, name = []deezerTrack{t}, t.Title
+	}
+
+	dz.fillISRCs(ctx, list)
+
+	tracks := make([]models.Track, 0, len(list))
+	for _, t := range list {
+		tracks = append(tracks, t.track())
+	}
+	return &Result{Tracks: tracks, Name: name, Meta: map[string]any{"kind": kind}}, nil
+}
+
+// resolveShortLink follows a short link's redirects and returns where they end.
+func resolveShortLink(ctx context.Context, client *http.Client, link string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return resp.Request.URL.String(), nil
+}
+
+type deezerTrack struct {
+	ID       int64  `json:"id"`
+	Title    string `json:"title"`
+	Duration int    `json:"duration"`
+	ISRC     string `json:"isrc"`
+	Artist   struct {
+		Name string `json:"name"`
+	} `json:"artist"`
+	Contributors []struct {
+		Name string `json:"name"`
+		Role string `json:"role"`
+	} `json:"contributors"`
+	Album struct {
+		Title string `json:"title"`
+	} `json:"album"`
+}
+
+func (t deezerTrack) track() models.Track {
+	// Contributors, only on full track objects, list every main artist
+	artist := t.Artist.Name
+	var main []string
+	for _, c := range t.Contributors {
+		if c.Role == "Main" && c.Name != "" {
+			main = append(main, c.Name)
+		}
+	}
+	if len(main) > 0 {
+		artist = strings.Join(main, ", ")
+	}
+
+	return models.Track{
+		Title:      t.Title,
+		Artist:     artist,
+		Album:      t.Album.Title,
+		ISRC:       strings.ToUpper(t.ISRC),
+		SourceID:   strconv.FormatInt(t.ID, 10),
+		DurationMs: t.Duration * 1000,
+		Type:       "deezer",
+	}
+}
+
+type deezerClient struct {
+	hc   *http.Client
+	base string
+}
+
+// deezerError is the error object the API returns with a 200 status.
+type deezerError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+	Code    int    `json:"code"`
+}
+
+func (e *deezerError) Error() string { return fmt.Sprintf("deezer: %s (%d)", e.Message, e.Code) }
+
+// Deezer error codes worth telling apart
+const (
+	deezerQuotaExceeded = 4
+	deezerNoData        = 800
+)
+
+// get fetches an API path (or a full "next" URL) into out, waiting out the
+// request quota once if it is exceeded.
+func (c deezerClient) get(ctx context.Context, path string, out any) error {
+	target := path
+	if !strings.HasPrefix(target, "http") {
+		target = strings.TrimSuffix(c.base, "/") + path
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := deezerLimiter.Wait(ctx); err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := c.hc.Do(req)
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return fmt.Errorf("deezer %s: %s", path, resp.Status)
+		}
+		var body json.RawMessage
+		err = json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("deezer %s: %w", path, err)
+		}
+
+		var envelope struct {
+			Error *deezerError `json:"error"`
+		}
+		_ = json.Unmarshal(body, &envelope)
+		if e := envelope.Error; e != nil {
+			if e.Code == deezerQuotaExceeded && attempt == 0 {
+				select {
+				case <-time.After(deezerQuotaWait):
+					continue
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			if e.Code == deezerNoData {
+				return &InputError{"Deezer item not found; is it public?"}
+			}
+			return e
+		}
+		return json.Unmarshal(body, out)
+	}
+}
+
+// tracks reads a paginated track list, following "next" links.
+func (c deezerClient) tracks(ctx context.Context, path string) ([]deezerTrack, error) {
+	var all []deezerTrack
+	next := path + "?limit=100"
+	for next != "" {
+		var page struct {
+			Data []deezerTrack `json:"data"`
+			Next string        `json:"next"`
+		}
+		if err := c.get(ctx, next, &page); err != nil {
+			return nil, err
+		}
+		all = append(all, page.Data...)
+		next = page.Next
+	}
+	if len(all) == 0 {
+		return nil, errors.New("deezer: no tracks found")
+	}
+	return all, nil
+}
+
+// fillISRCs fetches the full object of every track without an ISRC. A track
+// that cannot be fetched keeps its listing data and is matched by text.
+func (c deezerClient) fillISRCs(ctx context.Context, list []deezerTrack) {
+	const workers = 4
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				var full deezerTrack
+				if c.get(ctx, "/track/"+strconv.FormatInt(list[i].ID, 10), &full) == nil && full.ID == list[i].ID {
+					list[i] = full
+				}
+			}
+		}()
+	}
+
+feed:
+	for i := range list {
+		if list[i].ISRC != "" {
+			continue
+		}
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+}
@@ -0,0 +1,180 @@
+package parser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeDeezer struct {
+	mu         sync.Mutex
+	trackCalls []string // IDs of the single-track requests
+	quotaHits  int
+}
+
+func deezerListing(id int64, title, isrc string) map[string]any {
+	return map[string]any{
+		"id": id, "title": title, "duration": 200, "isrc": isrc,
+		"artist": map[string]any{"name": "Main Artist"},
+		"album":  map[string]any{"title": "Some Album"},
+	}
+}
+
+// newFakeDeezer serves playlist 1 (five tracks over three pages, only the
+// second with an ISRC in the listing), album 5 (which trips the quota once),
+// the full objects of tracks 101-105 (104 has gone missing), and errors.
+func newFakeDeezer(t *testing.T) (*fakeDeezer, *DeezerSource) {
+	t.Helper()
+	f := &fakeDeezer{}
+	var srv *httptest.Server
+
+	listing := []map[string]any{
+		deezerListing(101, "One", ""),
+		deezerListing(102, "Two", "GBAYE0000002"),
+		deezerListing(103, "Three", ""),
+		deezerListing(104, "Four", ""),
+		deezerListing(105, "Five", ""),
+	}
+	deezerErr := func(w http.ResponseWriter, code int, message string) {
+		json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"type": "Exception", "message": message, "code": code}})
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /playlist/1", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"id": 1, "title": "Road Trip"})
+	})
+	mux.HandleFunc("GET /playlist/1/tracks", func(w http.ResponseWriter, r *http.Request) {
+		// Pages of two, whatever limit was asked for
+		index, _ := strconv.Atoi(r.URL.Query().Get("index"))
+		end := min(index+2, len(listing))
+		page := map[string]any{"data": listing[index:end], "total": len(listing)}
+		if end < len(listing) {
+			page["next"] = srv.URL + "/playlist/1/tracks?limit=100&index=" + strconv.Itoa(end)
+		}
+		json.NewEncoder(w).Encode(page)
+	})
+	mux.HandleFunc("GET /album/5", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.quotaHits++
+		first := f.quotaHits == 1
+		f.mu.Unlock()
+		if first {
+			deezerErr(w, deezerQuotaExceeded, "Quota limit exceeded")
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"id": 5, "title": "Quota Album"})
+	})
+	mux.HandleFunc("GET /album/5/tracks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"data": listing[1:2]})
+	})
+	mux.HandleFunc("GET /track/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		f.mu.Lock()
+		f.trackCalls = append(f.trackCalls, id)
+		f.mu.Unlock()
+		n, _ := strconv.ParseInt(id, 10, 64)
+		if n < 101 || n > 105 || n == 104 {
+			deezerErr(w, deezerNoData, "no data")
+			return
+		}
+		full := deezerListing(n, listing[n-101]["title"].(string), "gbaye000000"+strconv.FormatInt(n-100, 10))
+		full["contributors"] = []map[string]any{
+			{"name": "Main Artist", "role": "Main"}, {"name": "Second Artist", "role": "Main"}, {"name": "Guest", "role": "Featured"},
+		}
+		json.NewEncoder(w).Encode(full)
+	})
+	mux.HandleFunc("GET /album/{id}", func(w http.ResponseWriter, r *http.Request) {
+		deezerErr(w, deezerNoData, "no data")
+	})
+	mux.HandleFunc("GET /playlist/{id}", func(w http.ResponseWriter, r *http.Request) {
+		// Any other error object comes back with a 200 status too
+		deezerErr(w, 200, "Permission denied")
+	})
+
+	srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return f, &DeezerSource{BaseURL: srv.URL, HTTPClient: srv.Client()}
+}
+
+func TestDeezerPlaylistPagesAndISRCs(t *testing.T) {
+	f, s := newFakeDeezer(t)
+
+	res, err := s.Extract(context.Background(), Request{URL: "https://www.deezer.com/en/playlist/1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Name != "Road Trip" || res.Meta["kind"] != "playlist" {
+		t.Errorf("name %q, kind %v", res.Name, res.Meta["kind"])
+	}
+	if len(res.Tracks) != 5 {
+		t.Fatalf("%d tracks, want all 5 across three pages", len(res.Tracks))
+	}
+
+	// Only the tracks listed without an ISRC are fetched on their own
+	calls := slices.Clone(f.trackCalls)
+	slices.Sort(calls)
+	if want := []string{"101", "103", "104", "105"}; !slices.Equal(calls, want) {
+		t.Errorf("track requests = %v, want %v", calls, want)
+	}
+
+	first := res.Tracks[0]
+	if first.Title != "One" || first.ISRC != "GBAYE0000001" || first.Artist != "Main Artist, Second Artist" ||
+		first.Album != "Some Album" || first.SourceID != "101" || first.DurationMs != 200000 || first.Type != "deezer" {
+		t.Errorf("first track = %+v", first)
+	}
+	if tr := res.Tracks[1]; tr.ISRC != "GBAYE0000002" || tr.Artist != "Main Artist" {
+		t.Errorf("listed ISRC: track = %+v", tr)
+	}
+	// A track that cannot be fetched keeps its listing data
+	if tr := res.Tracks[3]; tr.Title != "Four" || tr.ISRC != "" || tr.SourceID != "104" {
+		t.Errorf("unfetchable track = %+v", tr)
+	}
+}
+
+func TestDeezerQuotaRetry(t *testing.T) {
+	defer func(d time.Duration) { deezerQuotaWait = d }(deezerQuotaWait)
+	deezerQuotaWait = time.Millisecond
+
+	f, s := newFakeDeezer(t)
+	res, err := s.Extract(context.Background(), Request{URL: "https://www.deezer.com/album/5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.quotaHits != 2 || res.Name != "Quota Album" || len(res.Tracks) != 1 {
+		t.Errorf("%d album requests, name %q, tracks %+v; want one retry", f.quotaHits, res.Name, res.Tracks)
+	}
+}
+
+func TestDeezerErrors(t *testing.T) {
+	_, s := newFakeDeezer(t)
+	var inputErr *InputError
+
+	_, err := s.Extract(context.Background(), Request{URL: "https://www.deezer.com/album/9"})
+	if !errors.As(err, &inputErr) || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("missing album: err = %v, want an InputError", err)
+	}
+
+	_, err = s.Extract(context.Background(), Request{URL: "https://www.deezer.com/playlist/3"})
+	var dzErr *deezerError
+	if !errors.As(err, &dzErr) || dzErr.Code != 200 || errors.As(err, &inputErr) {
+		t.Errorf("error object with status 200: err = %v, want a deezerError", err)
+	}
+
+	_, err = s.Extract(context.Background(), Request{URL: "https://www.deezer.com/artist/27"})
+	if !errors.As(err, &inputErr) {
+		t.Errorf("artist URL: err = %v, want an InputError", err)
+	}
+
+	_, err = s.Extract(context.Background(), Request{URL: "https://www.deezer.com.evil.example/playlist/1"})
+	if !errors.As(err, &inputErr) {
+		t.Errorf("foreign host: err = %v, want an InputError", err)
+	}
+}
@@ -0,0 +1,231 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"dbh-go-srv/internal/models"
+	"dbh-go-srv/internal/spotifetch"
+	"github.com/zmb3/spotify/v2"
+)
+
+// DiscographyFilter narrows what an artist or discography URL converts to.
+// The zero value takes every album, single and compilation and drops duplicates.
+type DiscographyFilter struct {
+	// AlbumTypes limits releases to "album", "single" and/or "compilation".
+	AlbumTypes []string `json:"album_types,omitempty"`
+	// ReleasedAfter and ReleasedBefore are inclusive bounds as YYYY, YYYY-MM or
+	// YYYY-MM-DD. Releases without a known date are skipped when either is set.
+	ReleasedAfter  string `json:"released_after,omitempty"`
+	ReleasedBefore string `json:"released_before,omitempty"`
+	// KeepDuplicates keeps a song once per release it appears on.
+	KeepDuplicates bool `json:"keep_duplicates,omitempty"`
+}
+
+var releaseDatePattern = regexp.MustCompile(`^\d{4}(-\d{2}(-\d{2})?)?$`)
+
+// Validate checks the filter before any fetching starts.
+func (f DiscographyFilter) Validate() error {
+	for _, t := range f.AlbumTypes {
+		switch strings.ToLower(t) {
+		case "album", "single", "compilation":
+		default:
+			return fmt.Errorf("unknown album type %q (want album, single or compilation)", t)
+		}
+	}
+	for _, d := range []string{f.ReleasedAfter, f.ReleasedBefore} {
+		if d != "" && !releaseDatePattern.MatchString(d) {
+			return fmt.Errorf("invalid release date %q (want YYYY, YYYY-MM or YYYY-MM-DD)", d)
+		}
+	}
+	return nil
+}
+
+// allows reports whether a release of the given type and date passes the filter.
+// Dates are compared on their common precision, so "2020" matches all of 2020.
+func (f DiscographyFilter) allows(albumType, releaseDate string) bool {
+	if len(f.AlbumTypes) > 0 {
+		ok := false
+		for _, t := range f.AlbumTypes {
+			if strings.EqualFold(t, albumType) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+
+	if f.ReleasedAfter == "" && f.ReleasedBefore == "" {
+		return true
+	}
+	if releaseDate == "" {
+		return false
+	}
+	if f.ReleasedAfter != "" && compareDatePrefix(releaseDate, f.ReleasedAfter) < 0 {
+		return false
+	}
+	if f.ReleasedBefore != "" && compareDatePrefix(releaseDate, f.ReleasedBefore) > 0 {
+		return false
+	}
+	return true
+}
+
+func compareDatePrefix(a, b string) int {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	return strings.Compare(a[:n], b[:n])
+}
+
+// releaseTrack is a track together with the type of release it was found on,
+// which decides which copy survives deduplication.
+type releaseTrack struct {
+	track     models.Track
+	albumType string
+}
+
+// releasePriority prefers the album version of a song over singles and
+// compilations.
+func releasePriority(albumType string) int {
+	switch strings.ToLower(albumType) {
+	case "album":
+		return 3
+	case "single":
+		return 2
+	case "compilation":
+		return 1
+	}
+	return 0
+}
+
+// dedupeReleaseTracks collapses songs that appear on several releases into one
+// track, keeping the position of the first appearance and the copy from the
+// highest-priority release.
+func dedupeReleaseTracks(in []releaseTrack) []models.Track {
+	index := make(map[string]int, len(in))
+	kept := make([]releaseTrack, 0, len(in))
+
+	for _, rt := range in {
+		key := dedupeKey(rt.track)
+		if i, seen := index[key]; seen {
+			if releasePriority(rt.albumType) > releasePriority(kept[i].albumType) {
+				kept[i] = rt
+			}
+			continue
+		}
+		index[key] = len(kept)
+		kept = append(kept, rt)
+	}
+
+	out := make([]models.Track, len(kept))
+	for i, rt := range kept {
+		out[i] = rt.track
+	}
+	return out
+}
+
+func dedupeKey(t models.Track) string {
+	norm := func(s string) string {
+		return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+	}
+	return norm(t.Artist) + "|" + norm(t.Title)
+}
+
+// discographyTracks converts a spotifetch discography into tracks.
+func discographyTracks(v *spotifetch.ArtistDiscographyPayload, filter DiscographyFilter) []models.Track {
+	rts := make([]releaseTrack, 0, len(v.TrackList))
+	for _, t := range v.TrackList {
+		rts = append(rts, releaseTrack{
+			track: models.Track{
+				Title:    t.Name,
+				Artist:   t.Artists,
+				Album:    t.AlbumName,
+				ISRC:     t.ISRC,
+				SourceID: t.SpotifyID,
+				Type:     "spotify",
+			},
+			albumType: t.AlbumType,
+		})
+	}
+	return finishReleaseTracks(rts, filter)
+}
+
+func finishReleaseTracks(rts []releaseTrack, filter DiscographyFilter) []models.Track {
+	if filter.KeepDuplicates {
+		out := make([]models.Track, len(rts))
+		for i, rt := range rts {
+			out[i] = rt.track
+		}
+		return out
+	}
+	return dedupeReleaseTracks(rts)
+}
+
+// albumTypesForGroup maps a discography URL tab to Web API album types.
+func albumTypesForGroup(group string) []spotify.AlbumType {
+	switch group {
+	case "album":
+		return []spotify.AlbumType{spotify.AlbumTypeAlbum}
+	case "single":
+		return []spotify.AlbumType{spotify.AlbumTypeSingle}
+	case "compilation":
+		return []spotify.AlbumType{spotify.AlbumTypeCompilation}
+	default:
+		return []spotify.AlbumType{spotify.AlbumTypeAlbum, spotify.AlbumTypeSingle, spotify.AlbumTypeCompilation}
+	}
+}
+
+// handleArtist is the Web API fallback for artist and discography URLs.
+func (p *SpotifyParser) handleArtist(ctx context.Context, id spotify.ID, group string, filter DiscographyFilter) ([]models.Track, string, error) {
+	artist, err := p.client.GetArtist(ctx, id)
+	if err != nil {
+		return nil, "", fmt.Errorf("get artist: %w", err)
+	}
+
+	page, err := p.client.GetArtistAlbums(ctx, id, albumTypesForGroup(group), spotify.Limit(50))
+	if err != nil {
+		return nil, "", fmt.Errorf("get artist albums: %w", err)
+	}
+
+	var albums []spotify.SimpleAlbum
+	for {
+		for _, a := range page.Albums {
+			if filter.allows(a.AlbumType, a.ReleaseDate) {
+				albums = append(albums, a)
+			}
+		}
+		err = p.client.NextPage(ctx, page)
+		if err == spotify.ErrNoMorePages {
+			break
+		}
+		if err != nil {
+			return nil, artist.Name, fmt.Errorf("artist albums pagination error: %w", err)
+		}
+	}
+
+	var rts []releaseTrack
+	for _, a := range albums {
+		if err := ctx.Err(); err != nil {
+			return nil, artist.Name, err
+		}
+
+		tracks, _, err := p.handleAlbum(ctx, a.ID)
+		if err != nil {
+			if p.debugMode {
+				log.Printf("[SPOTIFY] skipping album %q: %v", a.Name, err)
+			}
+			continue
+		}
+		for _, t := range tracks {
+			rts = append(rts, releaseTrack{track: t, albumType: a.AlbumType})
+		}
+	}
+
+	return finishReleaseTracks(rts, filter), artist.Name, nil
+}
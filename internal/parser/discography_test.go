@@ -0,0 +1,104 @@
+package parser
+
+import (
+	"testing"
+
+	"dbh-go-srv/internal/spotifetch"
+)
+
+func TestDiscographyFilterValidate(t *testing.T) {
+	tests := []struct {
+		filter  DiscographyFilter
+		wantErr bool
+	}{
+		{DiscographyFilter{}, false},
+		{DiscographyFilter{AlbumTypes: []string{"album", "Single", "COMPILATION"}}, false},
+		{DiscographyFilter{AlbumTypes: []string{"album", "appears_on"}}, true},
+		{DiscographyFilter{ReleasedAfter: "2020"}, false},
+		{DiscographyFilter{ReleasedAfter: "2020-06", ReleasedBefore: "2021-01-31"}, false},
+		{DiscographyFilter{ReleasedAfter: "20"}, true},
+		{DiscographyFilter{ReleasedBefore: "2020/06/01"}, true},
+		{DiscographyFilter{ReleasedBefore: "2020-6"}, true},
+		{DiscographyFilter{ReleasedAfter: "last year"}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.filter.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) = %v, want error %v", tt.filter, err, tt.wantErr)
+		}
+	}
+}
+
+func TestDiscographyFilterAllows(t *testing.T) {
+	tests := []struct {
+		name                   string
+		filter                 DiscographyFilter
+		albumType, releaseDate string
+		want                   bool
+	}{
+		{"no filter", DiscographyFilter{}, "single", "", true},
+		{"type kept", DiscographyFilter{AlbumTypes: []string{"album", "single"}}, "Single", "2020-01-01", true},
+		{"type dropped", DiscographyFilter{AlbumTypes: []string{"album"}}, "compilation", "2020-01-01", false},
+
+		{"after, inside", DiscographyFilter{ReleasedAfter: "2020-06-01"}, "album", "2020-06-01", true},
+		{"after, outside", DiscographyFilter{ReleasedAfter: "2020-06-01"}, "album", "2020-05-31", false},
+		{"before, inside", DiscographyFilter{ReleasedBefore: "2020-06-01"}, "album", "2020-06-01", true},
+		{"before, outside", DiscographyFilter{ReleasedBefore: "2020-06-01"}, "album", "2020-06-02", false},
+		{"range", DiscographyFilter{ReleasedAfter: "2019", ReleasedBefore: "2020"}, "album", "2020-12-31", true},
+		{"range, outside", DiscographyFilter{ReleasedAfter: "2019", ReleasedBefore: "2020"}, "album", "2021-01-01", false},
+		{"unknown date", DiscographyFilter{ReleasedAfter: "2019"}, "album", "", false},
+
+		// Partial release dates compare on the precision both sides have
+		{"year release, day bound", DiscographyFilter{ReleasedAfter: "2020-06-15"}, "album", "2020", true},
+		{"year release, earlier year", DiscographyFilter{ReleasedAfter: "2020-06-15"}, "album", "2019", false},
+		{"month release, same month", DiscographyFilter{ReleasedBefore: "2020-06-15"}, "album", "2020-06", true},
+		{"month release, later month", DiscographyFilter{ReleasedBefore: "2020-06-15"}, "album", "2020-07", false},
+		{"month bound, day release", DiscographyFilter{ReleasedAfter: "2020-06"}, "album", "2020-06-30", true},
+		{"month bound, earlier day", DiscographyFilter{ReleasedAfter: "2020-06"}, "album", "2020-05-31", false},
+
+		{"type and date", DiscographyFilter{AlbumTypes: []string{"single"}, ReleasedAfter: "2020"}, "album", "2021", false},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.allows(tt.albumType, tt.releaseDate); got != tt.want {
+			t.Errorf("%s: allows(%q, %q) = %v, want %v", tt.name, tt.albumType, tt.releaseDate, got, tt.want)
+		}
+	}
+}
+
+func TestDiscographyTracksDedupe(t *testing.T) {
+	payload := &spotifetch.ArtistDiscographyPayload{TrackList: []spotifetch.AlbumTrackMetadata{
+		// The single comes out first, then the album with the same song
+		{SpotifyID: "single-1", Name: "Hit Song", Artists: "Band", AlbumName: "Hit Song", AlbumType: "single"},
+		{SpotifyID: "album-1", Name: "Opener", Artists: "Band", AlbumName: "Record", AlbumType: "album"},
+		{SpotifyID: "album-2", Name: "Hit  song", Artists: "BAND", AlbumName: "Record", AlbumType: "album", ISRC: "GBAAA2000001"},
+		// A compilation never replaces the album or single copy
+		{SpotifyID: "comp-1", Name: "Hit Song", Artists: "Band", AlbumName: "Best Of", AlbumType: "compilation"},
+		{SpotifyID: "comp-2", Name: "Opener", Artists: "Band", AlbumName: "Best Of", AlbumType: "compilation"},
+		// Only on a compilation, and a cover by someone else
+		{SpotifyID: "comp-3", Name: "Rarity", Artists: "Band", AlbumName: "Best Of", AlbumType: "compilation"},
+		{SpotifyID: "other-1", Name: "Hit Song", Artists: "Other Band", AlbumName: "Covers", AlbumType: "album"},
+	}}
+
+	got := discographyTracks(payload, DiscographyFilter{})
+	want := []struct{ sourceID, album string }{
+		{"album-2", "Record"}, // in the single's position
+		{"album-1", "Record"},
+		{"comp-3", "Best Of"},
+		{"other-1", "Covers"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d tracks, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		if got[i].SourceID != w.sourceID || got[i].Album != w.album || got[i].Type != "spotify" {
+			t.Errorf("track %d = %+v, want %s on %q", i, got[i], w.sourceID, w.album)
+		}
+	}
+	if got[0].ISRC != "GBAAA2000001" {
+		t.Errorf("kept copy ISRC = %q", got[0].ISRC)
+	}
+
+	all := discographyTracks(payload, DiscographyFilter{KeepDuplicates: true})
+	if len(all) != len(payload.TrackList) {
+		t.Errorf("KeepDuplicates: %d tracks, want %d", len(all), len(payload.TrackList))
+	}
+}
@@ -0,0 +1,147 @@
+package parser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func lastfmTracks(n int) []map[string]any {
+	tracks := make([]map[string]any, n)
+	for i := range tracks {
+		id := strconv.Itoa(i + 1)
+		tracks[i] = map[string]any{
+			"name":   "Track " + id,
+			"mbid":   "",
+			"url":    "https://www.last.fm/music/Artist/_/Track+" + id,
+			"artist": map[string]any{"name": "Artist", "mbid": ""},
+		}
+	}
+	return tracks
+}
+
+// newFakeLastFM serves user "alice" with 250 loved tracks and three top
+// tracks, user "bob" with a single loved track, and a private user "carol".
+func newFakeLastFM(t *testing.T) *LastFMSource {
+	t.Helper()
+	loved := lastfmTracks(250)
+	loved[0]["mbid"] = "b1a9c0e9-d987-4042-ae91-78d6a3267d69"
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("api_key") != "test-key" || q.Get("format") != "json" {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]any{"error": 10, "message": "Invalid API key"})
+			return
+		}
+		page, _ := strconv.Atoi(q.Get("page"))
+		limit, _ := strconv.Atoi(q.Get("limit"))
+
+		switch {
+		case q.Get("user") == "carol":
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]any{"error": 17, "message": "Login: User required to be logged in"})
+		case q.Get("method") == "user.getlovedtracks" && q.Get("user") == "alice":
+			start, end := (page-1)*limit, min(page*limit, len(loved))
+			json.NewEncoder(w).Encode(map[string]any{"lovedtracks": map[string]any{
+				"track": loved[start:end],
+				"@attr": map[string]any{"page": strconv.Itoa(page), "totalPages": strconv.Itoa((len(loved) + limit - 1) / limit)},
+			}})
+		case q.Get("method") == "user.getlovedtracks" && q.Get("user") == "bob":
+			// A list of one comes back as a bare object
+			json.NewEncoder(w).Encode(map[string]any{"lovedtracks": map[string]any{
+				"track": lastfmTracks(1)[0],
+				"@attr": map[string]any{"page": "1", "totalPages": "1"},
+			}})
+		case q.Get("method") == "user.gettoptracks" && q.Get("period") == "12month":
+			top := lastfmTracks(3)
+			for _, tr := range top {
+				tr["duration"] = "215"
+			}
+			json.NewEncoder(w).Encode(map[string]any{"toptracks": map[string]any{
+				"track": top,
+				"@attr": map[string]any{"page": "1", "totalPages": "1"},
+			}})
+		default:
+			json.NewEncoder(w).Encode(map[string]any{"error": 6, "message": "User not found"})
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return &LastFMSource{BaseURL: srv.URL + "/2.0/", APIKey: "test-key", HTTPClient: srv.Client()}
+}
+
+func TestLastFMLovedTracksPaged(t *testing.T) {
+	s := newFakeLastFM(t)
+
+	res, err := s.Extract(context.Background(), Request{URL: "https://www.last.fm/user/alice/loved"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Name != "alice's loved tracks" || len(res.Tracks) != 250 {
+		t.Fatalf("name %q, %d tracks; want all 250 across two pages", res.Name, len(res.Tracks))
+	}
+	first := res.Tracks[0]
+	if first.Title != "Track 1" || first.Artist != "Artist" || first.MBID != "b1a9c0e9-d987-4042-ae91-78d6a3267d69" ||
+		first.SourceID != "https://www.last.fm/music/Artist/_/Track+1" || first.Type != "lastfm" {
+		t.Errorf("first track = %+v", first)
+	}
+}
+
+func TestLastFMSingleTrack(t *testing.T) {
+	s := newFakeLastFM(t)
+
+	res, err := s.Extract(context.Background(), Request{URL: "https://www.last.fm/de/user/bob/loved"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Tracks) != 1 || res.Tracks[0].Title != "Track 1" {
+		t.Errorf("tracks = %+v", res.Tracks)
+	}
+}
+
+func TestLastFMTopTracks(t *testing.T) {
+	s := newFakeLastFM(t)
+
+	res, err := s.Extract(context.Background(), Request{URL: "https://www.last.fm/user/alice/library/tracks?date_preset=LAST_365_DAYS&limit=2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Meta["period"] != "12month" || len(res.Tracks) != 2 {
+		t.Fatalf("period %v, %d tracks; want 12month and the limit of 2", res.Meta["period"], len(res.Tracks))
+	}
+	if res.Tracks[0].DurationMs != 215000 {
+		t.Errorf("duration = %dms, want 215000", res.Tracks[0].DurationMs)
+	}
+}
+
+func TestLastFMErrors(t *testing.T) {
+	s := newFakeLastFM(t)
+	var inputErr *InputError
+
+	_, err := s.Extract(context.Background(), Request{URL: "https://www.last.fm/user/carol/loved"})
+	if !errors.As(err, &inputErr) || !strings.Contains(err.Error(), "private") {
+		t.Errorf("private user: err = %v, want an InputError", err)
+	}
+
+	_, err = s.Extract(context.Background(), Request{URL: "https://www.last.fm/user/nobody/loved"})
+	if !errors.As(err, &inputErr) || !strings.Contains(err.Error(), "User not found") {
+		t.Errorf("unknown user: err = %v, want an InputError with Last.fm's message", err)
+	}
+
+	_, err = s.Extract(context.Background(), Request{URL: "https://www.last.fm/user/alice/library/tracks?date_preset=YESTERDAY"})
+	if !errors.As(err, &inputErr) {
+		t.Errorf("bad date_preset: err = %v, want an InputError", err)
+	}
+
+	s.APIKey = "revoked"
+	_, err = s.Extract(context.Background(), Request{URL: "https://www.last.fm/user/alice/loved"})
+	if err == nil || errors.As(err, &inputErr) {
+		t.Errorf("bad API key: err = %v, want a server error", err)
+	}
+}
@@ -0,0 +1,226 @@
+package parser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"dbh-go-srv/internal/models"
+)
+
+// DefaultListenBrainzBaseURL is the ListenBrainz API.
+const DefaultListenBrainzBaseURL = "https://api.listenbrainz.org/1"
+
+var (
+	lbPlaylistRegex = regexp.MustCompile(`^/playlist/([0-9a-f-]{36})`)
+	lbTopRegex      = regexp.MustCompile(`^/user/([^/]+)/stats/top-tracks`)
+	lbRecordingURL  = regexp.MustCompile(`musicbrainz\.org/recording/([0-9a-f-]{36})`)
+)
+
+// Stats ranges ListenBrainz computes top recordings for
+var lbRanges = map[string]bool{
+	"this_week": true, "this_month": true, "this_year": true,
+	"week": true, "month": true, "quarter": true, "year": true, "half_yearly": true, "all_time": true,
+}
+
+// ListenBrainzSource imports public ListenBrainz playlists and a user's top
+// recordings. Both carry MusicBrainz recording IDs, which the matcher turns
+// into ISRCs.
+type ListenBrainzSource struct {
+	// BaseURL is the API root; tests point it at a local fake server.
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// NewListenBrainzSource returns a source for the public API.
+func NewListenBrainzSource() *ListenBrainzSource {
+	return &ListenBrainzSource{
+		BaseURL:    DefaultListenBrainzBaseURL,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *ListenBrainzSource) Name() string { return "listenbrainz" }
+
+func (s *ListenBrainzSource) Detect(req Request) bool {
+	return hostMatches(req.URL, "listenbrainz.org")
+}
+
+func (s *ListenBrainzSource) Extract(ctx context.Context, req Request) (*Result, error) {
+	if !s.Detect(req) {
+		return nil, inputErrorf("Invalid ListenBrainz URL")
+	}
+	u, _ := url.Parse(strings.TrimSpace(req.URL))
+
+	if m := lbPlaylistRegex.FindStringSubmatch(u.Path); m != nil {
+		return s.playlist(ctx, m[1])
+	}
+	if m := lbTopRegex.FindStringSubmatch(u.Path); m != nil {
+		rng := u.Query().Get("range")
+		if rng == "" {
+			rng = "all_time"
+		}
+		if !lbRanges[rng] {
+			return nil, inputErrorf("Unsupported ListenBrainz range %q", rng)
+		}
+		count, err := countParam("count", u.Query().Get("count"))
+		if err != nil {
+			return nil, err
+		}
+		return s.topRecordings(ctx, m[1], rng, count)
+	}
+	return nil, inputErrorf("ListenBrainz URL must point to a playlist or a user's top tracks")
+}
+
+// playlist reads a playlist, which the API returns as JSPF.
+func (s *ListenBrainzSource) playlist(ctx context.Context, id string) (*Result, error) {
+	var body struct {
+		Playlist struct {
+			Title string `json:"title"`
+			Track []struct {
+				Title      string          `json:"title"`
+				Creator    string          `json:"creator"`
+				Album      string          `json:"album"`
+				Duration   int             `json:"duration"`
+				Identifier json.RawMessage `json:"identifier"`
+			} `json:"track"`
+		} `json:"playlist"`
+	}
+	if err := s.get(ctx, "/playlist/"+id, &body); err != nil {
+		return nil, err
+	}
+
+	res := &Result{Name: body.Playlist.Title, Meta: map[string]any{"kind": "playlist"}}
+	for i, jt := range body.Playlist.Track {
+		// JSPF allows one identifier or a list of them
+		var ids []string
+		if json.Unmarshal(jt.Identifier, &ids) != nil {
+			var one string
+			_ = json.Unmarshal(jt.Identifier, &one)
+			ids = []string{one}
+		}
+		mbid := ""
+		for _, id := range ids {
+			if m := lbRecordingURL.FindStringSubmatch(id); m != nil {
+				mbid = m[1]
+				break
+			}
+		}
+		if jt.Title == "" {
+			res.Warnings = append(res.Warnings, Warning{Row: i + 1, SourceID: mbid, Message: "track has no title"})
+			continue
+		}
+		res.Tracks = append(res.Tracks, models.Track{
+			Title:      jt.Title,
+			Artist:     jt.Creator,
+			Album:      jt.Album,
+			MBID:       mbid,
+			SourceID:   mbid,
+			DurationMs: jt.Duration,
+			Type:       "listenbrainz",
+		})
+	}
+	if len(res.Tracks) == 0 {
+		return nil, errors.New("listenbrainz: no tracks found")
+	}
+	return res, nil
+}
+
+// topRecordings reads up to count of a user's most listened recordings.
+func (s *ListenBrainzSource) topRecordings(ctx context.Context, user, rng string, count int) (*Result, error) {
+	const pageSize = 100
+	res := &Result{
+		Name: fmt.Sprintf("%s's top tracks (%s)", user, strings.ReplaceAll(rng, "_", " ")),
+		Meta: map[string]any{"kind": "top_tracks", "range": rng},
+	}
+	for offset := 0; offset < count; offset += pageSize {
+		var body struct {
+			Payload struct {
+				Recordings []struct {
+					TrackName     string `json:"track_name"`
+					ArtistName    string `json:"artist_name"`
+					ReleaseName   string `json:"release_name"`
+					RecordingMBID string `json:"recording_mbid"`
+				} `json:"recordings"`
+				Total int `json:"total_recording_count"`
+			} `json:"payload"`
+		}
+		q := url.Values{
+			"count":  {strconv.Itoa(min(pageSize, count-offset))},
+			"offset": {strconv.Itoa(offset)},
+			"range":  {rng},
+		}
+		path := "/stats/user/" + url.PathEscape(user) + "/recordings?" + q.Encode()
+		if err := s.get(ctx, path, &body); err != nil {
+			return nil, err
+		}
+		for _, r := range body.Payload.Recordings {
+			res.Tracks = append(res.Tracks, models.Track{
+				Title:    r.TrackName,
+				Artist:   r.ArtistName,
+				Album:    r.ReleaseName,
+				MBID:     r.RecordingMBID,
+				SourceID: r.RecordingMBID,
+				Type:     "listenbrainz",
+			})
+		}
+		if len(body.Payload.Recordings) < pageSize || offset+pageSize >= body.Payload.Total {
+			break
+		}
+	}
+	if len(res.Tracks) == 0 {
+		return nil, errors.New("listenbrainz: no tracks found")
+	}
+	return res, nil
+}
+
+func (s *ListenBrainzSource) get(ctx context.Context, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(s.BaseURL, "/")+path, nil)
+	if err != nil {
+		return err
+	}
+
+	client := s.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("listenbrainz: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return json.NewDecoder(resp.Body).Decode(out)
+	case http.StatusNoContent:
+		// Stats are computed periodically; new users have none yet
+		return &InputError{"ListenBrainz has no statistics for this user and range yet"}
+	case http.StatusNotFound:
+		return &InputError{"ListenBrainz user or playlist not found"}
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return &InputError{"ListenBrainz playlist is private"}
+	default:
+		return fmt.Errorf("listenbrainz: %s", resp.Status)
+	}
+}
+
+// countParam reads the number of top tracks asked for in the URL parameter
+// name, 100 by default.
+func countParam(name, raw string) (int, error) {
+	if raw == "" {
+		return 100, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 || n > 1000 {
+		return 0, inputErrorf("%s must be between 1 and 1000", name)
+	}
+	return n, nil
+}
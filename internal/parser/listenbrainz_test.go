@@ -0,0 +1,160 @@
+package parser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	lbPlaylistID = "4f5ad6bd-2b0e-4e1c-9b1a-2a0a9f4b5c6d"
+	lbMBID1      = "b1a9c0e9-d987-4042-ae91-78d6a3267d69"
+	lbMBID2      = "8f3471b5-7e6a-48da-86a9-c1c07a0f47ae"
+)
+
+type fakeListenBrainz struct {
+	mu      sync.Mutex
+	offsets []int // offset of every stats request, in order
+}
+
+// newFakeListenBrainz serves a JSPF playlist and the top recordings of "alice"
+// (200 in all), "newbie" (no statistics yet) and nobody else.
+func newFakeListenBrainz(t *testing.T) (*fakeListenBrainz, *ListenBrainzSource) {
+	t.Helper()
+	f := &fakeListenBrainz{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /playlist/"+lbPlaylistID, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"playlist": map[string]any{
+			"title": "Weekly Jams",
+			"track": []map[string]any{
+				// JSPF allows a list of identifiers...
+				{"title": "First", "creator": "Artist A", "album": "Album A", "duration": 215000,
+					"identifier": []string{"https://example.org/other", "https://musicbrainz.org/recording/" + lbMBID1}},
+				// ...or a single one
+				{"title": "Second", "creator": "Artist B", "identifier": "https://musicbrainz.org/recording/" + lbMBID2},
+				{"title": "", "creator": "Artist C", "identifier": "https://musicbrainz.org/recording/" + lbMBID1},
+				{"title": "No ID", "creator": "Artist D"},
+			},
+		}})
+	})
+	mux.HandleFunc("GET /playlist/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	mux.HandleFunc("GET /stats/user/alice/recordings", func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		count, _ := strconv.Atoi(r.URL.Query().Get("count"))
+		f.mu.Lock()
+		f.offsets = append(f.offsets, offset)
+		f.mu.Unlock()
+
+		const total = 200
+		var recs []map[string]any
+		for i := offset; i < min(offset+count, total); i++ {
+			recs = append(recs, map[string]any{
+				"track_name": "Song " + strconv.Itoa(i+1), "artist_name": "Artist", "release_name": "Album",
+				"recording_mbid": lbMBID1,
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"payload": map[string]any{
+			"recordings": recs, "total_recording_count": total, "range": r.URL.Query().Get("range"),
+		}})
+	})
+	mux.HandleFunc("GET /stats/user/newbie/recordings", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /stats/user/{user}/recordings", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return f, &ListenBrainzSource{BaseURL: srv.URL, HTTPClient: srv.Client()}
+}
+
+func TestListenBrainzPlaylist(t *testing.T) {
+	_, s := newFakeListenBrainz(t)
+
+	res, err := s.Extract(context.Background(), Request{URL: "https://listenbrainz.org/playlist/" + lbPlaylistID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Name != "Weekly Jams" || res.Meta["kind"] != "playlist" {
+		t.Errorf("name %q, kind %v", res.Name, res.Meta["kind"])
+	}
+	if len(res.Tracks) != 3 {
+		t.Fatalf("tracks = %+v, want 3", res.Tracks)
+	}
+	first := res.Tracks[0]
+	if first.Title != "First" || first.Artist != "Artist A" || first.Album != "Album A" || first.DurationMs != 215000 ||
+		first.MBID != lbMBID1 || first.SourceID != lbMBID1 || first.Type != "listenbrainz" {
+		t.Errorf("first track = %+v", first)
+	}
+	if res.Tracks[1].MBID != lbMBID2 {
+		t.Errorf("single identifier: MBID = %q, want %q", res.Tracks[1].MBID, lbMBID2)
+	}
+	if res.Tracks[2].MBID != "" {
+		t.Errorf("track without identifier: MBID = %q", res.Tracks[2].MBID)
+	}
+	if len(res.Warnings) != 1 || res.Warnings[0].Row != 3 || res.Warnings[0].SourceID != lbMBID1 {
+		t.Errorf("warnings = %+v, want one for the untitled row 3", res.Warnings)
+	}
+}
+
+func TestListenBrainzTopRecordingsPaging(t *testing.T) {
+	tests := []struct {
+		url     string
+		offsets []int
+		tracks  int
+	}{
+		// Stops at the user's total even though every page is full
+		{"https://listenbrainz.org/user/alice/stats/top-tracks?range=this_year&count=1000", []int{0, 100}, 200},
+		// Stops at the count asked for, with a short last request
+		{"https://listenbrainz.org/user/alice/stats/top-tracks?count=150", []int{0, 100}, 150},
+		{"https://listenbrainz.org/user/alice/stats/top-tracks", []int{0}, 100},
+	}
+	for _, tt := range tests {
+		f, s := newFakeListenBrainz(t)
+		res, err := s.Extract(context.Background(), Request{URL: tt.url})
+		if err != nil {
+			t.Errorf("%s: %v", tt.url, err)
+			continue
+		}
+		if !slices.Equal(f.offsets, tt.offsets) {
+			t.Errorf("%s: offsets = %v, want %v", tt.url, f.offsets, tt.offsets)
+		}
+		if len(res.Tracks) != tt.tracks {
+			t.Errorf("%s: %d tracks, want %d", tt.url, len(res.Tracks), tt.tracks)
+		}
+	}
+}
+
+func TestListenBrainzErrors(t *testing.T) {
+	_, s := newFakeListenBrainz(t)
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://listenbrainz.org/user/newbie/stats/top-tracks", "no statistics"},
+		{"https://listenbrainz.org/user/nobody/stats/top-tracks", "not found"},
+		{"https://listenbrainz.org/playlist/00000000-0000-0000-0000-000000000000", "private"},
+		{"https://listenbrainz.org/user/alice/stats/top-tracks?range=decade", "range"},
+		{"https://listenbrainz.org/user/alice/stats/top-tracks?count=0", "count"},
+		{"https://listenbrainz.org/user/alice/", "playlist or a user's top tracks"},
+	}
+	for _, tt := range tests {
+		_, err := s.Extract(context.Background(), Request{URL: tt.url})
+		var inputErr *InputError
+		if !errors.As(err, &inputErr) || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want an InputError mentioning %q", tt.url, err, tt.want)
+		}
+	}
+}
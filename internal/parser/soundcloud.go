@@ -0,0 +1,297 @@
+package parser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"dbh-go-srv/internal/models"
+)
+
+// DefaultSoundCloudBaseURL is the web player's API.
+const DefaultSoundCloudBaseURL = "https://api-v2.soundcloud.com"
+
+var (
+	scHydrationRegex = regexp.MustCompile(`(?s)window\.__sc_hydration\s*=\s*(\[.*?\]);\s*</script>`)
+	scScriptRegex    = regexp.MustCompile(`<script[^>]+src="(https://a-v2\.sndcdn\.com/assets/[^"]+\.js)"`)
+	scClientIDRegex  = regexp.MustCompile(`client_id\s*[:=]\s*"([0-9A-Za-z]{32})"`)
+	// Promotional tags uploaders put around the song name
+	scNoiseRegex = regexp.MustCompile(`(?i)\s*[(\[][^)\]]*\b(?:free\s*(?:download|dl)|out\s+now|buy\s*=\s*free)\b[^)\]]*[)\]]|^\s*premiere\s*:\s*`)
+)
+
+// SoundCloudSource imports public SoundCloud sets and tracks, including
+// on.soundcloud.com short links. Pages embed only the first few tracks of a set
+// in full, so the rest are fetched from the web player's API with the client ID
+// found in its scripts (or ClientID, when set).
+type SoundCloudSource struct {
+	// BaseURL is the API root; tests point it at a local fake server.
+	BaseURL    string
+	ClientID   string
+	HTTPClient *http.Client
+
+	mu       sync.Mutex
+	scrapeID string
+}
+
+// NewSoundCloudSource returns a source for the web player's API. An empty
+// clientID is scraped from the player's scripts.
+func NewSoundCloudSource(clientID string) *SoundCloudSource {
+	return &SoundCloudSource{
+		BaseURL:    DefaultSoundCloudBaseURL,
+		ClientID:   clientID,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (*SoundCloudSource) Name() string { return "soundcloud" }
+
+func (*SoundCloudSource) Detect(req Request) bool {
+	return hostMatches(req.URL, "soundcloud.com")
+}
+
+func (s *SoundCloudSource) Extract(ctx context.Context, req Request) (*Result, error) {
+	if !s.Detect(req) {
+		return nil, inputErrorf("Invalid SoundCloud URL")
+	}
+
+	client := s.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	pageURL := strings.TrimSpace(req.URL)
+	if hostMatches(pageURL, "on.soundcloud.com") {
+		resolved, err := resolveShortLink(ctx, client, pageURL)
+		if err != nil {
+			return nil, fmt.Errorf("resolve SoundCloud short link: %w", err)
+		}
+		// Only SoundCloud pages are fetched, wherever the link pointed
+		if !hostMatches(resolved, "soundcloud.com") {
+			return nil, inputErrorf("SoundCloud short link leads off SoundCloud")
+		}
+		pageURL = resolved
+	}
+
+	page, err := fetchPage(ctx, client, pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("fetch SoundCloud page: %w", err)
+	}
+	return s.fromPage(ctx, client, page)
+}
+
+// fromPage reads the set or track from a page's hydration data.
+func (s *SoundCloudSource) fromPage(ctx context.Context, client *http.Client, page []byte) (*Result, error) {
+	m := scHydrationRegex.FindSubmatch(page)
+	if m == nil {
+		return nil, errors.New("SoundCloud page has no embedded data")
+	}
+	var hydration []struct {
+		Hydratable string          `json:"hydratable"`
+		Data       json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(m[1], &hydration); err != nil {
+		return nil, fmt.Errorf("parse SoundCloud page data: %w", err)
+	}
+
+	for _, h := range hydration {
+		switch h.Hydratable {
+		case "playlist":
+			var set scPlaylist
+			if err := json.Unmarshal(h.Data, &set); err != nil {
+				return nil, fmt.Errorf("parse SoundCloud set: %w", err)
+			}
+			return s.playlist(ctx, client, page, set)
+		case "sound":
+			var t scTrack
+			if err := json.Unmarshal(h.Data, &t); err != nil {
+				return nil, fmt.Errorf("parse SoundCloud track: %w", err)
+			}
+			return &Result{Tracks: []models.Track{t.track("")}, Name: t.Title, Meta: map[string]any{"kind": "track"}}, nil
+		}
+	}
+	return nil, inputErrorf("SoundCloud URL must point to a public set or track")
+}
+
+type scPlaylist struct {
+	Title   string    `json:"title"`
+	SetType string    `json:"set_type"`
+	IsAlbum bool      `json:"is_album"`
+	Tracks  []scTrack `json:"tracks"`
+}
+
+type scTrack struct {
+	ID       int64  `json:"id"`
+	Title    string `json:"title"`
+	Duration int    `json:"duration"`
+	User     struct {
+		Username string `json:"username"`
+	} `json:"user"`
+	Publisher *struct {
+		Artist     string `json:"artist"`
+		AlbumTitle string `json:"album_title"`
+		ISRC       string `json:"isrc"`
+	} `json:"publisher_metadata"`
+}
+
+// track names the track. Label uploads carry publisher metadata with the real
+// artist; otherwise the title is split with the YouTube title rules, with the
+// uploader as the fallback artist.
+func (t scTrack) track(album string) models.Track {
+	out := models.Track{
+		SourceID:   strconv.FormatInt(t.ID, 10),
+		DurationMs: t.Duration,
+		Album:      album,
+		Type:       "soundcloud",
+	}
+	title := scNoiseRegex.ReplaceAllString(t.Title, "")
+
+	if p := t.Publisher; p != nil && p.Artist != "" {
+		// Label uploads often repeat the artist as "Artist - Title"
+		parsed := ParseYTTitle(title, p.Artist)
+		if !strings.EqualFold(parsed.Artist, p.Artist) {
+			parsed = normalizeAlbumTrack(title, p.Artist)
+		}
+		out.Artist, out.Title, out.Featuring = p.Artist, parsed.Title, parsed.Featuring
+		out.ISRC = strings.ToUpper(strings.ReplaceAll(p.ISRC, "-", ""))
+		if out.Album == "" {
+			out.Album = p.AlbumTitle
+		}
+	} else {
+		parsed := ParseYTTitle(title, t.User.Username)
+		out.Artist, out.Title, out.Featuring = parsed.Artist, parsed.Title, parsed.Featuring
+	}
+	return out
+}
+
+func (s *SoundCloudSource) playlist(ctx context.Context, client *http.Client, page []byte, set scPlaylist) (*Result, error) {
+	// Only the first tracks come complete; the rest are bare IDs
+	var missing []int64
+	for _, t := range set.Tracks {
+		if t.Title == "" {
+			missing = append(missing, t.ID)
+		}
+	}
+	if len(missing) > 0 {
+		full, err := s.fetchTracks(ctx, client, page, missing)
+		if err != nil {
+			return nil, err
+		}
+		for i, t := range set.Tracks {
+			if ft, ok := full[t.ID]; ok {
+				set.Tracks[i] = ft
+			}
+		}
+	}
+
+	album := ""
+	if set.IsAlbum || set.SetType == "album" || set.SetType == "ep" {
+		album = set.Title
+	}
+
+	res := &Result{Name: set.Title, Meta: map[string]any{"kind": "set"}}
+	for i, t := range set.Tracks {
+		if t.Title == "" {
+			// Removed or region-blocked tracks stay listed as bare IDs
+			res.Warnings = append(res.Warnings, Warning{Row: i + 1, SourceID: strconv.FormatInt(t.ID, 10), Message: "track is unavailable"})
+			continue
+		}
+		res.Tracks = append(res.Tracks, t.track(album))
+	}
+	return res, nil
+}
+
+// fetchTracks resolves track IDs through the API, 50 per request.
+func (s *SoundCloudSource) fetchTracks(ctx context.Context, client *http.Client, page []byte, ids []int64) (map[int64]scTrack, error) {
+	clientID, err := s.clientID(ctx, client, page)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[int64]scTrack, len(ids))
+	for i := 0; i < len(ids); i += 50 {
+		end := i + 50
+		if end > len(ids) {
+			end = len(ids)
+		}
+		parts := make([]string, 0, end-i)
+		for _, id := range ids[i:end] {
+			parts = append(parts, strconv.FormatInt(id, 10))
+		}
+		q := url.Values{"ids": {strings.Join(parts, ",")}, "client_id": {clientID}}
+
+		body, err := fetchPage(ctx, client, strings.TrimSuffix(s.BaseURL, "/")+"/tracks?"+q.Encode())
+		if err != nil {
+			// A stale scraped ID is the usual cause; scrape again next time
+			s.mu.Lock()
+			s.scrapeID = ""
+			s.mu.Unlock()
+			return nil, fmt.Errorf("fetch SoundCloud tracks: %w", err)
+		}
+		var tracks []scTrack
+		if err := json.Unmarshal(body, &tracks); err != nil {
+			return nil, fmt.Errorf("parse SoundCloud tracks: %w", err)
+		}
+		for _, t := range tracks {
+			out[t.ID] = t
+		}
+	}
+	return out, nil
+}
+
+// clientID returns the configured client ID, or the one found in the web
+// player's scripts, which is cached until the API rejects it.
+func (s *SoundCloudSource) clientID(ctx context.Context, client *http.Client, page []byte) (string, error) {
+	if s.ClientID != "" {
+		return s.ClientID, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.scrapeID != "" {
+		return s.scrapeID, nil
+	}
+
+	// The ID lives in one of the last bundles
+	scripts := scScriptRegex.FindAllSubmatch(page, -1)
+	for i := len(scripts) - 1; i >= 0; i-- {
+		js, err := fetchPage(ctx, client, string(scripts[i][1]))
+		if err != nil {
+			continue
+		}
+		if m := scClientIDRegex.FindSubmatch(js); m != nil {
+			s.scrapeID = string(m[1])
+			return s.scrapeID, nil
+		}
+	}
+	return "", errors.New("could not find a SoundCloud client ID; set SOUNDCLOUD_CLIENT_ID")
+}
+
+// fetchPage GETs a URL with a browser user agent and returns the body.
+func fetchPage(ctx context.Context, client *http.Client, pageURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0 Safari/537.36")
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, &InputError{"page not found; is it public?"}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 32<<20))
+}
@@ -0,0 +1,205 @@
+package parser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// hostTransport answers requests in process, by host, so pages on the real
+// SoundCloud hosts can be served from fixtures.
+type hostTransport map[string]http.Handler
+
+func (t hostTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	h, ok := t[r.URL.Host]
+	if !ok {
+		return nil, fmt.Errorf("unexpected request to %s", r.URL)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	resp := rec.Result()
+	resp.Request = r
+	return resp, nil
+}
+
+type fakeSoundCloud struct {
+	mu      sync.Mutex
+	batches []int // number of IDs in every /tracks request, in order
+}
+
+// newFakeSoundCloud serves the soundcloud_set.html fixture at
+// soundcloud.com/label/sets/night-set, a short link to it and one leading
+// elsewhere, and the API's /tracks, which knows every track but 1055.
+func newFakeSoundCloud(t *testing.T) (*fakeSoundCloud, *SoundCloudSource) {
+	t.Helper()
+	f := &fakeSoundCloud{}
+	page, err := os.ReadFile(filepath.Join("testdata", "soundcloud_set.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	site := http.NewServeMux()
+	site.HandleFunc("GET /label/sets/night-set", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(page)
+	})
+	short := http.NewServeMux()
+	short.HandleFunc("GET /good", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://soundcloud.com/label/sets/night-set", http.StatusFound)
+	})
+	short.HandleFunc("GET /evil", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://evil.example/label/sets/night-set", http.StatusFound)
+	})
+	api := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/tracks" || r.URL.Query().Get("client_id") != "test-id" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		ids := strings.Split(r.URL.Query().Get("ids"), ",")
+		f.mu.Lock()
+		f.batches = append(f.batches, len(ids))
+		f.mu.Unlock()
+
+		var tracks []map[string]any
+		for _, id := range ids {
+			n, _ := strconv.Atoi(id)
+			switch {
+			case n == 1055:
+				// Removed, so left out of the answer
+			case n == 1003:
+				tracks = append(tracks, map[string]any{"id": n, "title": "DJ Foo - Bar [Free DL]", "duration": 180000,
+					"user": map[string]any{"username": "djfoo"}})
+			default:
+				tracks = append(tracks, map[string]any{"id": n, "title": "Song " + id, "duration": 200000,
+					"user": map[string]any{"username": "Some Uploader"}})
+			}
+		}
+		json.NewEncoder(w).Encode(tracks)
+	})
+
+	client := &http.Client{Transport: hostTransport{
+		"soundcloud.com":        site,
+		"on.soundcloud.com":     short,
+		"api-v2.soundcloud.com": api,
+		"evil.example":          http.NotFoundHandler(),
+	}}
+	return f, &SoundCloudSource{BaseURL: DefaultSoundCloudBaseURL, ClientID: "test-id", HTTPClient: client}
+}
+
+func TestSoundCloudSet(t *testing.T) {
+	f, s := newFakeSoundCloud(t)
+
+	res, err := s.Extract(context.Background(), Request{URL: "https://soundcloud.com/label/sets/night-set"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Name != "Night Set" || res.Meta["kind"] != "set" {
+		t.Errorf("name %q, kind %v", res.Name, res.Meta["kind"])
+	}
+	// 53 bare IDs, fetched 50 at a time
+	if want := []int{50, 3}; !slices.Equal(f.batches, want) {
+		t.Errorf("API batches = %v, want %v", f.batches, want)
+	}
+	if len(res.Tracks) != 54 {
+		t.Fatalf("%d tracks, want 54 (55 less the removed one)", len(res.Tracks))
+	}
+	if len(res.Warnings) != 1 || res.Warnings[0].Row != 55 || res.Warnings[0].SourceID != "1055" {
+		t.Errorf("warnings = %+v, want one for row 55", res.Warnings)
+	}
+
+	tests := []struct {
+		i                                     int
+		title, artist, featuring, album, isrc string
+	}{
+		// Publisher metadata names the artist; the promotional tags go
+		{0, "Night Drive", "Kavinsky", "", "OutRun", "FRZ031300001"},
+		// A title repeating the publisher's artist is split
+		{1, "Nightcall", "Kavinsky", "Lovefoxxx", "", ""},
+		// Backfilled tracks without metadata are split like YouTube titles
+		{2, "Bar", "DJ Foo", "", "", ""},
+		{3, "Song 1004", "Some Uploader", "", "", ""},
+	}
+	for _, tt := range tests {
+		tr := res.Tracks[tt.i]
+		if tr.Title != tt.title || tr.Artist != tt.artist || tr.Featuring != tt.featuring || tr.Album != tt.album || tr.ISRC != tt.isrc {
+			t.Errorf("track %d = %+v, want %q by %q (feat. %q) on %q, ISRC %q", tt.i, tr, tt.title, tt.artist, tt.featuring, tt.album, tt.isrc)
+		}
+	}
+	if tr := res.Tracks[2]; tr.SourceID != "1003" || tr.DurationMs != 180000 || tr.Type != "soundcloud" {
+		t.Errorf("backfilled track = %+v", tr)
+	}
+}
+
+func TestSoundCloudShortLinks(t *testing.T) {
+	_, s := newFakeSoundCloud(t)
+
+	res, err := s.Extract(context.Background(), Request{URL: "https://on.soundcloud.com/good"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Name != "Night Set" {
+		t.Errorf("name = %q", res.Name)
+	}
+
+	_, err = s.Extract(context.Background(), Request{URL: "https://on.soundcloud.com/evil"})
+	var inputErr *InputError
+	if !errors.As(err, &inputErr) {
+		t.Errorf("short link off SoundCloud: err = %v, want an InputError", err)
+	}
+}
+
+func TestSoundCloudPages(t *testing.T) {
+	_, s := newFakeSoundCloud(t)
+	hydrated := func(v any) []byte {
+		data, _ := json.Marshal(v)
+		return []byte("<script>window.__sc_hydration = " + string(data) + ";</script>")
+	}
+
+	res, err := s.fromPage(context.Background(), s.HTTPClient, hydrated([]map[string]any{{"hydratable": "sound", "data": map[string]any{
+		"id": 5, "title": "Deep Cut (Out Now on Vinyl)", "duration": 300000, "user": map[string]any{"username": "Some Band"},
+	}}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Meta["kind"] != "track" || len(res.Tracks) != 1 || res.Tracks[0].Title != "Deep Cut" || res.Tracks[0].Artist != "Some Band" {
+		t.Errorf("track page = %v %+v", res.Meta, res.Tracks)
+	}
+
+	var inputErr *InputError
+	_, err = s.fromPage(context.Background(), s.HTTPClient, hydrated([]map[string]any{{"hydratable": "user", "data": map[string]any{"id": 1}}}))
+	if !errors.As(err, &inputErr) {
+		t.Errorf("user page: err = %v, want an InputError", err)
+	}
+	if _, err := s.fromPage(context.Background(), s.HTTPClient, []byte("<html></html>")); err == nil {
+		t.Error("fromPage accepted a page without hydration data")
+	}
+}
+
+func TestSCNoiseRegex(t *testing.T) {
+	tests := []struct{ title, want string }{
+		{"Night Drive [Free Download]", "Night Drive"},
+		{"Night Drive (FREE DL)", "Night Drive"},
+		{"Night Drive (Buy = Free)", "Night Drive"},
+		{"Night Drive [OUT NOW on Label]", "Night Drive"},
+		{"Premiere: Night Drive", "Night Drive"},
+		{"premiere : Night Drive", "Night Drive"},
+		// Ordinary brackets stay
+		{"Night Drive (Extended Mix)", "Night Drive (Extended Mix)"},
+		{"Freedom (Live)", "Freedom (Live)"},
+		{"The Premiere: Night Drive", "The Premiere: Night Drive"},
+	}
+	for _, tt := range tests {
+		if got := scNoiseRegex.ReplaceAllString(tt.title, ""); got != tt.want {
+			t.Errorf("noise stripped from %q = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+
+	"dbh-go-srv/internal/models"
+)
+
+// Source is one platform tracks can be imported from. Adding a platform means
+// implementing Source and registering it in the server's Registry.
+type Source interface {
+	// Name is the request "type" the source answers to, e.g. "spotify".
+	Name() string
+	// Detect reports whether the source recognizes the request's URL or upload.
+	// It is used when the request names no type, and should be cheap and offline.
+	Detect(req Request) bool
+	// Extract pulls the tracks. Problems with the request itself are returned as
+	// *InputError so callers can tell them from extraction failures.
+	Extract(ctx context.Context, req Request) (*Result, error)
+}
+
+// Request is what a conversion or sync asks a source to extract.
+type Request struct {
+	URL string
+	// Upload is set for file-based sources such as CSV.
+	Upload *Upload
+	// UserID is the DAB user the extraction runs for; sources that act on a
+	// linked account use it.
+	UserID string
+	// Discography filters Spotify artist and discography URLs.
+	Discography DiscographyFilter
+}
+
+// Upload is a file sent with the request.
+type Upload struct {
+	Filename string
+	Body     io.Reader
+	// Size is the file size in bytes, when known.
+	Size int64
+	// Profile names the exporter that wrote a CSV file; empty detects it.
+	Profile string
+}
+
+// Result is what a source extracted.
+type Result struct {
+	Tracks []models.Track
+	// Name is a human-readable name of what was extracted (playlist, album, file).
+	Name string
+	// Meta carries source-specific details, e.g. the kind of Spotify URL.
+	Meta map[string]any
+	// Warnings lists input that was skipped, e.g. unreadable CSV rows.
+	Warnings []Warning
+}
+
+// Warning reports one skipped row or item. Row is the 1-based line of the input
+// file or position in the playlist, when there is one.
+type Warning struct {
+	Row      int    `json:"row,omitempty"`
+	SourceID string `json:"source_id,omitempty"`
+	Message  string `json:"message"`
+}
+
+// InputError marks a problem with the request (bad URL, unknown type, invalid
+// option) as opposed to an extraction failure.
+type InputError struct{ Msg string }
+
+func (e *InputError) Error() string { return e.Msg }
+
+func inputErrorf(format string, args ...any) *InputError {
+	return &InputError{fmt.Sprintf(format, args...)}
+}
+
+// Registry looks sources up by name or detects them from a request. Detection
+// tries sources in registration order, so more specific ones go first.
+type Registry struct {
+	sources []Source
+	byName  map[string]Source
+}
+
+func NewRegistry(sources ...Source) *Registry {
+	r := &Registry{byName: make(map[string]Source)}
+	for _, s := range sources {
+		r.Register(s)
+	}
+	return r
+}
+
+// Register adds a source. A later source with the same name replaces the earlier one.
+func (r *Registry) Register(s Source) {
+	if prev, ok := r.byName[s.Name()]; ok {
+		for i, existing := range r.sources {
+			if existing == prev {
+				r.sources = append(r.sources[:i], r.sources[i+1:]...)
+				break
+			}
+		}
+	}
+	r.sources = append(r.sources, s)
+	r.byName[s.Name()] = s
+}
+
+// Lookup returns the source registered under name.
+func (r *Registry) Lookup(name string) (Source, bool) {
+	s, ok := r.byName[name]
+	return s, ok
+}
+
+// Names returns the registered source names, sorted.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.byName))
+	for n := range r.byName {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Resolve picks the source for a request: the one named by typ, or the first
+// that detects the request when typ is empty.
+func (r *Registry) Resolve(typ string, req Request) (Source, error) {
+	if typ != "" {
+		s, ok := r.byName[typ]
+		if !ok {
+			return nil, inputErrorf("Unsupported source type %q (supported: %s)", typ, strings.Join(r.Names(), ", "))
+		}
+		return s, nil
+	}
+
+	for _, s := range r.sources {
+		if s.Detect(req) {
+			return s, nil
+		}
+	}
+	if req.URL == "" && req.Upload == nil {
+		return nil, inputErrorf("Missing url")
+	}
+	return nil, inputErrorf("Could not detect the source type; set \"type\" to one of: %s", strings.Join(r.Names(), ", "))
+}
+
+// hostMatches reports whether rawURL is an absolute URL whose host is one of
+// hosts or a subdomain of one.
+func hostMatches(rawURL string, hosts ...string) bool {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, h := range hosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
@@ -2,37 +2,67 @@ package parser
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
 
+	"dbh-go-srv/internal/database"
 	"dbh-go-srv/internal/models"
 	"dbh-go-srv/internal/spotifetch"
+	"dbh-go-srv/internal/spotifyoauth"
 	"github.com/zmb3/spotify/v2"
 )
 
 type SpotifyParser struct {
 	client    *spotify.Client
+	db        *sql.DB
 	debugMode bool
 }
 
-func NewSpotifyParser(client *spotify.Client, debugMode bool) *SpotifyParser {
+func NewSpotifyParser(client *spotify.Client, db *sql.DB, debugMode bool) *SpotifyParser {
 	return &SpotifyParser{
 		client:    client,
+		db:        db,
 		debugMode: debugMode,
 	}
 }
 
 // Parse tries SpotiFLAC first, then falls back to official Spotify API
 func (p *SpotifyParser) Parse(ctx context.Context, url string) ([]models.Track, string, error) {
+	return p.ParseFiltered(ctx, url, DiscographyFilter{})
+}
+
+// ParseFiltered is Parse with a filter for artist and discography URLs; other
+// URLs ignore it.
+func (p *SpotifyParser) ParseFiltered(ctx context.Context, url string, filter DiscographyFilter) ([]models.Track, string, error) {
+	tracks, name, _, err := p.parseFiltered(ctx, url, filter)
+	return tracks, name, err
+}
+
+// parseFiltered is ParseFiltered that also returns why ISRCs could not be
+// looked up for web player results, if they could not.
+func (p *SpotifyParser) parseFiltered(ctx context.Context, url string, filter DiscographyFilter) ([]models.Track, string, error, error) {
 	if p.debugMode {
 		log.Printf("[SPOTIFY] Parse start url=%q", url)
 	}
 
 	// --- Step 1: Try SpotiFLAC metadata fetch ---
-	meta, err := spotifetch.GetFilteredSpotifyData(ctx, url, false, 0)
+	fetcher := spotifetch.NewSpotifyMetadataClient()
+	fetcher.ReleaseFilter = func(r spotifetch.DiscographyAlbumMetadata) bool {
+		return filter.allows(r.AlbumType, r.ReleaseDate)
+	}
+	meta, err := fetcher.GetFilteredData(ctx, url, false, 0)
 	if err == nil {
-		tracks, name := convertMetadataToTracks(meta)
+		tracks, name := convertMetadataToTracks(meta, filter)
+
+		// The web player responses carry no ISRCs; look them up so these tracks
+		// get the same ISRC-first matching as Web API results
+		isrcErr := p.enrichISRCs(ctx, tracks)
+		if isrcErr != nil && p.debugMode {
+			log.Printf("[SPOTIFY] isrc enrichment incomplete: %v", isrcErr)
+		}
 
 		if p.debugMode {
 			log.Printf(
@@ -54,7 +84,7 @@ func (p *SpotifyParser) Parse(ctx context.Context, url string) ([]models.Track,
 			}
 		}
 
-		return tracks, name, nil
+		return tracks, name, isrcErr, nil
 	}
 
 	if p.debugMode {
@@ -62,29 +92,125 @@ func (p *SpotifyParser) Parse(ctx context.Context, url string) ([]models.Track,
 	}
 
 	// --- Step 2: Fallback to official Spotify Web API ---
-	id, mediaType, err := p.parseURL(url)
+	id, mediaType, group, err := p.parseURL(url)
 	if err != nil {
-		return nil, "", fmt.Errorf("spotify parse url: %w", err)
+		return nil, "", nil, fmt.Errorf("spotify parse url: %w", err)
 	}
 
 	if p.debugMode {
 		log.Printf("[SPOTIFY] fallback mediaType=%s id=%s", mediaType, id)
 	}
 
+	var tracks []models.Track
+	var name string
 	switch mediaType {
 	case "playlist":
-		return p.handlePlaylist(ctx, id)
+		tracks, name, err = p.handlePlaylist(ctx, id)
 	case "album":
-		return p.handleAlbum(ctx, id)
+		tracks, name, err = p.handleAlbum(ctx, id)
 	case "track":
-		return p.handleTrack(ctx, id)
+		tracks, name, err = p.handleTrack(ctx, id)
+	case "artist":
+		tracks, name, err = p.handleArtist(ctx, id, group, filter)
 	default:
-		return nil, "", fmt.Errorf("unsupported spotify type: %s", mediaType)
+		err = fmt.Errorf("unsupported spotify type: %s", mediaType)
+	}
+	return tracks, name, nil, err
+}
+
+// enrichISRCs fills in missing ISRCs on spotify tracks, from the registry cache
+// first and then from the Web API in batches of 50. Failures are logged and leave
+// the affected tracks without an ISRC; matching then falls back to text search.
+// The Web API error, if any, is also returned.
+func (p *SpotifyParser) enrichISRCs(ctx context.Context, tracks []models.Track) error {
+	positions := make(map[string][]int)
+	var ids []string
+	for i, t := range tracks {
+		if t.ISRC != "" || t.SourceID == "" {
+			continue
+		}
+		if _, seen := positions[t.SourceID]; !seen {
+			ids = append(ids, t.SourceID)
+		}
+		positions[t.SourceID] = append(positions[t.SourceID], i)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	cached, err := database.GetCachedISRCs(p.db, ids)
+	if err != nil && p.debugMode {
+		log.Printf("[SPOTIFY] isrc cache lookup failed: %v", err)
+	}
+
+	var missing []spotify.ID
+	for _, id := range ids {
+		if isrc, ok := cached[id]; ok {
+			for _, i := range positions[id] {
+				tracks[i].ISRC = isrc
+			}
+			continue
+		}
+		missing = append(missing, spotify.ID(id))
+	}
+
+	fetched := make(map[string]string)
+	var lookupErr error
+	if p.client != nil {
+		for i := 0; i < len(missing); i += 50 {
+			end := i + 50
+			if end > len(missing) {
+				end = len(missing)
+			}
+
+			fullTracks, err := p.client.GetTracks(ctx, missing[i:end])
+			if err != nil {
+				log.Printf("[SPOTIFY] isrc lookup failed for %d tracks: %v", end-i, err)
+				lookupErr = err
+				break
+			}
+			for _, ft := range fullTracks {
+				if ft == nil {
+					continue
+				}
+				isrc := strings.ToUpper(strings.TrimSpace(ft.ExternalIDs["isrc"]))
+				if isrc == "" {
+					continue
+				}
+				fetched[string(ft.ID)] = isrc
+				for _, idx := range positions[string(ft.ID)] {
+					tracks[idx].ISRC = isrc
+				}
+			}
+		}
+	}
+
+	if err := database.CacheISRCs(p.db, fetched); err != nil && p.debugMode {
+		log.Printf("[SPOTIFY] isrc cache write failed: %v", err)
+	}
+
+	if p.debugMode {
+		log.Printf("[SPOTIFY] isrc enrichment: wanted=%d cached=%d fetched=%d", len(ids), len(cached), len(fetched))
 	}
+	return lookupErr
+}
+
+// LookupISRC returns the ISRC of one Spotify track, through the same cache and
+// Web API lookup as enrichISRCs. It suits matcher.SetSpotifyISRCResolver.
+// An uncached track without a Web API client is an error, as is a failed lookup.
+func (p *SpotifyParser) LookupISRC(ctx context.Context, spotifyID string) (string, error) {
+	tracks := []models.Track{{SourceID: spotifyID, Type: "spotify"}}
+	if err := p.enrichISRCs(ctx, tracks); err != nil {
+		return "", err
+	}
+	if tracks[0].ISRC == "" && p.client == nil {
+		return "", errors.New("spotify isrc lookup: no Web API client")
+	}
+	return tracks[0].ISRC, nil
 }
 
 // --- Conversion from SpotiFLAC metadata to models.Track ---
-func convertMetadataToTracks(meta interface{}) ([]models.Track, string) {
+func convertMetadataToTracks(meta interface{}, filter DiscographyFilter) ([]models.Track, string) {
 	tracks := []models.Track{}
 
 	switch v := meta.(type) {
@@ -125,6 +251,9 @@ func convertMetadataToTracks(meta interface{}) ([]models.Track, string) {
 			})
 		}
 		return tracks, v.PlaylistInfo.Owner.Name
+
+	case *spotifetch.ArtistDiscographyPayload:
+		return discographyTracks(v, filter), v.ArtistInfo.Name
 	}
 
 	return nil, ""
@@ -237,16 +366,27 @@ func (p *SpotifyParser) handleTrack(ctx context.Context, id spotify.ID) ([]model
 	return []models.Track{t}, res.Name, nil
 }
 
-func (p *SpotifyParser) parseURL(urlStr string) (spotify.ID, string, error) {
+// parseURL returns the ID and media type of a Spotify URL. For artist URLs group
+// is the discography tab (album, single, compilation), or "all".
+func (p *SpotifyParser) parseURL(urlStr string) (id spotify.ID, mediaType, group string, err error) {
 	switch {
 	case strings.Contains(urlStr, "/playlist/"):
-		return p.extractID(urlStr), "playlist", nil
+		return p.extractID(urlStr), "playlist", "", nil
 	case strings.Contains(urlStr, "/album/"):
-		return p.extractID(urlStr), "album", nil
+		return p.extractID(urlStr), "album", "", nil
 	case strings.Contains(urlStr, "/track/"):
-		return p.extractID(urlStr), "track", nil
+		return p.extractID(urlStr), "track", "", nil
+	case strings.Contains(urlStr, "/artist/"):
+		// /artist/{id}[/discography[/{group}]]
+		rest := strings.Split(strings.SplitN(urlStr, "/artist/", 2)[1], "?")[0]
+		parts := strings.Split(strings.Trim(rest, "/"), "/")
+		group = "all"
+		if len(parts) >= 3 && parts[1] == "discography" {
+			group = parts[2]
+		}
+		return spotify.ID(parts[0]), "artist", group, nil
 	}
-	return "", "", fmt.Errorf("could not identify media type from URL")
+	return "", "", "", fmt.Errorf("could not identify media type from URL")
 }
 
 func (p *SpotifyParser) extractID(urlStr string) spotify.ID {
@@ -270,4 +410,65 @@ func (p *SpotifyParser) transform(st spotify.FullTrack) models.Track {
 		Type:     "spotify",
 		SourceID: string(st.ID),
 	}
-}
\ No newline at end of file
+}
+
+// SpotifySource imports Spotify track, album, playlist and artist URLs.
+type SpotifySource struct {
+	parser *SpotifyParser
+	// accounts is optional; with it, a linked account lets the Web API fallback
+	// read the user's private playlists
+	accounts *spotifyoauth.Accounts
+}
+
+func NewSpotifySource(p *SpotifyParser, accounts *spotifyoauth.Accounts) *SpotifySource {
+	return &SpotifySource{parser: p, accounts: accounts}
+}
+
+func (s *SpotifySource) Name() string { return "spotify" }
+
+func (s *SpotifySource) Detect(req Request) bool {
+	return hostMatches(req.URL, "spotify.com", "googleusercontent.com") &&
+		!strings.Contains(req.URL, "/collection/")
+}
+
+func (s *SpotifySource) Extract(ctx context.Context, req Request) (*Result, error) {
+	if !s.Detect(req) {
+		return nil, inputErrorf("Invalid Spotify URL")
+	}
+	if err := req.Discography.Validate(); err != nil {
+		return nil, inputErrorf("Invalid discography filter: %v", err)
+	}
+
+	p := s.parser
+	if s.accounts != nil && req.UserID != "" {
+		if client, err := s.accounts.Client(ctx, req.UserID); err == nil {
+			p = p.WithClient(client)
+		}
+	}
+
+	tracks, name, isrcErr, err := p.parseFiltered(ctx, req.URL, req.Discography)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Result{Tracks: tracks, Name: name, Meta: map[string]any{}}
+	if _, kind, group, err := p.parseURL(req.URL); err == nil {
+		res.Meta["kind"] = kind
+		if kind == "artist" {
+			res.Meta["discography"] = group
+		}
+	}
+	if isrcErr != nil {
+		missing := 0
+		for _, t := range tracks {
+			if t.ISRC == "" {
+				missing++
+			}
+		}
+		res.Meta["isrc_lookup_failed"] = true
+		res.Warnings = append(res.Warnings, Warning{
+			Message: fmt.Sprintf("ISRC lookup failed (%v); %d tracks are matched by title and artist only", isrcErr, missing),
+		})
+	}
+	return res, nil
+}
@@ -0,0 +1,247 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"dbh-go-srv/internal/models"
+	"dbh-go-srv/internal/spotifyoauth"
+	"github.com/zmb3/spotify/v2"
+)
+
+// Library source kinds read a linked user's Spotify account rather than a URL.
+const (
+	LibrarySavedTracks     = "saved_tracks"
+	LibrarySavedAlbums     = "saved_albums"
+	LibraryFollowedArtists = "followed_artists"
+)
+
+// WithClient returns a copy of the parser whose Web API calls use client, e.g.
+// one acting as a linked user so private playlists resolve.
+func (p *SpotifyParser) WithClient(client *spotify.Client) *SpotifyParser {
+	cp := *p
+	cp.client = client
+	return &cp
+}
+
+// ParseLibrary reads one part of the library of the user that client acts as.
+func (p *SpotifyParser) ParseLibrary(ctx context.Context, client *spotify.Client, kind string) ([]models.Track, string, error) {
+	if p.debugMode {
+		log.Printf("[SPOTIFY] library fetch kind=%s", kind)
+	}
+
+	switch kind {
+	case LibrarySavedTracks:
+		return p.savedTracks(ctx, client)
+	case LibrarySavedAlbums:
+		return p.savedAlbums(ctx, client)
+	case LibraryFollowedArtists:
+		return p.followedArtistsTopTracks(ctx, client)
+	default:
+		return nil, "", fmt.Errorf("unsupported spotify library kind: %s", kind)
+	}
+}
+
+func (p *SpotifyParser) savedTracks(ctx context.Context, client *spotify.Client) ([]models.Track, string, error) {
+	const name = "Liked Songs"
+
+	page, err := client.CurrentUsersTracks(ctx, spotify.Limit(50))
+	if err != nil {
+		return nil, "", fmt.Errorf("get saved tracks: %w", err)
+	}
+
+	var tracks []models.Track
+	for {
+		for _, item := range page.Tracks {
+			if item.ID != "" {
+				tracks = append(tracks, p.transform(item.FullTrack))
+			}
+		}
+
+		err = client.NextPage(ctx, page)
+		if err == spotify.ErrNoMorePages {
+			break
+		}
+		if err != nil {
+			return tracks, name, fmt.Errorf("saved tracks pagination error: %w", err)
+		}
+	}
+
+	return tracks, name, nil
+}
+
+func (p *SpotifyParser) savedAlbums(ctx context.Context, client *spotify.Client) ([]models.Track, string, error) {
+	const name = "Saved Albums"
+
+	page, err := client.CurrentUsersAlbums(ctx, spotify.Limit(50))
+	if err != nil {
+		return nil, "", fmt.Errorf("get saved albums: %w", err)
+	}
+
+	// Album track listings carry no ISRCs, so collect the IDs and fetch the full
+	// tracks in batches afterwards
+	var ids []spotify.ID
+	for {
+		for _, saved := range page.Albums {
+			trackPage := saved.Tracks
+			for {
+				for _, t := range trackPage.Tracks {
+					ids = append(ids, t.ID)
+				}
+				err := client.NextPage(ctx, &trackPage)
+				if err == spotify.ErrNoMorePages {
+					break
+				}
+				if err != nil {
+					return nil, name, fmt.Errorf("album %q tracks pagination error: %w", saved.Name, err)
+				}
+			}
+		}
+
+		err = client.NextPage(ctx, page)
+		if err == spotify.ErrNoMorePages {
+			break
+		}
+		if err != nil {
+			return nil, name, fmt.Errorf("saved albums pagination error: %w", err)
+		}
+	}
+
+	tracks, err := p.fullTracks(ctx, client, ids)
+	if err != nil {
+		return nil, name, err
+	}
+	return tracks, name, nil
+}
+
+func (p *SpotifyParser) followedArtistsTopTracks(ctx context.Context, client *spotify.Client) ([]models.Track, string, error) {
+	const name = "Followed Artists: Top Tracks"
+
+	// Top tracks are per market; use the account's own country
+	me, err := client.CurrentUser(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("get current user: %w", err)
+	}
+	country := me.Country
+	if country == "" {
+		country = "US"
+	}
+
+	var artists []spotify.FullArtist
+	opts := []spotify.RequestOption{spotify.Limit(50)}
+	for {
+		page, err := client.CurrentUsersFollowedArtists(ctx, opts...)
+		if err != nil {
+			return nil, name, fmt.Errorf("get followed artists: %w", err)
+		}
+		artists = append(artists, page.Artists...)
+		if page.Next == "" || page.Cursor.After == "" {
+			break
+		}
+		opts = []spotify.RequestOption{spotify.Limit(50), spotify.After(page.Cursor.After)}
+	}
+
+	var tracks []models.Track
+	for _, a := range artists {
+		if err := ctx.Err(); err != nil {
+			return nil, name, err
+		}
+
+		top, err := client.GetArtistsTopTracks(ctx, a.ID, country)
+		if err != nil {
+			if p.debugMode {
+				log.Printf("[SPOTIFY] skipping top tracks of %q: %v", a.Name, err)
+			}
+			continue
+		}
+		for _, ft := range top {
+			tracks = append(tracks, p.transform(ft))
+		}
+	}
+
+	return tracks, name, nil
+}
+
+// fullTracks resolves track IDs to tracks with ISRCs, 50 per request.
+func (p *SpotifyParser) fullTracks(ctx context.Context, client *spotify.Client, ids []spotify.ID) ([]models.Track, error) {
+	var tracks []models.Track
+	for i := 0; i < len(ids); i += 50 {
+		end := i + 50
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		fullTracks, err := client.GetTracks(ctx, ids[i:end])
+		if err != nil {
+			return nil, fmt.Errorf("get full tracks: %w", err)
+		}
+		for _, ft := range fullTracks {
+			if ft != nil {
+				tracks = append(tracks, p.transform(*ft))
+			}
+		}
+	}
+	return tracks, nil
+}
+
+// SpotifyLibrarySource imports one part of the library of the requesting user's
+// linked Spotify account. It needs no URL, but also detects the web player's
+// collection pages (open.spotify.com/collection/tracks and so on).
+type SpotifyLibrarySource struct {
+	name     string
+	kind     string
+	parser   *SpotifyParser
+	accounts *spotifyoauth.Accounts
+}
+
+// NewSpotifyLibrarySources returns the saved tracks, saved albums and followed
+// artists sources. accounts may be nil, in which case they report that account
+// linking is not configured.
+func NewSpotifyLibrarySources(p *SpotifyParser, accounts *spotifyoauth.Accounts) []Source {
+	return []Source{
+		&SpotifyLibrarySource{name: "spotify_saved_tracks", kind: LibrarySavedTracks, parser: p, accounts: accounts},
+		&SpotifyLibrarySource{name: "spotify_saved_albums", kind: LibrarySavedAlbums, parser: p, accounts: accounts},
+		&SpotifyLibrarySource{name: "spotify_followed_artists", kind: LibraryFollowedArtists, parser: p, accounts: accounts},
+	}
+}
+
+// collectionPaths maps library kinds to their web player pages.
+var collectionPaths = map[string]string{
+	LibrarySavedTracks:     "/collection/tracks",
+	LibrarySavedAlbums:     "/collection/albums",
+	LibraryFollowedArtists: "/collection/artists",
+}
+
+func (s *SpotifyLibrarySource) Name() string { return s.name }
+
+func (s *SpotifyLibrarySource) Detect(req Request) bool {
+	if !hostMatches(req.URL, "spotify.com") {
+		return false
+	}
+	u, _ := url.Parse(req.URL)
+	return strings.TrimSuffix(u.Path, "/") == collectionPaths[s.kind]
+}
+
+func (s *SpotifyLibrarySource) Extract(ctx context.Context, req Request) (*Result, error) {
+	if s.accounts == nil {
+		return nil, inputErrorf("Spotify account linking is not configured")
+	}
+
+	client, err := s.accounts.Client(ctx, req.UserID)
+	if errors.Is(err, spotifyoauth.ErrNotConnected) {
+		return nil, &InputError{err.Error()}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tracks, name, err := s.parser.ParseLibrary(ctx, client, s.kind)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Tracks: tracks, Name: name, Meta: map[string]any{"kind": s.kind}}, nil
+}
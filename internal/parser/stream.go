@@ -0,0 +1,90 @@
+package parser
+
+import (
+	"context"
+	"sync"
+
+	"dbh-go-srv/internal/models"
+)
+
+// StreamingSource is a Source that can hand tracks over while it is still reading
+// its input, so matching starts before a large file has been read to the end.
+type StreamingSource interface {
+	Source
+	// Stream validates the request and starts reading. Problems found before
+	// reading starts (limits, headers) are returned as *InputError.
+	Stream(ctx context.Context, req Request) (*Stream, error)
+}
+
+// Stream delivers a source's tracks as they are read.
+type Stream struct {
+	Name string
+	Meta map[string]any
+	// Columns is the header row of a file source, which each track's
+	// SourceRow follows.
+	Columns []string
+	// Total is the expected number of tracks, from a cheap pre-count. Skipped
+	// rows make the real number lower; 0 means unknown.
+	Total int
+	// Tracks is closed when reading ends, after which Err and Warnings are final.
+	Tracks <-chan models.Track
+
+	mu       sync.Mutex
+	err      error
+	warnings []Warning
+}
+
+// Err returns the error that stopped reading early, if any. Call it after Tracks is closed.
+func (s *Stream) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Warnings returns the skipped input so far.
+func (s *Stream) Warnings() []Warning {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Warning(nil), s.warnings...)
+}
+
+func (s *Stream) warn(w Warning) {
+	s.mu.Lock()
+	s.warnings = append(s.warnings, w)
+	s.mu.Unlock()
+}
+
+func (s *Stream) fail(err error) {
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+}
+
+// StreamResult wraps an already extracted result, so callers can treat every
+// source as a stream.
+func StreamResult(res *Result) *Stream {
+	ch := make(chan models.Track, len(res.Tracks))
+	for _, t := range res.Tracks {
+		ch <- t
+	}
+	close(ch)
+	return &Stream{
+		Name:     res.Name,
+		Meta:     res.Meta,
+		Total:    len(res.Tracks),
+		Tracks:   ch,
+		warnings: res.Warnings,
+	}
+}
+
+// OpenStream starts extraction from src, streaming when the source supports it.
+func OpenStream(ctx context.Context, src Source, req Request) (*Stream, error) {
+	if ss, ok := src.(StreamingSource); ok {
+		return ss.Stream(ctx, req)
+	}
+	res, err := src.Extract(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return StreamResult(res), nil
+}
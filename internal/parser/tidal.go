@@ -0,0 +1,210 @@
+package parser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"dbh-go-srv/internal/models"
+)
+
+// DefaultTidalBaseURL is Tidal's public v1 API.
+const DefaultTidalBaseURL = "https://api.tidal.com/v1"
+
+var tidalPathRegex = regexp.MustCompile(`^/(?:browse/)?(playlist|album|mix)/([0-9A-Za-z-]+)`)
+
+// TidalSource imports public Tidal playlists, albums and mixes through the v1
+// API, whose tracks carry ISRCs. The API needs a client token (the one the
+// web player sends as x-tidal-token) but no user login.
+type TidalSource struct {
+	// BaseURL is the API root; tests point it at a local fake server.
+	BaseURL string
+	Token   string
+	// CountryCode picks the catalog; availability differs per country.
+	CountryCode string
+	HTTPClient  *http.Client
+}
+
+// NewTidalSource returns a source for the public API with the given token.
+func NewTidalSource(token, countryCode string) *TidalSource {
+	if countryCode == "" {
+		countryCode = "US"
+	}
+	return &TidalSource{
+		BaseURL:     DefaultTidalBaseURL,
+		Token:       token,
+		CountryCode: countryCode,
+		HTTPClient:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *TidalSource) Name() string { return "tidal" }
+
+func (s *TidalSource) Detect(req Request) bool {
+	return hostMatches(req.URL, "tidal.com")
+}
+
+func (s *TidalSource) Extract(ctx context.Context, req Request) (*Result, error) {
+	if !s.Detect(req) {
+		return nil, inputErrorf("Invalid Tidal URL")
+	}
+	u, _ := url.Parse(strings.TrimSpace(req.URL))
+	m := tidalPathRegex.FindStringSubmatch(u.Path)
+	if m == nil {
+		return nil, inputErrorf("Tidal URL must point to a playlist, album or mix")
+	}
+	kind, id := m[1], m[2]
+
+	var (
+		name  string
+		items string
+	)
+	switch kind {
+	case "playlist":
+		var head struct {
+			Title string `json:"title"`
+		}
+		if err := s.get(ctx, "/playlists/"+id, nil, &head); err != nil {
+			return nil, err
+		}
+		name, items = head.Title, "/playlists/"+id+"/items"
+	case "album":
+		var head struct {
+			Title string `json:"title"`
+		}
+		if err := s.get(ctx, "/albums/"+id, nil, &head); err != nil {
+			return nil, err
+		}
+		name, items = head.Title, "/albums/"+id+"/items"
+	case "mix":
+		// Mixes have no metadata endpoint in v1
+		name, items = "Tidal Mix", "/mixes/"+id+"/items"
+	}
+
+	list, err := s.items(ctx, items)
+	if err != nil {
+		return nil, err
+	}
+
+	tracks := make([]models.Track, 0, len(list))
+	for _, t := range list {
+		tracks = append(tracks, t.track())
+	}
+	return &Result{Tracks: tracks, Name: name, Meta: map[string]any{"kind": kind}}, nil
+}
+
+type tidalTrack struct {
+	ID       int64  `json:"id"`
+	Title    string `json:"title"`
+	Version  string `json:"version"`
+	Duration int    `json:"duration"`
+	ISRC     string `json:"isrc"`
+	Artists  []struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	} `json:"artists"`
+	Album struct {
+		Title string `json:"title"`
+	} `json:"album"`
+}
+
+func (t tidalTrack) track() models.Track {
+	title := t.Title
+	// Tidal keeps "Remastered 2011" and the like apart from the title
+	if t.Version != "" && !strings.Contains(title, t.Version) {
+		title += " (" + t.Version + ")"
+	}
+
+	var main, featured []string
+	for _, a := range t.Artists {
+		if a.Type == "FEATURED" {
+			featured = append(featured, a.Name)
+		} else {
+			main = append(main, a.Name)
+		}
+	}
+
+	return models.Track{
+		Title:      title,
+		Artist:     strings.Join(main, ", "),
+		Featuring:  strings.Join(featured, ", "),
+		Album:      t.Album.Title,
+		ISRC:       strings.ToUpper(t.ISRC),
+		SourceID:   strconv.FormatInt(t.ID, 10),
+		DurationMs: t.Duration * 1000,
+		Type:       "tidal",
+	}
+}
+
+// items reads a paginated item list, keeping the tracks (playlists and mixes
+// may also hold videos).
+func (s *TidalSource) items(ctx context.Context, path string) ([]tidalTrack, error) {
+	const limit = 100
+	var tracks []tidalTrack
+	for offset := 0; ; offset += limit {
+		var page struct {
+			Items []struct {
+				Type string     `json:"type"`
+				Item tidalTrack `json:"item"`
+			} `json:"items"`
+			Total int `json:"totalNumberOfItems"`
+		}
+		q := url.Values{"limit": {strconv.Itoa(limit)}, "offset": {strconv.Itoa(offset)}}
+		if err := s.get(ctx, path, q, &page); err != nil {
+			return nil, err
+		}
+		for _, it := range page.Items {
+			if it.Type == "track" {
+				tracks = append(tracks, it.Item)
+			}
+		}
+		if len(page.Items) < limit || offset+limit >= page.Total {
+			break
+		}
+	}
+	if len(tracks) == 0 {
+		return nil, errors.New("tidal: no tracks found")
+	}
+	return tracks, nil
+}
+
+func (s *TidalSource) get(ctx context.Context, path string, q url.Values, out any) error {
+	if q == nil {
+		q = url.Values{}
+	}
+	q.Set("countryCode", s.CountryCode)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(s.BaseURL, "/")+path+"?"+q.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("x-tidal-token", s.Token)
+
+	client := s.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("tidal %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return json.NewDecoder(resp.Body).Decode(out)
+	case http.StatusNotFound:
+		return &InputError{"Tidal item not found; is it public and available in " + s.CountryCode + "?"}
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("tidal rejected the client token: %s", resp.Status)
+	default:
+		return fmt.Errorf("tidal %s: %s", path, resp.Status)
+	}
+}
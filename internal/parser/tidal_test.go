@@ -0,0 +1,177 @@
+package parser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeTidal serves a playlist of 250 entries (every tenth one a video), an
+// album and a mix in the v1 API's shape.
+type fakeTidal struct {
+	mu      sync.Mutex
+	offsets []int // offset of every playlist page request, in order
+}
+
+func tidalItem(typ string, id int, title string) map[string]any {
+	return map[string]any{"type": typ, "item": map[string]any{
+		"id": id, "title": title, "duration": 200, "isrc": fmt.Sprintf("usabc24%05d", id),
+		"artists": []map[string]any{{"name": "Main Artist", "type": "MAIN"}},
+		"album":   map[string]any{"title": "Some Album"},
+	}}
+}
+
+func newFakeTidal(t *testing.T) (*fakeTidal, *TidalSource) {
+	t.Helper()
+	f := &fakeTidal{}
+
+	var playlist []map[string]any
+	for i := 1; i <= 250; i++ {
+		if i%10 == 0 {
+			playlist = append(playlist, tidalItem("video", i, "Video "+strconv.Itoa(i)))
+		} else {
+			playlist = append(playlist, tidalItem("track", i, "Track "+strconv.Itoa(i)))
+		}
+	}
+	page := func(w http.ResponseWriter, r *http.Request, items []map[string]any) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		end := min(offset+limit, len(items))
+		json.NewEncoder(w).Encode(map[string]any{
+			"limit": limit, "offset": offset, "totalNumberOfItems": len(items), "items": items[offset:end],
+		})
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /playlists/pl-1", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"uuid": "pl-1", "title": "Big Playlist"})
+	})
+	mux.HandleFunc("GET /playlists/pl-1/items", func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		f.mu.Lock()
+		f.offsets = append(f.offsets, offset)
+		f.mu.Unlock()
+		page(w, r, playlist)
+	})
+	mux.HandleFunc("GET /albums/77", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"id": 77, "title": "Remasters"})
+	})
+	mux.HandleFunc("GET /albums/77/items", func(w http.ResponseWriter, r *http.Request) {
+		song := tidalItem("track", 501, "Song")
+		song["item"].(map[string]any)["version"] = "Remastered 2011"
+		song["item"].(map[string]any)["artists"] = []map[string]any{
+			{"name": "Main Artist", "type": "MAIN"}, {"name": "Guest", "type": "FEATURED"},
+		}
+		// The version is already in this title and is not added twice
+		live := tidalItem("track", 502, "Other Song (Live)")
+		live["item"].(map[string]any)["version"] = "Live"
+		page(w, r, []map[string]any{song, live})
+	})
+	mux.HandleFunc("GET /mixes/0123abc/items", func(w http.ResponseWriter, r *http.Request) {
+		page(w, r, []map[string]any{tidalItem("track", 901, "Mix Track")})
+	})
+	mux.HandleFunc("GET /albums/gone", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-tidal-token") != "test-token" {
+			http.Error(w, `{"status":401,"userMessage":"Invalid token"}`, http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("countryCode") != "DE" {
+			http.Error(w, "missing countryCode", http.StatusBadRequest)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	return f, &TidalSource{BaseURL: srv.URL, Token: "test-token", CountryCode: "DE", HTTPClient: srv.Client()}
+}
+
+func TestTidalPlaylistPagesAndSkipsVideos(t *testing.T) {
+	f, s := newFakeTidal(t)
+
+	res, err := s.Extract(context.Background(), Request{URL: "https://tidal.com/browse/playlist/pl-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Name != "Big Playlist" || res.Meta["kind"] != "playlist" {
+		t.Errorf("name %q, kind %v", res.Name, res.Meta["kind"])
+	}
+	if want := []int{0, 100, 200}; !slices.Equal(f.offsets, want) {
+		t.Errorf("page offsets = %v, want %v", f.offsets, want)
+	}
+	if len(res.Tracks) != 225 {
+		t.Fatalf("%d tracks, want 225 (250 entries less 25 videos)", len(res.Tracks))
+	}
+	for _, tr := range res.Tracks {
+		if strings.HasPrefix(tr.Title, "Video") {
+			t.Errorf("video %q was kept", tr.Title)
+		}
+	}
+	last := res.Tracks[len(res.Tracks)-1]
+	if last.Title != "Track 249" || last.SourceID != "249" || last.ISRC != "USABC2400249" || last.DurationMs != 200000 || last.Type != "tidal" {
+		t.Errorf("last track = %+v", last)
+	}
+}
+
+func TestTidalAlbumVersionsAndFeaturing(t *testing.T) {
+	_, s := newFakeTidal(t)
+
+	res, err := s.Extract(context.Background(), Request{URL: "https://listen.tidal.com/album/77"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Name != "Remasters" || len(res.Tracks) != 2 {
+		t.Fatalf("name %q, tracks %+v", res.Name, res.Tracks)
+	}
+	if tr := res.Tracks[0]; tr.Title != "Song (Remastered 2011)" || tr.Artist != "Main Artist" || tr.Featuring != "Guest" {
+		t.Errorf("first track = %+v", tr)
+	}
+	if tr := res.Tracks[1]; tr.Title != "Other Song (Live)" {
+		t.Errorf("second track title = %q, want the version once", tr.Title)
+	}
+}
+
+func TestTidalMix(t *testing.T) {
+	_, s := newFakeTidal(t)
+
+	res, err := s.Extract(context.Background(), Request{URL: "https://tidal.com/mix/0123abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Name != "Tidal Mix" || res.Meta["kind"] != "mix" || len(res.Tracks) != 1 || res.Tracks[0].Title != "Mix Track" {
+		t.Errorf("mix = %q %v %+v", res.Name, res.Meta, res.Tracks)
+	}
+}
+
+func TestTidalErrors(t *testing.T) {
+	_, s := newFakeTidal(t)
+
+	_, err := s.Extract(context.Background(), Request{URL: "https://tidal.com/album/gone"})
+	var inputErr *InputError
+	if !errors.As(err, &inputErr) || !strings.Contains(err.Error(), "DE") {
+		t.Errorf("missing album: err = %v, want an InputError naming the country", err)
+	}
+
+	s.Token = "expired"
+	_, err = s.Extract(context.Background(), Request{URL: "https://tidal.com/album/77"})
+	if err == nil || errors.As(err, &inputErr) || !strings.Contains(err.Error(), "client token") {
+		t.Errorf("bad token: err = %v, want a server error about the client token", err)
+	}
+
+	_, err = s.Extract(context.Background(), Request{URL: "https://tidal.com/artist/1"})
+	if !errors.As(err, &inputErr) {
+		t.Errorf("artist URL: err = %v, want an InputError", err)
+	}
+}
@@ -1,48 +1,314 @@
 package parser
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"dbh-go-srv/internal/models"
 	"github.com/kkdai/youtube/v2"
 )
 
-func ParseYouTube(url string) ([]models.Track, string, error) {
-	client := youtube.Client{}
+const (
+	// ytRequestTimeout bounds each request to YouTube.
+	ytRequestTimeout = 30 * time.Second
+	// ytExtractTimeout bounds a whole extraction, including the per-video
+	// description lookups of large playlists.
+	ytExtractTimeout = 10 * time.Minute
+)
+
+// Reasons a single video cannot be read. They are reported per track for
+// playlists and as the error for video URLs.
+var (
+	ErrYTVideoPrivate       = errors.New("video is private")
+	ErrYTVideoDeleted       = errors.New("video was removed or does not exist")
+	ErrYTVideoAgeRestricted = errors.New("video is age-restricted")
+	ErrYTVideoUnavailable   = errors.New("video is unavailable")
+)
+
+func newYTClient() *youtube.Client {
+	return &youtube.Client{HTTPClient: &http.Client{Timeout: ytRequestTimeout}}
+}
+
+// ParseYouTube extracts a playlist, YouTube Music album or single video. The
+// playlist client follows continuation pages, so playlists of any length come
+// back whole. Unavailable playlist entries are skipped with a warning each.
+func ParseYouTube(ctx context.Context, rawURL string) (*Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, ytExtractTimeout)
+	defer cancel()
+
+	client := newYTClient()
+
+	// 0. YouTube Music album pages are browse IDs, not playlists; resolve them to
+	// the album's auto-generated playlist first
+	album := false
+	if browseID := musicAlbumBrowseID(rawURL); browseID != "" {
+		playlistID, err := resolveMusicAlbum(ctx, browseID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve YouTube Music album: %w", err)
+		}
+		rawURL = "https://www.youtube.com/playlist?list=" + playlistID
+		album = true
+	}
 
 	// 1. Try to parse as a playlist first
-	playlist, err := client.GetPlaylist(url)
-	if err == nil {
-		var tracks []models.Track
-		
-		for _, entry := range playlist.Videos {
-			artist, title := NormalizeYTTitle(entry.Title, entry.Author)
-			
-			tracks = append(tracks, models.Track{
-				Title:    title,
-				Artist:   artist,
-				SourceID: entry.ID,
-				Type:     "youtube", // Set type for registry
-			})
-		}
-		return tracks, playlist.Title, nil
+	var playlistErr error
+	if strings.Contains(rawURL, "list=") {
+		playlist, err := client.GetPlaylistContext(ctx, rawURL)
+		if err == nil {
+			return parsePlaylist(ctx, playlist, album), nil
+		}
+		playlistErr = err
+		if !hasVideoID(rawURL) {
+			return nil, fmt.Errorf("failed to read YouTube playlist: %w", err)
+		}
 	}
 
 	// 2. Fallback: Parse as a single video
-	video, err := client.GetVideo(url)
+	video, err := client.GetVideoContext(ctx, rawURL)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to parse YouTube URL: %w", err)
+		err = classifyVideoError(err)
+		if playlistErr != nil {
+			return nil, fmt.Errorf("failed to read YouTube URL as a playlist (%v) or as a video: %w", playlistErr, err)
+		}
+		return nil, fmt.Errorf("failed to read YouTube video: %w", err)
+	}
+
+	parsed := ParseYTTitle(video.Title, video.Author)
+	track := models.Track{
+		Title:      parsed.Title,
+		Artist:     parsed.Artist,
+		Featuring:  parsed.Featuring,
+		SourceID:   video.ID,
+		DurationMs: int(video.Duration.Milliseconds()),
+		Type:       "youtube", // Set type for registry
+	}
+	if d, ok := parseYTDescription(video.Description); ok {
+		d.apply(&track)
+	}
+
+	return &Result{Tracks: []models.Track{track}, Name: video.Title, Meta: map[string]any{"kind": "video"}}, nil
+}
+
+func parsePlaylist(ctx context.Context, playlist *youtube.Playlist, album bool) *Result {
+	album = album || strings.HasPrefix(playlist.ID, albumPlaylistPrefix)
+	res := &Result{Name: playlist.Title, Meta: map[string]any{"kind": "playlist"}}
+	if album {
+		res.Name = strings.TrimPrefix(res.Name, "Album - ")
+		res.Meta["kind"] = "album"
+	}
+
+	var (
+		entries []*youtube.PlaylistEntry
+		rows    []int
+	)
+	for i, entry := range playlist.Videos {
+		// Private and deleted videos stay listed under a placeholder title
+		if reason := unavailableEntry(entry); reason != nil {
+			res.Warnings = append(res.Warnings, Warning{Row: i + 1, SourceID: entry.ID, Message: reason.Error()})
+			continue
+		}
+
+		var parsed YTTitle
+		if album {
+			parsed = normalizeAlbumTrack(entry.Title, entry.Author)
+		} else {
+			parsed = ParseYTTitle(entry.Title, entry.Author)
+		}
+
+		t := models.Track{
+			Title:      parsed.Title,
+			Artist:     parsed.Artist,
+			Featuring:  parsed.Featuring,
+			SourceID:   entry.ID,
+			DurationMs: int(entry.Duration.Milliseconds()),
+			Type:       "youtube", // Set type for registry
+		}
+		if album {
+			t.Album = res.Name
+		}
+		res.Tracks = append(res.Tracks, t)
+		entries = append(entries, entry)
+		rows = append(rows, i+1)
 	}
 
-	artist, title := NormalizeYTTitle(video.Title, video.Author)
-	tracks := []models.Track{
-		{
-			Title:    title,
-			Artist:   artist,
-			SourceID: video.ID,
-			Type:     "youtube", // Set type for registry
-		},
+	res.Warnings = append(res.Warnings, describeTracks(ctx, res.Tracks, entries, rows, album)...)
+	return res
+}
+
+// unavailableEntry recognizes the placeholders YouTube lists instead of
+// private and deleted videos.
+func unavailableEntry(entry *youtube.PlaylistEntry) error {
+	switch entry.Title {
+	case "[Private video]":
+		return ErrYTVideoPrivate
+	case "[Deleted video]":
+		return ErrYTVideoDeleted
 	}
+	return nil
+}
 
-	return tracks, video.Title, nil
+// hasVideoID reports whether a URL names a video as well as a playlist.
+func hasVideoID(rawURL string) bool {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return false
+	}
+	return u.Query().Get("v") != "" || hostMatches(rawURL, "youtu.be")
+}
+
+// classifyVideoError maps the client's playability errors to the ErrYTVideo
+// reasons. Other errors, such as network failures, are returned unchanged.
+func classifyVideoError(err error) error {
+	var status *youtube.ErrPlayabiltyStatus
+	switch {
+	case errors.Is(err, youtube.ErrVideoPrivate):
+		return ErrYTVideoPrivate
+	case errors.Is(err, youtube.ErrLoginRequired):
+		return ErrYTVideoAgeRestricted
+	case errors.As(err, &status) && status.Status == "ERROR":
+		return fmt.Errorf("%w: %s", ErrYTVideoDeleted, status.Reason)
+	case errors.As(err, &status):
+		return fmt.Errorf("%w: %s", ErrYTVideoUnavailable, status.Reason)
+	}
+	return err
+}
+
+func isUnavailable(err error) bool {
+	return errors.Is(err, ErrYTVideoPrivate) || errors.Is(err, ErrYTVideoDeleted) ||
+		errors.Is(err, ErrYTVideoAgeRestricted) || errors.Is(err, ErrYTVideoUnavailable)
+}
+
+// describeWorkers bounds the concurrent video lookups of describeTracks.
+const describeWorkers = 4
+
+// describeTracks replaces the title guesses of playlist tracks with the
+// auto-generated description of their video. Playlist listings carry no
+// descriptions, so each video is fetched; only Topic channel videos (and every
+// track of an album) are art tracks that have one. Tracks whose video cannot
+// be fetched keep their guess; a private, removed or age-restricted video is
+// reported as a warning. youtube.Client caches session state without locking,
+// so every worker has its own. entries and rows (playlist positions) run
+// parallel to tracks.
+func describeTracks(ctx context.Context, tracks []models.Track, entries []*youtube.PlaylistEntry, rows []int, album bool) []Warning {
+	jobs := make(chan int)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		warnings []Warning
+	)
+	for w := 0; w < describeWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client := newYTClient()
+			for i := range jobs {
+				video, err := client.GetVideoContext(ctx, tracks[i].SourceID)
+				if err != nil {
+					if reason := classifyVideoError(err); isUnavailable(reason) {
+						mu.Lock()
+						warnings = append(warnings, Warning{Row: rows[i], SourceID: tracks[i].SourceID, Message: reason.Error() + "; using the playlist title"})
+						mu.Unlock()
+					}
+					continue
+				}
+				if d, ok := parseYTDescription(video.Description); ok {
+					d.apply(&tracks[i])
+				}
+			}
+		}()
+	}
+
+feed:
+	for i, entry := range entries {
+		if _, topic := topicArtist(entry.Author); !topic && !album {
+			continue
+		}
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	sort.Slice(warnings, func(a, b int) bool { return warnings[a].Row < warnings[b].Row })
+	return warnings
+}
+
+// albumPlaylistPrefix starts the IDs of the playlists YouTube generates for
+// releases; their entries are the album's tracks in order.
+const albumPlaylistPrefix = "OLAK5uy_"
+
+var albumPlaylistRegex = regexp.MustCompile(albumPlaylistPrefix + `[A-Za-z0-9_-]+`)
+
+// musicAlbumBrowseID returns the browse ID of a music.youtube.com album URL
+// (music.youtube.com/browse/MPREb_...), or "".
+func musicAlbumBrowseID(rawURL string) string {
+	if !hostMatches(rawURL, "music.youtube.com") {
+		return ""
+	}
+	u, _ := url.Parse(strings.TrimSpace(rawURL))
+	id, ok := strings.CutPrefix(strings.TrimSuffix(u.Path, "/"), "/browse/")
+	if !ok || !strings.HasPrefix(id, "MPREb_") {
+		return ""
+	}
+	return id
+}
+
+// resolveMusicAlbum finds the playlist ID of a YouTube Music album in its page.
+func resolveMusicAlbum(ctx context.Context, browseID string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://music.youtube.com/browse/"+browseID, nil)
+	if err != nil {
+		return "", err
+	}
+	// Without a browser user agent the page is an "unsupported browser" stub,
+	// and without the consent cookie EU visitors get the consent wall
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0 Safari/537.36")
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	req.Header.Set("Cookie", "CONSENT=YES+1")
+
+	client := &http.Client{Timeout: ytRequestTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("album page returned %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+	if err != nil {
+		return "", err
+	}
+	id := albumPlaylistRegex.Find(body)
+	if id == nil {
+		return "", fmt.Errorf("no playlist found for album %s", browseID)
+	}
+	return string(id), nil
+}
+
+// YouTubeSource imports YouTube and YouTube Music playlist, album and video URLs.
+type YouTubeSource struct{}
+
+func (YouTubeSource) Name() string { return "youtube" }
+
+func (YouTubeSource) Detect(req Request) bool {
+	return hostMatches(req.URL, "youtube.com", "youtu.be")
+}
+
+func (s YouTubeSource) Extract(ctx context.Context, req Request) (*Result, error) {
+	if !s.Detect(req) {
+		return nil, inputErrorf("Invalid YouTube URL")
+	}
+	return ParseYouTube(ctx, req.URL)
 }
@@ -0,0 +1,101 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+
+	"dbh-go-srv/internal/models"
+)
+
+// ytDescription is the metadata YouTube puts in the description of the "art
+// track" videos it generates for distributed releases:
+//
+//	Provided to YouTube by <distributor>
+//
+//	<title> · <artist> · <artist>
+//
+//	<album>
+//
+//	℗ <year> <label>
+//
+//	Released on: <date>
+//	...
+//	Auto-generated by YouTube.
+type ytDescription struct {
+	Title    string
+	Artists  []string
+	Album    string
+	Label    string
+	Released string
+	ISRC     string
+}
+
+var (
+	ytDescLabelRegex    = regexp.MustCompile(`^[℗©]\s*(?:\d{4}\s+)?(.+)$`)
+	ytDescReleasedRegex = regexp.MustCompile(`(?i)^released on:\s*(\S+)`)
+	ytDescISRCRegex     = regexp.MustCompile(`(?i)\bISRC\b\W*([A-Z]{2}-?[A-Z0-9]{3}-?\d{2}-?\d{5})\b`)
+)
+
+// parseYTDescription reads an auto-generated description. ok is false when the
+// description is not one, or has no "title · artist" line.
+func parseYTDescription(desc string) (d ytDescription, ok bool) {
+	var lines []string
+	for _, l := range strings.Split(strings.ReplaceAll(desc, "\r\n", "\n"), "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			lines = append(lines, l)
+		}
+	}
+
+	start := -1
+	for i, l := range lines {
+		if strings.HasPrefix(l, "Provided to YouTube by") {
+			start = i
+			break
+		}
+	}
+	if start < 0 || start+1 >= len(lines) {
+		return d, false
+	}
+
+	// The first line after the distributor is "title · artist[ · artist...]"
+	parts := strings.Split(lines[start+1], " · ")
+	if len(parts) < 2 {
+		return d, false
+	}
+	d.Title = strings.TrimSpace(parts[0])
+	for _, a := range parts[1:] {
+		if a = strings.TrimSpace(a); a != "" {
+			d.Artists = append(d.Artists, a)
+		}
+	}
+	if d.Title == "" || len(d.Artists) == 0 {
+		return d, false
+	}
+
+	for i, l := range lines[start+2:] {
+		switch {
+		case i == 0 && !ytDescLabelRegex.MatchString(l) && !ytDescReleasedRegex.MatchString(l):
+			// The album line follows the title line, unless the release has none
+			d.Album = l
+		case d.Label == "" && ytDescLabelRegex.MatchString(l):
+			d.Label = strings.TrimSpace(ytDescLabelRegex.FindStringSubmatch(l)[1])
+		case d.Released == "" && ytDescReleasedRegex.MatchString(l):
+			d.Released = ytDescReleasedRegex.FindStringSubmatch(l)[1]
+		case d.ISRC == "" && ytDescISRCRegex.MatchString(l):
+			d.ISRC = strings.ReplaceAll(strings.ToUpper(ytDescISRCRegex.FindStringSubmatch(l)[1]), "-", "")
+		}
+	}
+	return d, true
+}
+
+// apply fills the track from the description.
+func (d ytDescription) apply(t *models.Track) {
+	t.Title = d.Title
+	t.Artist = strings.Join(d.Artists, ", ")
+	if d.Album != "" {
+		t.Album = d.Album
+	}
+	if d.ISRC != "" {
+		t.ISRC = d.ISRC
+	}
+}
@@ -0,0 +1,119 @@
+package parser
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"dbh-go-srv/internal/models"
+)
+
+const ytFullDescription = `Provided to YouTube by Universal Music Group
+
+Blinding Lights · The Weeknd
+
+After Hours
+
+℗ 2020 The Weeknd XO, Inc., marketed by Republic Records
+
+Released on: 2020-03-20
+
+Producer: Max Martin
+Composer Lyricist: Abel Tesfaye
+ISRC: USUG11904206
+
+Auto-generated by YouTube.`
+
+func TestParseYTDescription(t *testing.T) {
+	tests := []struct {
+		name string
+		desc string
+		want ytDescription
+		ok   bool
+	}{
+		{
+			name: "full",
+			desc: ytFullDescription,
+			want: ytDescription{Title: "Blinding Lights", Artists: []string{"The Weeknd"}, Album: "After Hours",
+				Label: "The Weeknd XO, Inc., marketed by Republic Records", Released: "2020-03-20", ISRC: "USUG11904206"},
+			ok: true,
+		},
+		{
+			// Singles without an album go straight to the label line
+			name: "no album",
+			desc: "Provided to YouTube by DistroKid\n\nSome Single · Indie Artist\n\n℗ Indie Artist\n\nReleased on: 2023-05-05\n\nAuto-generated by YouTube.",
+			want: ytDescription{Title: "Some Single", Artists: []string{"Indie Artist"}, Label: "Indie Artist", Released: "2023-05-05"},
+			ok:   true,
+		},
+		{
+			name: "no album or label",
+			desc: "Provided to YouTube by DistroKid\n\nSome Single · Indie Artist\n\nReleased on: 2023-05-05",
+			want: ytDescription{Title: "Some Single", Artists: []string{"Indie Artist"}, Released: "2023-05-05"},
+			ok:   true,
+		},
+		{
+			name: "several artists",
+			desc: "Provided to YouTube by Sony Music Entertainment\n\nTaki Taki · DJ Snake · Selena Gomez · Ozuna · Cardi B\n\nCarte Blanche\n\n© 2019 Geffen Records\n\nISRC: US-UM7-18-00123",
+			want: ytDescription{Title: "Taki Taki", Artists: []string{"DJ Snake", "Selena Gomez", "Ozuna", "Cardi B"},
+				Album: "Carte Blanche", Label: "Geffen Records", ISRC: "USUM71800123"},
+			ok: true,
+		},
+		{
+			name: "CRLF line endings",
+			desc: strings.ReplaceAll(ytFullDescription, "\n", "\r\n"),
+			want: ytDescription{Title: "Blinding Lights", Artists: []string{"The Weeknd"}, Album: "After Hours",
+				Label: "The Weeknd XO, Inc., marketed by Republic Records", Released: "2020-03-20", ISRC: "USUG11904206"},
+			ok: true,
+		},
+		{
+			// Text before the credits, e.g. a channel's promotion, is skipped
+			name: "preamble",
+			desc: "Stream the album now!\nProvided to YouTube by Label\nSong · Band\nRecord",
+			want: ytDescription{Title: "Song", Artists: []string{"Band"}, Album: "Record"},
+			ok:   true,
+		},
+		{
+			name: "uploaded video",
+			desc: "Official video for Blinding Lights · The Weeknd\n\nISRC: USUG11904206\nFollow The Weeknd: https://theweeknd.com",
+		},
+		{
+			name: "no artist line",
+			desc: "Provided to YouTube by Label\n\nJust A Title\n\nRecord",
+		},
+		{
+			name: "nothing after the distributor",
+			desc: "Provided to YouTube by Label",
+		},
+		{
+			name: "empty",
+		},
+	}
+	for _, tt := range tests {
+		got, ok := parseYTDescription(tt.desc)
+		if ok != tt.ok {
+			t.Errorf("%s: ok = %v, want %v", tt.name, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if got.Title != tt.want.Title || !slices.Equal(got.Artists, tt.want.Artists) || got.Album != tt.want.Album ||
+			got.Label != tt.want.Label || got.Released != tt.want.Released || got.ISRC != tt.want.ISRC {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestYTDescriptionApply(t *testing.T) {
+	d, ok := parseYTDescription("Provided to YouTube by Label\n\nTaki Taki · DJ Snake · Ozuna")
+	if !ok {
+		t.Fatal("description not parsed")
+	}
+	tr := models.Track{Title: "DJ Snake - Taki Taki (Official Video)", Album: "From Search", ISRC: "USUM71800123"}
+	d.apply(&tr)
+	// An empty album or ISRC keeps what the track had
+	want := models.Track{Title: "Taki Taki", Artist: "DJ Snake, Ozuna", Album: "From Search", ISRC: "USUM71800123"}
+	if tr.Title != want.Title || tr.Artist != want.Artist || tr.Album != want.Album || tr.ISRC != want.ISRC {
+		t.Errorf("applied track = %+v, want %+v", tr, want)
+	}
+}
@@ -0,0 +1,343 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// TitleRules configures how YouTube video titles are split into artist, title
+// and featured artists. The defaults cover the common English, Indian and
+// Japanese upload styles; a JSON file can replace any of the lists (see
+// LoadTitleRules).
+type TitleRules struct {
+	// Noise are regular expressions removed from titles, e.g. "(Official Video)".
+	Noise []string `json:"noise"`
+	// Separators split "Artist - Title", tried in order. Pipes are handled
+	// separately as credit fields.
+	Separators []string `json:"separators"`
+	// Featuring are the words that introduce featured artists, matched
+	// case-insensitively with an optional trailing dot.
+	Featuring []string `json:"featuring"`
+	// CreditLabels are regular expressions for the label of a pipe-separated
+	// credit field, e.g. "Singer:", stripped before the field is used as an artist.
+	CreditLabels []string `json:"credit_labels"`
+	// UploaderSuffixes are cut from a channel name before it is used as the
+	// artist, e.g. "VEVO".
+	UploaderSuffixes []string `json:"uploader_suffixes"`
+}
+
+// DefaultTitleRules returns the built-in rules.
+func DefaultTitleRules() TitleRules {
+	return TitleRules{
+		Noise: []string{
+			// Lenticular brackets hold tags like 【MV】 or 【Official】, never the song
+			`【[^】]*】`,
+			// Any bracketed group mentioning the kind of upload
+			`(?i)[(\[][^)\]]*\b(?:official|video|audio|lyrics?|lyrical|visuali[sz]er|mv|m/v|hd|hq|4k|8k|uhd|remaster(?:ed)?|full song)\b[^)\]]*[)\]]`,
+			// The same as trailing unbracketed words
+			`(?i)\s+(?:official\s+(?:music\s+|lyric\s+|hd\s+)?(?:video|audio)|(?:lyric(?:al)?|music)\s+video|visuali[sz]er)\s*$`,
+		},
+		Separators: []string{" - ", " – ", " — ", " ― ", " － ", " : "},
+		Featuring:  []string{"feat", "ft", "featuring"},
+		CreditLabels: []string{
+			`(?i)^(?:singers?|vocals?|music|composers?|composed by|lyrics?|lyricists?|artists?)\s*[:\-–]\s*`,
+		},
+		UploaderSuffixes: []string{"VEVO", " Official", " - Topic"},
+	}
+}
+
+// LoadTitleRules reads rules from a JSON file with the TitleRules fields. Lists
+// present in the file replace the defaults; absent ones keep them.
+func LoadTitleRules(path string) (TitleRules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return TitleRules{}, err
+	}
+
+	var file TitleRules
+	if err := json.Unmarshal(data, &file); err != nil {
+		return TitleRules{}, fmt.Errorf("parse title rules %s: %w", path, err)
+	}
+
+	rules := DefaultTitleRules()
+	if file.Noise != nil {
+		rules.Noise = file.Noise
+	}
+	if file.Separators != nil {
+		rules.Separators = file.Separators
+	}
+	if file.Featuring != nil {
+		rules.Featuring = file.Featuring
+	}
+	if file.CreditLabels != nil {
+		rules.CreditLabels = file.CreditLabels
+	}
+	if file.UploaderSuffixes != nil {
+		rules.UploaderSuffixes = file.UploaderSuffixes
+	}
+
+	if _, err := compileTitleRules(rules); err != nil {
+		return TitleRules{}, fmt.Errorf("title rules %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// SetTitleRules replaces the rules ParseYTTitle uses.
+func SetTitleRules(r TitleRules) error {
+	c, err := compileTitleRules(r)
+	if err != nil {
+		return err
+	}
+	activeTitleRules.Store(c)
+	return nil
+}
+
+var activeTitleRules atomic.Pointer[titleRules]
+
+func init() {
+	c, err := compileTitleRules(DefaultTitleRules())
+	if err != nil {
+		panic(err)
+	}
+	activeTitleRules.Store(c)
+}
+
+type titleRules struct {
+	noise            []*regexp.Regexp
+	separators       []string
+	featBracketed    *regexp.Regexp
+	featTrailing     *regexp.Regexp
+	creditLabels     []*regexp.Regexp
+	uploaderSuffixes []string
+}
+
+func compileTitleRules(r TitleRules) (*titleRules, error) {
+	c := &titleRules{uploaderSuffixes: r.UploaderSuffixes}
+
+	for _, p := range r.Noise {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("noise pattern %q: %w", p, err)
+		}
+		c.noise = append(c.noise, re)
+	}
+	for _, p := range r.CreditLabels {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("credit label pattern %q: %w", p, err)
+		}
+		c.creditLabels = append(c.creditLabels, re)
+	}
+	for _, s := range r.Separators {
+		if strings.TrimSpace(s) == "" {
+			return nil, fmt.Errorf("separator %q is blank", s)
+		}
+		c.separators = append(c.separators, s)
+	}
+
+	if len(r.Featuring) > 0 {
+		words := make([]string, len(r.Featuring))
+		for i, w := range r.Featuring {
+			words[i] = regexp.QuoteMeta(strings.TrimSuffix(w, "."))
+		}
+		feat := `(?:` + strings.Join(words, "|") + `)\.?\s+`
+		c.featBracketed = regexp.MustCompile(`(?i)\s*[(\[]\s*` + feat + `([^)\]]+)[)\]]`)
+		// The word must follow something, or "Feat of Strength" loses its title
+		c.featTrailing = regexp.MustCompile(`(?i)\s` + feat + `(.+)$`)
+	}
+	return c, nil
+}
+
+// YTTitle is a video title split into its parts. Casing is kept as uploaded.
+type YTTitle struct {
+	Artist string
+	Title  string
+	// Featuring lists featured artists, comma-separated.
+	Featuring string
+}
+
+var (
+	spaceRegex   = regexp.MustCompile(`\s{2,}`)
+	pipeRegex    = regexp.MustCompile(`\s*[|｜]\s*`)
+	cornerQuotes = regexp.MustCompile(`^(.+?)(?:\s+(?i:M/?V|PV))?\s*[「『](.+?)[」』]`)
+	// Featured artists are listed as "A, B & C"; an unspaced "&" stays in the name
+	artistListRegex = regexp.MustCompile(`\s*(?:,| & )\s*`)
+)
+
+// ParseYTTitle splits a video title using the active rules. In order:
+//
+//   - "Artist - Topic" channels name the artist, and the title is the song
+//   - Artist「Title」 and Artist『Title』
+//   - Title | Credit | Credit, where the credits become the artist unless the
+//     first field is itself "Artist - Title" with a credited or uploading artist
+//   - Artist - Title (or Title - Artist when the channel is the right side)
+//   - otherwise the channel is the artist
+//
+// Featured artists are moved out of both parts into Featuring.
+func ParseYTTitle(rawTitle, uploader string) YTTitle {
+	return activeTitleRules.Load().parse(rawTitle, uploader)
+}
+
+func (r *titleRules) parse(rawTitle, uploader string) YTTitle {
+	t := r.clean(rawTitle)
+
+	var out YTTitle
+	if artist, ok := topicArtist(uploader); ok {
+		out = YTTitle{Artist: artist, Title: t}
+	} else if m := cornerQuotes.FindStringSubmatch(t); m != nil {
+		out = YTTitle{Artist: m[1], Title: m[2]}
+	} else if fields := splitFields(t); len(fields) > 1 {
+		out = r.parseFields(fields, uploader)
+	} else if artist, title, ok := r.split(t, uploader); ok {
+		out = YTTitle{Artist: artist, Title: title}
+	} else {
+		out = YTTitle{Artist: r.cleanUploader(uploader), Title: t}
+	}
+
+	var featured []string
+	out.Artist, featured = r.extractFeaturing(out.Artist, featured)
+	out.Title, featured = r.extractFeaturing(out.Title, featured)
+	out.Title = trimQuotes(out.Title)
+	out.Featuring = strings.Join(featured, ", ")
+	return out
+}
+
+// clean removes noise and collapses the whitespace and stray separators it leaves.
+func (r *titleRules) clean(s string) string {
+	for _, re := range r.noise {
+		s = re.ReplaceAllString(s, " ")
+	}
+	s = spaceRegex.ReplaceAllString(s, " ")
+	return strings.Trim(s, " -–—|｜")
+}
+
+// split applies the first separator found in s.
+func (r *titleRules) split(s, uploader string) (artist, title string, ok bool) {
+	for _, sep := range r.separators {
+		left, right, found := strings.Cut(s, sep)
+		left, right = strings.TrimSpace(left), strings.TrimSpace(right)
+		if !found || left == "" || right == "" {
+			continue
+		}
+		// "Title - Artist" from the artist's own channel
+		if up := r.cleanUploader(uploader); up != "" && strings.EqualFold(right, up) && !strings.EqualFold(left, up) {
+			return right, left, true
+		}
+		return left, right, true
+	}
+	return "", "", false
+}
+
+func (r *titleRules) parseFields(fields []string, uploader string) YTTitle {
+	var credits []string
+	for _, f := range fields[1:] {
+		for _, re := range r.creditLabels {
+			f = re.ReplaceAllString(f, "")
+		}
+		if f = strings.TrimSpace(f); f != "" {
+			credits = append(credits, f)
+		}
+	}
+
+	artist, title, ok := r.split(fields[0], uploader)
+	switch {
+	case ok && len(credits) == 0:
+		return YTTitle{Artist: artist, Title: title}
+	case ok && (isCredited(artist, credits) || strings.EqualFold(artist, r.cleanUploader(uploader))):
+		return YTTitle{Artist: artist, Title: title}
+	case ok && isCredited(title, credits):
+		return YTTitle{Artist: title, Title: artist}
+	case ok:
+		// Label uploads read "Title - Movie | Cast | Composer | Singer"; the
+		// right side is context, not the song
+		return YTTitle{Artist: strings.Join(credits, ", "), Title: artist}
+	case len(credits) == 0:
+		return YTTitle{Artist: r.cleanUploader(uploader), Title: fields[0]}
+	}
+	return YTTitle{Artist: strings.Join(credits, ", "), Title: fields[0]}
+}
+
+func isCredited(name string, credits []string) bool {
+	for _, c := range credits {
+		if strings.EqualFold(name, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// extractFeaturing removes "(feat. X)" or a trailing "ft. X" from s and adds X
+// to featured.
+func (r *titleRules) extractFeaturing(s string, featured []string) (string, []string) {
+	if r.featBracketed == nil {
+		return s, featured
+	}
+	rest, names := s, ""
+	if m := r.featBracketed.FindStringSubmatchIndex(s); m != nil {
+		rest, names = s[:m[0]]+s[m[1]:], s[m[2]:m[3]]
+	} else if m := r.featTrailing.FindStringSubmatchIndex(s); m != nil {
+		rest, names = s[:m[0]], s[m[2]:m[3]]
+	}
+	rest = strings.TrimSpace(spaceRegex.ReplaceAllString(rest, " "))
+	if rest == "" {
+		// Nothing would be left; the words belong to the name itself
+		return strings.TrimSpace(s), featured
+	}
+	return rest, appendArtists(featured, names)
+}
+
+// cleanUploader turns a channel name into an artist name.
+func (r *titleRules) cleanUploader(uploader string) string {
+	u := strings.TrimSpace(uploader)
+	for _, suffix := range r.uploaderSuffixes {
+		if trimmed, ok := strings.CutSuffix(u, suffix); ok && strings.TrimSpace(trimmed) != "" {
+			u = strings.TrimSpace(trimmed)
+		}
+	}
+	return u
+}
+
+func splitFields(s string) []string {
+	var fields []string
+	for _, f := range pipeRegex.Split(s, -1) {
+		if f = strings.TrimSpace(f); f != "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// appendArtists adds the names of an "A, B & C" list that are not yet present.
+func appendArtists(list []string, names string) []string {
+	for _, n := range artistListRegex.Split(names, -1) {
+		n = strings.TrimSpace(n)
+		if n == "" {
+			continue
+		}
+		dup := false
+		for _, existing := range list {
+			if strings.EqualFold(existing, n) {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			list = append(list, n)
+		}
+	}
+	return list
+}
+
+// trimQuotes removes quotes around a whole title, as in Artist - "Title".
+func trimQuotes(s string) string {
+	for _, q := range [][2]string{{`"`, `"`}, {"“", "”"}, {"'", "'"}} {
+		if len(s) > len(q[0])+len(q[1]) && strings.HasPrefix(s, q[0]) && strings.HasSuffix(s, q[1]) {
+			return strings.TrimSpace(s[len(q[0]) : len(s)-len(q[1])])
+		}
+	}
+	return s
+}
@@ -0,0 +1,93 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseYTTitle(t *testing.T) {
+	tests := []struct {
+		title, uploader string
+		want            YTTitle
+	}{
+		// Pipe-separated credits from Indian label channels
+		{`Tum Hi Ho (From "Aashiqui 2") | Mithoon | Arijit Singh`, "T-Series",
+			YTTitle{Artist: "Mithoon, Arijit Singh", Title: `Tum Hi Ho (From "Aashiqui 2")`}},
+		{`Song Name (From "Movie") | Composer: A. R. Rahman | Singer: Shreya Ghoshal`, "Sony Music India",
+			YTTitle{Artist: "A. R. Rahman, Shreya Ghoshal", Title: `Song Name (From "Movie")`}},
+		{`Song Name - Movie | Cast | Composer | Singer`, "Label",
+			YTTitle{Artist: "Cast, Composer, Singer", Title: "Song Name"}},
+		{`Arijit Singh - Kesariya | Pritam | Arijit Singh`, "Sony Music India",
+			YTTitle{Artist: "Arijit Singh", Title: "Kesariya"}},
+
+		// Japanese styles
+		{`YOASOBI「夜に駆ける」Official Music Video`, "Ayase / YOASOBI", YTTitle{Artist: "YOASOBI", Title: "夜に駆ける"}},
+		{`【MV】米津玄師 - Lemon`, "Kenshi Yonezu", YTTitle{Artist: "米津玄師", Title: "Lemon"}},
+		{`Lemon - 米津玄師`, "米津玄師", YTTitle{Artist: "米津玄師", Title: "Lemon"}},
+
+		// Noise
+		{`Daft Punk - Get Lucky [4K]`, "DaftPunkVEVO", YTTitle{Artist: "Daft Punk", Title: "Get Lucky"}},
+		{`The Weeknd - Blinding Lights Official Lyric Video`, "TheWeekndVEVO", YTTitle{Artist: "The Weeknd", Title: "Blinding Lights"}},
+		{`AC/DC - Back In Black (Official Video)`, "AC/DC", YTTitle{Artist: "AC/DC", Title: "Back In Black"}},
+		{`Drake - "Hotline Bling"`, "DrakeVEVO", YTTitle{Artist: "Drake", Title: "Hotline Bling"}},
+
+		// Featured artists
+		{`Calvin Harris - This Is What You Came For ft. Rihanna`, "CalvinHarrisVEVO",
+			YTTitle{Artist: "Calvin Harris", Title: "This Is What You Came For", Featuring: "Rihanna"}},
+		{`DJ Snake - Taki Taki (feat. Selena Gomez, Ozuna & Cardi B)`, "DJSnakeVEVO",
+			YTTitle{Artist: "DJ Snake", Title: "Taki Taki", Featuring: "Selena Gomez, Ozuna, Cardi B"}},
+		{`Macklemore feat. Ryan Lewis - Can't Hold Us`, "Macklemore",
+			YTTitle{Artist: "Macklemore", Title: "Can't Hold Us", Featuring: "Ryan Lewis"}},
+
+		// Casing is kept and channels fill in the artist
+		{`deadmau5 - Strobe`, "deadmau5", YTTitle{Artist: "deadmau5", Title: "Strobe"}},
+		{`Strobe`, "deadmau5 - Topic", YTTitle{Artist: "deadmau5", Title: "Strobe"}},
+
+		// Other separators
+		{`Artist – Title`, "x", YTTitle{Artist: "Artist", Title: "Title"}},
+		{`Artist : Title`, "x", YTTitle{Artist: "Artist", Title: "Title"}},
+
+		// Titles that start with a featuring word keep it
+		{`Feat of Strength`, "Some Band", YTTitle{Artist: "Some Band", Title: "Feat of Strength"}},
+		{`Featuring You - Band`, "Band", YTTitle{Artist: "Band", Title: "Featuring You"}},
+		{`Featuring You`, "Some Band", YTTitle{Artist: "Some Band", Title: "Featuring You"}},
+		{`Ft. Lauderdale - Band`, "Band", YTTitle{Artist: "Band", Title: "Ft. Lauderdale"}},
+		{`(feat. Someone)`, "Some Band", YTTitle{Artist: "Some Band", Title: "(feat. Someone)"}},
+	}
+	for _, tt := range tests {
+		if got := ParseYTTitle(tt.title, tt.uploader); got != tt.want {
+			t.Errorf("ParseYTTitle(%q, %q) = %+v, want %+v", tt.title, tt.uploader, got, tt.want)
+		}
+	}
+}
+
+func TestLoadTitleRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(path, []byte(`{"separators": [" ~ "], "featuring": ["with"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := LoadTitleRules(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules.Noise) != len(DefaultTitleRules().Noise) {
+		t.Errorf("noise patterns were not kept from the defaults")
+	}
+
+	c, err := compileTitleRules(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := YTTitle{Artist: "Artist", Title: "Song", Featuring: "Guest"}
+	if got := c.parse("Artist ~ Song with Guest (Official Video)", ""); got != want {
+		t.Errorf("parse with loaded rules = %+v, want %+v", got, want)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"noise": ["("]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadTitleRules(path); err == nil {
+		t.Error("LoadTitleRules accepted an invalid pattern")
+	}
+}
@@ -0,0 +1,232 @@
+package spotifetch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultConfigPath is read when SPOTIFETCH_CONFIG is not set. A missing file is
+// not an error: the built-in values are used.
+const DefaultConfigPath = "./data/spotifetch.json"
+
+// configCheckInterval bounds how often the config file is stat'ed for changes.
+const configCheckInterval = 10 * time.Second
+
+// Config holds the values Spotify rotates with web-player releases. Entries in the
+// JSON file override the built-in ones key by key, so an operator only needs to
+// ship what changed:
+//
+//	{
+//	  "totp_secrets": {"62": [12, 34, ...]},
+//	  "persisted_queries": {"fetchPlaylist": ["<new hash>", "<old hash>"]}
+//	}
+type Config struct {
+	// TOTPSecrets maps a secret version to its obfuscated byte list. Versions are
+	// tried newest first until Spotify accepts one.
+	TOTPSecrets map[int][]int `json:"totp_secrets"`
+	// PersistedQueries maps a GraphQL operation to candidate sha256 hashes, tried
+	// in order until one is recognized.
+	PersistedQueries map[string][]string `json:"persisted_queries"`
+}
+
+func defaultConfig() Config {
+	return Config{
+		TOTPSecrets: map[int][]int{
+			59: {123, 105, 79, 70, 110, 59, 52, 125, 60, 49, 80, 70, 89, 75, 80, 86, 63, 53, 123, 37, 117, 49, 52, 93, 77, 62, 47, 86, 48, 104, 68, 72},
+			60: {79, 109, 69, 123, 90, 65, 46, 74, 94, 34, 58, 48, 70, 71, 92, 85, 122, 63, 91, 64, 87, 87},
+			61: {44, 55, 47, 42, 70, 40, 34, 114, 76, 74, 50, 111, 120, 97, 75, 76, 94, 102, 43, 69, 49, 120, 118, 80, 64, 78},
+		},
+		PersistedQueries: map[string][]string{
+			"getTrack":                  {"612585ae06ba435ad26369870deaae23b5c8800a256cd8a57e08eddc25a37294"},
+			"getAlbum":                  {"b9bfabef66ed756e5e13f68a942deb60bd4125ec1f1be8cc42769dc0259b4b10"},
+			"fetchPlaylist":             {"bb67e0af06e8d6f52b531f97468ee4acd44cd0f82b988e15c2ea47b1148efc77"},
+			"queryArtistOverview":       {"446130b4a0aa6522a686aafccddb0ae849165b5e0436fd802f96e0243617b5d8"},
+			"queryArtistDiscographyAll": {"5e07d323febb57b4a56a42abbf781490e58764aa45feb6e3dc0591564fc56599"},
+			"searchDesktop":             {"fcad5a3e0d5af727fb76966f06971c19cfa2275e6ff7671196753e008611873c"},
+		},
+	}
+}
+
+// TOTPVersions returns the configured secret versions, newest first.
+func (c Config) TOTPVersions() []int {
+	versions := make([]int, 0, len(c.TOTPSecrets))
+	for v := range c.TOTPSecrets {
+		versions = append(versions, v)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+	return versions
+}
+
+// configStore hot-reloads the config file when its modification time changes.
+type configStore struct {
+	mu        sync.Mutex
+	path      string
+	cfg       Config
+	modTime   time.Time
+	checkedAt time.Time
+
+	// Versions and hashes that last worked, tried first on the next request
+	goodTOTP int
+	goodHash map[string]string
+}
+
+var configs = &configStore{}
+
+// CurrentConfig returns the active config, reloading the file if it changed.
+func CurrentConfig() Config {
+	return configs.current()
+}
+
+// ReloadConfig re-reads the config file immediately.
+func ReloadConfig() error {
+	return configs.reload(true)
+}
+
+func (s *configStore) current() Config {
+	if err := s.reload(false); err != nil {
+		log.Printf("[SPOTIFETCH] config reload failed, keeping previous values: %v", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cfg
+}
+
+func (s *configStore) reload(force bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cfg.TOTPSecrets == nil {
+		s.cfg = defaultConfig()
+		s.goodHash = make(map[string]string)
+	}
+
+	now := time.Now()
+	if !force && now.Sub(s.checkedAt) < configCheckInterval {
+		return nil
+	}
+	s.checkedAt = now
+
+	path := os.Getenv("SPOTIFETCH_CONFIG")
+	if path == "" {
+		path = DefaultConfigPath
+	}
+
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		if s.path != "" {
+			// The override was removed; fall back to the built-in values
+			s.cfg, s.path, s.modTime = defaultConfig(), "", time.Time{}
+			s.goodTOTP, s.goodHash = 0, make(map[string]string)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !force && path == s.path && info.ModTime().Equal(s.modTime) {
+		return nil
+	}
+
+	cfg, err := loadConfigFile(path)
+	if err != nil {
+		return err
+	}
+
+	s.cfg, s.path, s.modTime = cfg, path, info.ModTime()
+	s.goodTOTP, s.goodHash = 0, make(map[string]string)
+	log.Printf("[SPOTIFETCH] loaded config %s (totp versions %v)", path, cfg.TOTPVersions())
+	return nil
+}
+
+func loadConfigFile(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	var override Config
+	if err := json.Unmarshal(data, &override); err != nil {
+		return Config{}, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	cfg := defaultConfig()
+	for v, secret := range override.TOTPSecrets {
+		if len(secret) == 0 {
+			delete(cfg.TOTPSecrets, v)
+			continue
+		}
+		cfg.TOTPSecrets[v] = secret
+	}
+	for op, hashes := range override.PersistedQueries {
+		if len(hashes) > 0 {
+			cfg.PersistedQueries[op] = hashes
+		}
+	}
+
+	if len(cfg.TOTPSecrets) == 0 {
+		return Config{}, fmt.Errorf("%s leaves no TOTP secrets", path)
+	}
+	return cfg, nil
+}
+
+// totpOrder returns the versions to try, starting with the last one that worked.
+func (s *configStore) totpOrder(cfg Config) []int {
+	s.mu.Lock()
+	good := s.goodTOTP
+	s.mu.Unlock()
+	if _, ok := cfg.TOTPSecrets[good]; !ok {
+		good = 0
+	}
+	return preferFirst(cfg.TOTPVersions(), good)
+}
+
+func (s *configStore) markTOTP(version int) {
+	s.mu.Lock()
+	s.goodTOTP = version
+	s.mu.Unlock()
+}
+
+// hashOrder returns the candidate hashes for an operation, last good one first.
+func (s *configStore) hashOrder(cfg Config, operation string) []string {
+	s.mu.Lock()
+	good := s.goodHash[operation]
+	s.mu.Unlock()
+
+	hashes := cfg.PersistedQueries[operation]
+	if good == "" {
+		return hashes
+	}
+	ordered := []string{good}
+	for _, h := range hashes {
+		if h != good {
+			ordered = append(ordered, h)
+		}
+	}
+	return ordered
+}
+
+func (s *configStore) markHash(operation, hash string) {
+	s.mu.Lock()
+	s.goodHash[operation] = hash
+	s.mu.Unlock()
+}
+
+func preferFirst(versions []int, good int) []int {
+	if good == 0 {
+		return versions
+	}
+	ordered := []int{good}
+	for _, v := range versions {
+		if v != good {
+			ordered = append(ordered, v)
+		}
+	}
+	return ordered
+}
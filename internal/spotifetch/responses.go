@@ -0,0 +1,836 @@
+package spotifetch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SchemaError reports a GraphQL response that no longer has the shape this
+// package expects, naming the path that is missing or has changed type.
+type SchemaError struct {
+	Operation string
+	Path      string
+	Problem   string
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("%v: %s response schema changed: %s %s", SpotifyError, e.Operation, e.Path, e.Problem)
+}
+
+func (e *SchemaError) Unwrap() error { return SpotifyError }
+
+func missing(operation, path string) error {
+	return &SchemaError{Operation: operation, Path: path, Problem: "is missing"}
+}
+
+// decodeResponse unmarshals a GraphQL body into out, turning type mismatches
+// into schema errors that carry the offending path.
+func decodeResponse(operation string, body []byte, out interface{}) error {
+	err := json.Unmarshal(body, out)
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return &SchemaError{
+			Operation: operation,
+			Path:      typeErr.Field,
+			Problem:   fmt.Sprintf("is a JSON %s, expected %s", typeErr.Value, typeErr.Type),
+		}
+	}
+	return err
+}
+
+// notFound is the __typename Spotify uses for unions that resolved to nothing.
+const notFound = "NotFound"
+
+/* =========================
+   Shared fragments
+   ========================= */
+
+// flexInt accepts numbers and numeric strings; date parts come back as either.
+// Anything else reads as 0, which callers treat as unknown.
+type flexInt int
+
+func (f *flexInt) UnmarshalJSON(b []byte) error {
+	n, _ := strconv.Atoi(strings.Trim(string(b), `"`))
+	*f = flexInt(n)
+	return nil
+}
+
+type gqlImageSource struct {
+	URL       string  `json:"url"`
+	Width     float64 `json:"width"`
+	Height    float64 `json:"height"`
+	MaxWidth  float64 `json:"maxWidth"`
+	MaxHeight float64 `json:"maxHeight"`
+}
+
+type gqlSources struct {
+	Sources []gqlImageSource `json:"sources"`
+}
+
+func (s gqlSources) firstURL() string {
+	if len(s.Sources) > 0 {
+		return s.Sources[0].URL
+	}
+	return ""
+}
+
+// gqlCoverArt covers both coverArt nodes and visualIdentity, which nests its
+// sources under squareCoverImage.
+type gqlCoverArt struct {
+	Sources          []gqlImageSource `json:"sources"`
+	SquareCoverImage *struct {
+		Image struct {
+			Data gqlSources `json:"data"`
+		} `json:"image"`
+	} `json:"squareCoverImage"`
+}
+
+func (c *gqlCoverArt) cover() coverSet {
+	if c == nil {
+		return coverSet{}
+	}
+	if len(c.Sources) > 0 {
+		return coverFromSources(c.Sources)
+	}
+	if c.SquareCoverImage != nil {
+		return coverFromSources(c.SquareCoverImage.Image.Data.Sources)
+	}
+	return coverSet{}
+}
+
+type gqlArtists struct {
+	Items []struct {
+		URI     string `json:"uri"`
+		Profile struct {
+			Name string `json:"name"`
+		} `json:"profile"`
+	} `json:"items"`
+}
+
+func (a gqlArtists) names() []string {
+	names := make([]string, 0, len(a.Items))
+	for _, item := range a.Items {
+		names = append(names, item.Profile.Name)
+	}
+	return names
+}
+
+func (a gqlArtists) ids() []string {
+	ids := []string{}
+	for _, item := range a.Items {
+		if id := idFromURI(item.URI); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+type gqlDuration struct {
+	TotalMilliseconds float64 `json:"totalMilliseconds"`
+}
+
+func (d gqlDuration) formatted() string {
+	return getString(extractDuration(d.TotalMilliseconds), "formatted")
+}
+
+type gqlDate struct {
+	IsoString string  `json:"isoString"`
+	Year      flexInt `json:"year"`
+	Month     flexInt `json:"month"`
+	Day       flexInt `json:"day"`
+}
+
+// release returns the date as YYYY-MM-DD (or just YYYY when that is all Spotify
+// knows) along with the year.
+func (d gqlDate) release() (string, int) {
+	if d.IsoString != "" {
+		date := strings.Split(d.IsoString, "T")[0]
+		year, _ := strconv.Atoi(strings.Split(date, "-")[0])
+		return date, year
+	}
+	if d.Year == 0 {
+		return "", 0
+	}
+	if d.Month != 0 && d.Day != 0 {
+		return fmt.Sprintf("%d-%02d-%02d", d.Year, d.Month, d.Day), int(d.Year)
+	}
+	return strconv.Itoa(int(d.Year)), int(d.Year)
+}
+
+// idFromURI returns the last segment of a spotify:type:id URI.
+func idFromURI(uri string) string {
+	if !strings.Contains(uri, ":") {
+		return ""
+	}
+	parts := strings.Split(uri, ":")
+	return parts[len(parts)-1]
+}
+
+/* =========================
+   Covers
+   ========================= */
+
+type coverSet struct {
+	Small  string
+	Medium string
+	Large  string
+}
+
+// first returns the smallest available size.
+func (c coverSet) first() string {
+	switch {
+	case c.Small != "":
+		return c.Small
+	case c.Medium != "":
+		return c.Medium
+	default:
+		return c.Large
+	}
+}
+
+// coverFromSources picks the 300px and 640px renditions and derives the
+// full-size URL from the image ID embedded in them.
+func coverFromSources(sources []gqlImageSource) coverSet {
+	type sourceInfo struct {
+		url   string
+		width float64
+	}
+
+	filtered := []sourceInfo{}
+	for _, s := range sources {
+		if s.URL == "" {
+			continue
+		}
+		width, height := s.Width, s.Height
+		if width == 0 {
+			width = s.MaxWidth
+		}
+		if height == 0 {
+			height = s.MaxHeight
+		}
+		if (width > 64 && height > 64) || (width == 0 && height == 0) {
+			filtered = append(filtered, sourceInfo{url: s.URL, width: width})
+		}
+	}
+
+	sort.Slice(filtered, func(i, j int) bool {
+		return filtered[i].width < filtered[j].width
+	})
+
+	var c coverSet
+	var imageID, fallbackURL string
+	prefixes := []string{"ab67616d0000b273", "ab67616d00001e02", "ab67616d00004851"}
+
+	for _, source := range filtered {
+		switch source.width {
+		case 300:
+			c.Small = source.url
+		case 640:
+			c.Medium = source.url
+		case 0:
+			fallbackURL = source.url
+		}
+
+		if imageID != "" {
+			continue
+		}
+		name := source.url
+		if i := strings.LastIndex(name, "/image/"); i >= 0 {
+			name = strings.Split(name[i+len("/image/"):], "?")[0]
+		}
+		for _, prefix := range prefixes {
+			if i := strings.Index(name, prefix); i >= 0 {
+				imageID = name[i+len(prefix):]
+				break
+			}
+		}
+	}
+
+	if imageID != "" {
+		c.Large = "https://i.scdn.co/image/ab67616d000082c1" + imageID
+	}
+	if c == (coverSet{}) && fallbackURL != "" {
+		c = coverSet{Small: fallbackURL, Medium: fallbackURL, Large: fallbackURL}
+	}
+	return c
+}
+
+/* =========================
+   getTrack
+   ========================= */
+
+type getTrackResponse struct {
+	Data *struct {
+		TrackUnion *gqlTrack `json:"trackUnion"`
+	} `json:"data"`
+}
+
+type gqlTrack struct {
+	Typename       string       `json:"__typename"`
+	ID             string       `json:"id"`
+	URI            string       `json:"uri"`
+	Name           string       `json:"name"`
+	TrackNumber    int          `json:"trackNumber"`
+	DiscNumber     int          `json:"discNumber"`
+	Playcount      string       `json:"playcount"`
+	Duration       gqlDuration  `json:"duration"`
+	Artists        gqlArtists   `json:"artists"`
+	FirstArtist    gqlArtists   `json:"firstArtist"`
+	OtherArtists   gqlArtists   `json:"otherArtists"`
+	VisualIdentity *gqlCoverArt `json:"visualIdentity"`
+	AlbumOfTrack   *struct {
+		ID        string       `json:"id"`
+		URI       string       `json:"uri"`
+		Name      string       `json:"name"`
+		Date      gqlDate      `json:"date"`
+		CoverArt  *gqlCoverArt `json:"coverArt"`
+		Artists   gqlArtists   `json:"artists"`
+		Copyright struct {
+			Items []struct {
+				Type string `json:"type"`
+				Text string `json:"text"`
+			} `json:"items"`
+		} `json:"copyright"`
+		Tracks struct {
+			TotalCount int `json:"totalCount"`
+			Items      []struct {
+				Track struct {
+					DiscNumber int `json:"discNumber"`
+				} `json:"track"`
+			} `json:"items"`
+		} `json:"tracks"`
+	} `json:"albumOfTrack"`
+}
+
+func (r *getTrackResponse) track() (*gqlTrack, error) {
+	const op = "getTrack"
+	if r.Data == nil {
+		return nil, missing(op, "data")
+	}
+	t := r.Data.TrackUnion
+	if t == nil {
+		return nil, missing(op, "data.trackUnion")
+	}
+	if t.Typename == notFound {
+		return nil, fmt.Errorf("%w: track not found", SpotifyError)
+	}
+	if t.ID == "" && t.URI == "" {
+		return nil, missing(op, "data.trackUnion.id")
+	}
+	if t.Name == "" {
+		return nil, missing(op, "data.trackUnion.name")
+	}
+	return t, nil
+}
+
+// toAPITrack flattens a track, using the separately fetched album (which may be
+// nil) for album artists and label.
+func (t *gqlTrack) toAPITrack(album *gqlAlbum) *apiTrackResponse {
+	res := &apiTrackResponse{
+		ID:       t.ID,
+		Name:     t.Name,
+		Duration: t.Duration.formatted(),
+		Track:    t.TrackNumber,
+		Disc:     t.DiscNumber,
+		Discs:    1,
+		Plays:    t.Playcount,
+	}
+	if res.ID == "" {
+		res.ID = idFromURI(t.URI)
+	}
+	if res.Disc == 0 {
+		res.Disc = 1
+	}
+
+	artists := t.Artists.names()
+	if len(artists) == 0 {
+		artists = append(t.FirstArtist.names(), t.OtherArtists.names()...)
+	}
+
+	cover := t.VisualIdentity.cover()
+
+	if a := t.AlbumOfTrack; a != nil {
+		if len(artists) == 0 {
+			artists = a.Artists.names()
+		}
+		if cover == (coverSet{}) {
+			cover = a.CoverArt.cover()
+		}
+
+		copyrights := []string{}
+		for _, item := range a.Copyright.Items {
+			if item.Type != "P" {
+				copyrights = append(copyrights, item.Text)
+			}
+		}
+		res.Copyright = strings.Join(copyrights, ", ")
+
+		for _, item := range a.Tracks.Items {
+			disc := item.Track.DiscNumber
+			if disc == 0 {
+				disc = 1
+			}
+			if disc > res.Discs {
+				res.Discs = disc
+			}
+		}
+
+		res.Album.ID = a.ID
+		if res.Album.ID == "" {
+			res.Album.ID = idFromURI(a.URI)
+		}
+		res.Album.Name = a.Name
+		res.Album.Released, res.Album.Year = a.Date.release()
+		res.Album.Tracks = a.Tracks.TotalCount
+
+		if album != nil {
+			res.Album.Artists = strings.Join(album.Artists.names(), ", ")
+			res.Album.Label = album.Label
+		}
+	}
+
+	res.Artists = strings.Join(artists, ", ")
+	res.Cover.Small, res.Cover.Medium, res.Cover.Large = cover.Small, cover.Medium, cover.Large
+	return res
+}
+
+/* =========================
+   getAlbum
+   ========================= */
+
+type getAlbumResponse struct {
+	Data *struct {
+		AlbumUnion *gqlAlbum `json:"albumUnion"`
+	} `json:"data"`
+}
+
+type gqlAlbum struct {
+	Typename string       `json:"__typename"`
+	URI      string       `json:"uri"`
+	Name     string       `json:"name"`
+	Label    string       `json:"label"`
+	Date     gqlDate      `json:"date"`
+	CoverArt *gqlCoverArt `json:"coverArt"`
+	Artists  gqlArtists   `json:"artists"`
+	TracksV2 *struct {
+		TotalCount int `json:"totalCount"`
+		Items      []struct {
+			Track *struct {
+				URI       string      `json:"uri"`
+				Name      string      `json:"name"`
+				Playcount string      `json:"playcount"`
+				Duration  gqlDuration `json:"duration"`
+				Artists   gqlArtists  `json:"artists"`
+			} `json:"track"`
+		} `json:"items"`
+	} `json:"tracksV2"`
+}
+
+func (r *getAlbumResponse) album() (*gqlAlbum, error) {
+	const op = "getAlbum"
+	if r.Data == nil {
+		return nil, missing(op, "data")
+	}
+	a := r.Data.AlbumUnion
+	if a == nil {
+		return nil, missing(op, "data.albumUnion")
+	}
+	if a.Typename == notFound {
+		return nil, fmt.Errorf("%w: album not found", SpotifyError)
+	}
+	if a.URI == "" {
+		return nil, missing(op, "data.albumUnion.uri")
+	}
+	if a.TracksV2 == nil {
+		return nil, missing(op, "data.albumUnion.tracksV2")
+	}
+	for i, item := range a.TracksV2.Items {
+		if item.Track == nil {
+			return nil, missing(op, fmt.Sprintf("data.albumUnion.tracksV2.items[%d].track", i))
+		}
+		if item.Track.URI == "" {
+			return nil, missing(op, fmt.Sprintf("data.albumUnion.tracksV2.items[%d].track.uri", i))
+		}
+	}
+	return a, nil
+}
+
+func (a *gqlAlbum) toAPIAlbum() *apiAlbumResponse {
+	res := &apiAlbumResponse{
+		ID:      idFromURI(a.URI),
+		Name:    a.Name,
+		Artists: strings.Join(a.Artists.names(), ", "),
+		Cover:   a.CoverArt.cover().first(),
+	}
+	res.ReleaseDate, _ = a.Date.release()
+
+	for _, item := range a.TracksV2.Items {
+		t := item.Track
+		res.Tracks = append(res.Tracks, apiAlbumTrack{
+			ID:        idFromURI(t.URI),
+			Name:      t.Name,
+			Artists:   strings.Join(t.Artists.names(), ", "),
+			ArtistIds: t.Artists.ids(),
+			Duration:  t.Duration.formatted(),
+			Plays:     t.Playcount,
+		})
+	}
+	res.Count = len(res.Tracks)
+	return res
+}
+
+/* =========================
+   fetchPlaylist
+   ========================= */
+
+type fetchPlaylistResponse struct {
+	Data *struct {
+		PlaylistV2 *gqlPlaylist `json:"playlistV2"`
+	} `json:"data"`
+}
+
+type gqlPlaylist struct {
+	Typename    string `json:"__typename"`
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	OwnerV2     struct {
+		Data struct {
+			Name   string      `json:"name"`
+			Avatar *gqlSources `json:"avatar"`
+		} `json:"data"`
+	} `json:"ownerV2"`
+	Images    *gqlPlaylistImages `json:"images"`
+	ImagesV2  *gqlPlaylistImages `json:"imagesV2"`
+	Followers json.RawMessage    `json:"followers"`
+	Content   *struct {
+		TotalCount int                   `json:"totalCount"`
+		Items      []gqlPlaylistItemNode `json:"items"`
+	} `json:"content"`
+}
+
+type gqlPlaylistImages struct {
+	Items   []gqlSources     `json:"items"`
+	Sources []gqlImageSource `json:"sources"`
+}
+
+type gqlPlaylistItemNode struct {
+	Attributes []struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	} `json:"attributes"`
+	ItemV2 struct {
+		Data *struct {
+			Typename      string      `json:"__typename"`
+			ID            string      `json:"id"`
+			URI           string      `json:"uri"`
+			Name          string      `json:"name"`
+			TrackDuration gqlDuration `json:"trackDuration"`
+			Artists       gqlArtists  `json:"artists"`
+			AlbumOfTrack  *struct {
+				URI      string       `json:"uri"`
+				Name     string       `json:"name"`
+				CoverArt *gqlCoverArt `json:"coverArt"`
+			} `json:"albumOfTrack"`
+		} `json:"data"`
+	} `json:"itemV2"`
+}
+
+func (r *fetchPlaylistResponse) playlist() (*gqlPlaylist, error) {
+	const op = "fetchPlaylist"
+	if r.Data == nil {
+		return nil, missing(op, "data")
+	}
+	p := r.Data.PlaylistV2
+	if p == nil {
+		return nil, missing(op, "data.playlistV2")
+	}
+	if p.Typename == notFound {
+		return nil, fmt.Errorf("%w: playlist not found", SpotifyError)
+	}
+	if p.Content == nil {
+		return nil, missing(op, "data.playlistV2.content")
+	}
+	for i, item := range p.Content.Items {
+		d := item.ItemV2.Data
+		// Episodes and unavailable entries are skipped, not treated as drift
+		if d == nil || (d.Typename != "" && d.Typename != "Track") {
+			continue
+		}
+		if d.ID == "" && d.URI == "" {
+			return nil, missing(op, fmt.Sprintf("data.playlistV2.content.items[%d].itemV2.data.uri", i))
+		}
+		if d.Name == "" {
+			return nil, missing(op, fmt.Sprintf("data.playlistV2.content.items[%d].itemV2.data.name", i))
+		}
+	}
+	return p, nil
+}
+
+func (p *gqlPlaylist) toAPIPlaylist() *apiPlaylistResponse {
+	res := &apiPlaylistResponse{
+		ID:          idFromURI(p.URI),
+		Name:        p.Name,
+		Description: p.Description,
+	}
+
+	owner := p.OwnerV2.Data
+	res.Owner.Name = owner.Name
+	if owner.Avatar != nil {
+		res.Owner.Avatar = owner.Avatar.firstURL()
+		for _, s := range owner.Avatar.Sources {
+			if s.Width == 300 {
+				res.Owner.Avatar = s.URL
+				break
+			}
+		}
+	}
+
+	images := p.Images
+	if images == nil {
+		images = p.ImagesV2
+	}
+	if images != nil {
+		if len(images.Items) > 0 {
+			res.Cover = images.Items[0].firstURL()
+		}
+		if res.Cover == "" {
+			res.Cover = gqlSources{Sources: images.Sources}.firstURL()
+		}
+	}
+
+	// followers is either a number or {"totalCount": n} depending on the client version
+	var followers struct {
+		TotalCount float64 `json:"totalCount"`
+	}
+	var count float64
+	if json.Unmarshal(p.Followers, &count) == nil {
+		res.Followers = int(count)
+	} else if json.Unmarshal(p.Followers, &followers) == nil {
+		res.Followers = int(followers.TotalCount)
+	}
+
+	for _, item := range p.Content.Items {
+		d := item.ItemV2.Data
+		if d == nil || (d.Typename != "" && d.Typename != "Track") {
+			continue
+		}
+
+		var rank, status string
+		for _, attr := range item.Attributes {
+			switch attr.Key {
+			case "rank":
+				rank = attr.Value
+			case "status":
+				status = attr.Value
+			}
+		}
+
+		id := d.ID
+		if id == "" {
+			id = idFromURI(d.URI)
+		}
+
+		var albumName, albumID, cover string
+		if a := d.AlbumOfTrack; a != nil {
+			albumName = a.Name
+			albumID = idFromURI(a.URI)
+			cover = a.CoverArt.cover().first()
+		}
+
+		res.Tracks = append(res.Tracks, apiPlaylistTrack{
+			ID:        id,
+			Cover:     cover,
+			Title:     d.Name,
+			Artist:    strings.Join(d.Artists.names(), ", "),
+			ArtistIds: d.Artists.ids(),
+			Plays:     rank,
+			Status:    status,
+			Album:     albumName,
+			AlbumID:   albumID,
+			Duration:  d.TrackDuration.formatted(),
+		})
+	}
+
+	res.Count = len(res.Tracks)
+	if p.Content.TotalCount > 0 {
+		res.Count = p.Content.TotalCount
+	}
+	return res
+}
+
+/* =========================
+   queryArtistOverview / queryArtistDiscographyAll
+   ========================= */
+
+type artistOverviewResponse struct {
+	Data *struct {
+		ArtistUnion *gqlArtist `json:"artistUnion"`
+	} `json:"data"`
+}
+
+type gqlArtist struct {
+	Typename string `json:"__typename"`
+	URI      string `json:"uri"`
+	Profile  *struct {
+		Name      string `json:"name"`
+		Verified  bool   `json:"verified"`
+		Biography struct {
+			Text string `json:"text"`
+		} `json:"biography"`
+	} `json:"profile"`
+	HeaderImage struct {
+		Data gqlSources `json:"data"`
+	} `json:"headerImage"`
+	Stats struct {
+		Followers        float64 `json:"followers"`
+		MonthlyListeners float64 `json:"monthlyListeners"`
+		WorldRank        float64 `json:"worldRank"`
+	} `json:"stats"`
+	Visuals struct {
+		Gallery struct {
+			Items []gqlSources `json:"items"`
+		} `json:"gallery"`
+		AvatarImage *gqlCoverArt `json:"avatarImage"`
+	} `json:"visuals"`
+	Discography struct {
+		All gqlReleaseList `json:"all"`
+	} `json:"discography"`
+}
+
+type artistDiscographyResponse struct {
+	Data *struct {
+		ArtistUnion *struct {
+			Discography struct {
+				All gqlReleaseList `json:"all"`
+			} `json:"discography"`
+		} `json:"artistUnion"`
+	} `json:"data"`
+}
+
+type gqlReleaseList struct {
+	TotalCount int                  `json:"totalCount"`
+	Items      []gqlDiscographyItem `json:"items"`
+}
+
+type gqlDiscographyItem struct {
+	Releases *struct {
+		Items []gqlRelease `json:"items"`
+	} `json:"releases"`
+	Album *gqlRelease `json:"album"`
+}
+
+// release returns the item's first release; older responses put it under album.
+func (i gqlDiscographyItem) release() *gqlRelease {
+	if i.Releases != nil {
+		if len(i.Releases.Items) > 0 {
+			return &i.Releases.Items[0]
+		}
+		return nil
+	}
+	return i.Album
+}
+
+type gqlRelease struct {
+	ID       string       `json:"id"`
+	URI      string       `json:"uri"`
+	Name     string       `json:"name"`
+	Type     string       `json:"type"`
+	Date     gqlDate      `json:"date"`
+	CoverArt *gqlCoverArt `json:"coverArt"`
+}
+
+func (r *artistOverviewResponse) artist() (*gqlArtist, error) {
+	const op = "queryArtistOverview"
+	if r.Data == nil {
+		return nil, missing(op, "data")
+	}
+	a := r.Data.ArtistUnion
+	if a == nil {
+		return nil, missing(op, "data.artistUnion")
+	}
+	if a.Typename == notFound {
+		return nil, fmt.Errorf("%w: artist not found", SpotifyError)
+	}
+	if a.Profile == nil {
+		return nil, missing(op, "data.artistUnion.profile")
+	}
+	if a.Profile.Name == "" {
+		return nil, missing(op, "data.artistUnion.profile.name")
+	}
+	return a, nil
+}
+
+func (r *artistDiscographyResponse) releases() (*gqlReleaseList, error) {
+	const op = "queryArtistDiscographyAll"
+	if r.Data == nil {
+		return nil, missing(op, "data")
+	}
+	if r.Data.ArtistUnion == nil {
+		return nil, missing(op, "data.artistUnion")
+	}
+	return &r.Data.ArtistUnion.Discography.All, nil
+}
+
+// toAPIArtist flattens an artist. releases replaces the overview's own (partial)
+// discography when the full list was paged in.
+func (a *gqlArtist) toAPIArtist(releases []gqlDiscographyItem) *apiArtistResponse {
+	res := &apiArtistResponse{
+		ID:     idFromURI(a.URI),
+		Name:   a.Profile.Name,
+		Header: a.HeaderImage.Data.firstURL(),
+	}
+	res.Profile.Name = a.Profile.Name
+	res.Profile.Verified = a.Profile.Verified
+	res.Profile.Biography = html.UnescapeString(a.Profile.Biography.Text)
+
+	res.Stats.Followers = int(a.Stats.Followers)
+	res.Stats.Listeners = int(a.Stats.MonthlyListeners)
+	res.Stats.Rank = int(a.Stats.WorldRank)
+
+	res.Gallery = []string{}
+	for _, item := range a.Visuals.Gallery.Items {
+		if u := item.firstURL(); u != "" {
+			res.Gallery = append(res.Gallery, u)
+		}
+	}
+
+	avatar := a.Visuals.AvatarImage.cover()
+	res.Avatar = avatar.Medium
+	if res.Avatar == "" {
+		res.Avatar = avatar.Small
+	}
+
+	res.Discography.Total = a.Discography.All.TotalCount
+	if len(releases) == 0 {
+		releases = a.Discography.All.Items
+	} else {
+		res.Discography.Total = len(releases)
+	}
+
+	for _, item := range releases {
+		rel := item.release()
+		if rel == nil {
+			continue
+		}
+		id := rel.ID
+		if id == "" {
+			id = idFromURI(rel.URI)
+		}
+		date, year := rel.Date.release()
+		res.Discography.All = append(res.Discography.All, apiArtistRelease{
+			ID:    id,
+			Name:  rel.Name,
+			Type:  strings.ToLower(rel.Type),
+			Cover: rel.CoverArt.cover().Medium,
+			Date:  date,
+			Year:  year,
+		})
+	}
+	return res
+}
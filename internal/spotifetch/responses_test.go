@@ -0,0 +1,243 @@
+package spotifetch
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+// withoutField returns body with the object key at path (dot-separated, with
+// numeric segments indexing arrays) removed.
+func withoutField(t *testing.T, body []byte, path string) []byte {
+	t.Helper()
+	var doc any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(path, ".")
+	node := doc
+	for _, p := range parts[:len(parts)-1] {
+		switch n := node.(type) {
+		case map[string]any:
+			node = n[p]
+		case []any:
+			var i int
+			if err := json.Unmarshal([]byte(p), &i); err != nil {
+				t.Fatalf("bad index %q in %s", p, path)
+			}
+			node = n[i]
+		}
+	}
+	obj, ok := node.(map[string]any)
+	if !ok {
+		t.Fatalf("%s does not name an object key", path)
+	}
+	delete(obj, parts[len(parts)-1])
+	out, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestDecodeGetTrack(t *testing.T) {
+	var resp getTrackResponse
+	if err := decodeResponse("getTrack", readFixture(t, "getTrack.json"), &resp); err != nil {
+		t.Fatal(err)
+	}
+	track, err := resp.track()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := track.toAPITrack(nil)
+
+	if got.ID != "0VjIjW4GlUZAMYd2vXMi3b" || got.Name != "Blinding Lights" || got.Artists != "The Weeknd" {
+		t.Errorf("track = %s %q by %q", got.ID, got.Name, got.Artists)
+	}
+	if got.Duration != "3:20" || got.Track != 9 || got.Disc != 1 || got.Plays != "4830169447" {
+		t.Errorf("duration %s, track %d, disc %d, plays %s", got.Duration, got.Track, got.Disc, got.Plays)
+	}
+	if got.Album.Name != "After Hours" || got.Album.Released != "2020-03-20" || got.Album.Year != 2020 || got.Album.Tracks != 14 {
+		t.Errorf("album = %+v", got.Album)
+	}
+	if got.Copyright != "© 2020 The Weeknd XO, Inc." {
+		t.Errorf("copyright = %q, want only the C line", got.Copyright)
+	}
+	if got.Cover.Large != "https://i.scdn.co/image/ab67616d000082c18863bc11d2aa12b54f5aeb36" {
+		t.Errorf("large cover = %q", got.Cover.Large)
+	}
+}
+
+func TestDecodeGetAlbum(t *testing.T) {
+	var resp getAlbumResponse
+	if err := decodeResponse("getAlbum", readFixture(t, "getAlbum.json"), &resp); err != nil {
+		t.Fatal(err)
+	}
+	album, err := resp.album()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := album.toAPIAlbum()
+
+	if got.ID != "6DEjYFkNZh67HP7R9PSZvv" || got.Name != "Discovery" || got.Artists != "Daft Punk" || got.ReleaseDate != "2001-03-12" {
+		t.Errorf("album = %s %q by %q released %s", got.ID, got.Name, got.Artists, got.ReleaseDate)
+	}
+	if got.Count != 3 || len(got.Tracks) != 3 {
+		t.Fatalf("%d tracks (count %d), want 3", len(got.Tracks), got.Count)
+	}
+	first := got.Tracks[0]
+	if first.ID != "0DiWol3AO6WpXZgp0goxAV" || first.Name != "One More Time" || first.Duration != "5:20" {
+		t.Errorf("first track = %+v", first)
+	}
+	if len(first.ArtistIds) != 1 || first.ArtistIds[0] != "4tZwfgrHOc3mvqYlEYSvVi" {
+		t.Errorf("first track artist IDs = %v", first.ArtistIds)
+	}
+}
+
+func TestDecodeFetchPlaylist(t *testing.T) {
+	var resp fetchPlaylistResponse
+	if err := decodeResponse("fetchPlaylist", readFixture(t, "fetchPlaylist.json"), &resp); err != nil {
+		t.Fatal(err)
+	}
+	playlist, err := resp.playlist()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := playlist.toAPIPlaylist()
+
+	if got.ID != "37i9dQZF1DXcBWIGoYBM5M" || got.Name != "Today's Top Hits" || got.Owner.Name != "Spotify" {
+		t.Errorf("playlist = %s %q by %q", got.ID, got.Name, got.Owner.Name)
+	}
+	if got.Owner.Avatar != "https://i.scdn.co/image/ab67757000003b82avatar300" {
+		t.Errorf("owner avatar = %q, want the 300px one", got.Owner.Avatar)
+	}
+	if got.Followers != 34876512 || got.Count != 50 {
+		t.Errorf("followers %d, count %d", got.Followers, got.Count)
+	}
+	// The episode in between is skipped
+	if len(got.Tracks) != 2 || got.Tracks[0].Title != "Houdini" || got.Tracks[1].Title != "Espresso" {
+		t.Fatalf("tracks = %+v", got.Tracks)
+	}
+	if tr := got.Tracks[1]; tr.ID != "7tFiyTwD0nx5a1eklYtX2J" || tr.Artist != "Sabrina Carpenter" || tr.AlbumID != "2H6i2CrWgXE1HookLu8Au0" || tr.Plays != "3" {
+		t.Errorf("second track = %+v", tr)
+	}
+}
+
+func TestDecodeArtistOverview(t *testing.T) {
+	var resp artistOverviewResponse
+	if err := decodeResponse("queryArtistOverview", readFixture(t, "queryArtistOverview.json"), &resp); err != nil {
+		t.Fatal(err)
+	}
+	artist, err := resp.artist()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := artist.toAPIArtist(nil)
+
+	if got.ID != "4tZwfgrHOc3mvqYlEYSvVi" || got.Name != "Daft Punk" || !got.Profile.Verified {
+		t.Errorf("artist = %s %q verified=%v", got.ID, got.Name, got.Profile.Verified)
+	}
+	if got.Profile.Biography != "Thomas Bangalter & Guy-Manuel de Homem-Christo" {
+		t.Errorf("biography = %q", got.Profile.Biography)
+	}
+	if got.Stats.Followers != 9876543 || got.Stats.Listeners != 23456789 {
+		t.Errorf("stats = %+v", got.Stats)
+	}
+	if got.Avatar != "https://i.scdn.co/image/ab6761610000e5ebavatar640" || len(got.Gallery) != 1 {
+		t.Errorf("avatar %q, gallery %v", got.Avatar, got.Gallery)
+	}
+	if got.Discography.Total != 2 || len(got.Discography.All) != 2 {
+		t.Fatalf("discography = %+v", got.Discography)
+	}
+	// Date parts come back as numbers or strings
+	if r := got.Discography.All[1]; r.Name != "Random Access Memories" || r.Type != "album" || r.Date != "2013-05-17" || r.Year != 2013 {
+		t.Errorf("second release = %+v", r)
+	}
+}
+
+func TestSchemaErrors(t *testing.T) {
+	tests := []struct {
+		fixture string
+		remove  string
+		decode  func([]byte) error
+		want    string
+	}{
+		{"getTrack.json", "data.trackUnion.name", func(b []byte) error {
+			var r getTrackResponse
+			if err := decodeResponse("getTrack", b, &r); err != nil {
+				return err
+			}
+			_, err := r.track()
+			return err
+		}, "data.trackUnion.name"},
+		{"getAlbum.json", "data.albumUnion.tracksV2", func(b []byte) error {
+			var r getAlbumResponse
+			if err := decodeResponse("getAlbum", b, &r); err != nil {
+				return err
+			}
+			_, err := r.album()
+			return err
+		}, "data.albumUnion.tracksV2"},
+		{"getAlbum.json", "data.albumUnion.tracksV2.items.1.track.uri", func(b []byte) error {
+			var r getAlbumResponse
+			if err := decodeResponse("getAlbum", b, &r); err != nil {
+				return err
+			}
+			_, err := r.album()
+			return err
+		}, "data.albumUnion.tracksV2.items[1].track.uri"},
+		{"fetchPlaylist.json", "data.playlistV2.content.items.2.itemV2.data.name", func(b []byte) error {
+			var r fetchPlaylistResponse
+			if err := decodeResponse("fetchPlaylist", b, &r); err != nil {
+				return err
+			}
+			_, err := r.playlist()
+			return err
+		}, "data.playlistV2.content.items[2].itemV2.data.name"},
+		{"queryArtistOverview.json", "data.artistUnion.profile", func(b []byte) error {
+			var r artistOverviewResponse
+			if err := decodeResponse("queryArtistOverview", b, &r); err != nil {
+				return err
+			}
+			_, err := r.artist()
+			return err
+		}, "data.artistUnion.profile"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.remove, func(t *testing.T) {
+			err := tt.decode(withoutField(t, readFixture(t, tt.fixture), tt.remove))
+			var schemaErr *SchemaError
+			if !errors.As(err, &schemaErr) {
+				t.Fatalf("err = %v, want a SchemaError", err)
+			}
+			if schemaErr.Path != tt.want {
+				t.Errorf("path = %q, want %q", schemaErr.Path, tt.want)
+			}
+			if !errors.Is(err, SpotifyError) {
+				t.Errorf("SchemaError does not unwrap to SpotifyError")
+			}
+		})
+	}
+}
+
+func TestSchemaErrorOnTypeChange(t *testing.T) {
+	body := strings.Replace(string(readFixture(t, "getAlbum.json")), `"totalCount": 3`, `"totalCount": "3"`, 1)
+	var r getAlbumResponse
+	err := decodeResponse("getAlbum", []byte(body), &r)
+	var schemaErr *SchemaError
+	if !errors.As(err, &schemaErr) || schemaErr.Path != "data.albumUnion.tracksV2.totalCount" {
+		t.Errorf("err = %v, want a SchemaError for data.albumUnion.tracksV2.totalCount", err)
+	}
+}
@@ -0,0 +1,82 @@
+package spotifetch
+
+import (
+	"sync"
+	"time"
+)
+
+// tokenSkew is how long before their stated expiry cached tokens are refreshed,
+// so a token never runs out between the check and the request using it.
+const tokenSkew = time.Minute
+
+// tokenSet is a consistent snapshot of the credentials one query needs.
+type tokenSet struct {
+	accessToken   string
+	clientToken   string
+	clientVersion string
+}
+
+var (
+	defaultClient     *SpotifyClient
+	defaultClientOnce sync.Once
+)
+
+// DefaultClient returns the process-wide client. Its session is scraped once and
+// reused by every fetch until the tokens expire or Spotify rejects them.
+func DefaultClient() *SpotifyClient {
+	defaultClientOnce.Do(func() {
+		defaultClient = NewSpotifyClient()
+	})
+	return defaultClient
+}
+
+// EnsureSession initializes the client if it has no valid tokens. Concurrent
+// callers share a single refresh.
+func (c *SpotifyClient) EnsureSession() error {
+	_, err := c.session()
+	return err
+}
+
+func (c *SpotifyClient) session() (tokenSet, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.valid(time.Now()) {
+		if err := c.initialize(); err != nil {
+			return tokenSet{}, err
+		}
+	}
+
+	return tokenSet{
+		accessToken:   c.accessToken,
+		clientToken:   c.clientToken,
+		clientVersion: c.clientVersion,
+	}, nil
+}
+
+// valid reports whether both tokens are present and not about to expire. Tokens
+// without a known expiry are trusted until a query is rejected.
+func (c *SpotifyClient) valid(now time.Time) bool {
+	if c.accessToken == "" || c.clientToken == "" {
+		return false
+	}
+	if !c.accessExpiresAt.IsZero() && now.Add(tokenSkew).After(c.accessExpiresAt) {
+		return false
+	}
+	if !c.clientTokenExpiry.IsZero() && now.Add(tokenSkew).After(c.clientTokenExpiry) {
+		return false
+	}
+	return true
+}
+
+// invalidate drops the cached tokens after a 401, unless another caller already
+// replaced the rejected access token with a fresh one.
+func (c *SpotifyClient) invalidate(rejected string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.accessToken == rejected {
+		c.accessToken = ""
+		c.clientToken = ""
+	}
+}
@@ -8,16 +8,15 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"html"
 	"io"
+	"log"
 	"net/http"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	"sort"
-
 	"github.com/pquerna/otp"
 	"github.com/pquerna/otp/totp"
 )
@@ -32,6 +31,12 @@ type SpotifyClient struct {
 	deviceID      string
 	clientVersion string
 	cookies       map[string]string
+
+	// mu guards the token fields above; refreshes hold it for their whole duration
+	// so concurrent callers wait for one refresh instead of starting their own.
+	mu                sync.Mutex
+	accessExpiresAt   time.Time
+	clientTokenExpiry time.Time
 }
 
 func NewSpotifyClient() *SpotifyClient {
@@ -41,24 +46,10 @@ func NewSpotifyClient() *SpotifyClient {
 	}
 }
 
-func (c *SpotifyClient) getTOTPSecret() (int, []byte) {
-	secrets := map[int][]byte{
-		59: {123, 105, 79, 70, 110, 59, 52, 125, 60, 49, 80, 70, 89, 75, 80, 86, 63, 53, 123, 37, 117, 49, 52, 93, 77, 62, 47, 86, 48, 104, 68, 72},
-		60: {79, 109, 69, 123, 90, 65, 46, 74, 94, 34, 58, 48, 70, 71, 92, 85, 122, 63, 91, 64, 87, 87},
-		61: {44, 55, 47, 42, 70, 40, 34, 114, 76, 74, 50, 111, 120, 97, 75, 76, 94, 102, 43, 69, 49, 120, 118, 80, 64, 78},
-	}
-
-	version := 61
-	secretList := secrets[version]
-	return version, secretList
-}
-
-func (c *SpotifyClient) generateTOTP() (string, int, error) {
-	version, secretList := c.getTOTPSecret()
-
+func (c *SpotifyClient) generateTOTP(secretList []int) (string, error) {
 	transformed := make([]byte, len(secretList))
 	for i, b := range secretList {
-		transformed[i] = b ^ byte((i%33)+9)
+		transformed[i] = byte(b) ^ byte((i%33)+9)
 	}
 
 	var joined strings.Builder
@@ -69,7 +60,7 @@ func (c *SpotifyClient) generateTOTP() (string, int, error) {
 	hexStr := hex.EncodeToString([]byte(joined.String()))
 	hexBytes, err := hex.DecodeString(hexStr)
 	if err != nil {
-		return "", 0, err
+		return "", err
 	}
 
 	secret := base32Encode(hexBytes)
@@ -77,15 +68,10 @@ func (c *SpotifyClient) generateTOTP() (string, int, error) {
 
 	key, err := otp.NewKeyFromURL(fmt.Sprintf("otpauth://totp/secret?secret=%s", secret))
 	if err != nil {
-		return "", 0, err
+		return "", err
 	}
 
-	totpCode, err := totp.GenerateCode(key.Secret(), time.Now())
-	if err != nil {
-		return "", 0, err
-	}
-
-	return totpCode, version, nil
+	return totp.GenerateCode(key.Secret(), time.Now())
 }
 
 func base32Encode(data []byte) string {
@@ -93,15 +79,39 @@ func base32Encode(data []byte) string {
 	return b32.EncodeToString(data)
 }
 
+// getAccessToken negotiates a TOTP secret version: configured versions are tried
+// newest first (the last accepted one before that) until Spotify issues a token.
 func (c *SpotifyClient) getAccessToken() error {
-	totpCode, version, err := c.generateTOTP()
+	cfg := CurrentConfig()
+	var failures []string
+
+	for _, version := range configs.totpOrder(cfg) {
+		retry, err := c.requestAccessToken(version, cfg.TOTPSecrets[version])
+		if err == nil {
+			configs.markTOTP(version)
+			return nil
+		}
+		if !retry {
+			return err
+		}
+		log.Printf("[SPOTIFETCH] TOTP secret version %d rejected: %v", version, err)
+		failures = append(failures, fmt.Sprintf("v%d: %v", version, err))
+	}
+
+	return fmt.Errorf("%w: no TOTP secret version accepted (%s)", SpotifyError, strings.Join(failures, "; "))
+}
+
+// requestAccessToken reports retry=true when the failure points at the secret
+// rather than the network, so the next version is worth trying.
+func (c *SpotifyClient) requestAccessToken(version int, secretList []int) (retry bool, err error) {
+	totpCode, err := c.generateTOTP(secretList)
 	if err != nil {
-		return err
+		return true, err
 	}
 
 	req, err := http.NewRequest("GET", "https://open.spotify.com/api/token", nil)
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	q := req.URL.Query()
@@ -117,21 +127,30 @@ func (c *SpotifyClient) getAccessToken() error {
 
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return err
+		return false, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return fmt.Errorf("%w: access token request failed: HTTP %d", SpotifyError, resp.StatusCode)
+		return resp.StatusCode < 500, fmt.Errorf("HTTP %d", resp.StatusCode)
 	}
 
 	var data map[string]interface{}
 	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return err
+		return false, err
+	}
+
+	accessToken := getString(data, "accessToken")
+	if accessToken == "" {
+		return true, errors.New("empty access token")
 	}
 
-	c.accessToken = getString(data, "accessToken")
+	c.accessToken = accessToken
 	c.clientID = getString(data, "clientId")
+	c.accessExpiresAt = time.Time{}
+	if ms := getFloat64(data, "accessTokenExpirationTimestampMs"); ms > 0 {
+		c.accessExpiresAt = time.UnixMilli(int64(ms))
+	}
 
 	for _, cookie := range resp.Cookies() {
 		if cookie.Name == "sp_t" {
@@ -140,7 +159,7 @@ func (c *SpotifyClient) getAccessToken() error {
 		c.cookies[cookie.Name] = cookie.Value
 	}
 
-	return nil
+	return false, nil
 }
 
 func (c *SpotifyClient) getSessionInfo() error {
@@ -253,11 +272,22 @@ func (c *SpotifyClient) getClientToken() error {
 
 	grantedToken := getMap(data, "granted_token")
 	c.clientToken = getString(grantedToken, "token")
+	c.clientTokenExpiry = time.Time{}
+	if secs := getFloat64(grantedToken, "refresh_after_seconds"); secs > 0 {
+		c.clientTokenExpiry = time.Now().Add(time.Duration(secs) * time.Second)
+	}
 
 	return nil
 }
 
+// Initialize scrapes a fresh session and tokens, replacing any cached ones.
 func (c *SpotifyClient) Initialize() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.initialize()
+}
+
+func (c *SpotifyClient) initialize() error {
 	if err := c.getSessionInfo(); err != nil {
 		return err
 	}
@@ -267,38 +297,170 @@ func (c *SpotifyClient) Initialize() error {
 	return c.getClientToken()
 }
 
+// Query runs a GraphQL request. A 401 means the cached tokens were revoked early,
+// so the session is re-initialized once and the request retried.
 func (c *SpotifyClient) Query(payload map[string]interface{}) (map[string]interface{}, error) {
-	if c.accessToken == "" || c.clientToken == "" {
-		if err := c.Initialize(); err != nil {
+	body, err := c.queryRaw(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *SpotifyClient) queryRaw(payload map[string]interface{}) ([]byte, error) {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	sess, err := c.session()
+	if err != nil {
+		return nil, err
+	}
+
+	body, status, err := c.query(sess, jsonData)
+	if status == http.StatusUnauthorized {
+		c.invalidate(sess.accessToken)
+		if sess, err = c.session(); err != nil {
 			return nil, err
 		}
+		body, _, err = c.query(sess, jsonData)
 	}
+	return body, err
+}
 
-	jsonData, err := json.Marshal(payload)
+// QueryError is a non-200 response from the GraphQL endpoint.
+type QueryError struct {
+	Status int
+	Body   string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("%v: API query failed: HTTP %d | %s", SpotifyError, e.Status, e.Body)
+}
+
+func (e *QueryError) Unwrap() error { return SpotifyError }
+
+// QueryOperation runs a persisted GraphQL operation and returns the generic
+// decoded response.
+func (c *SpotifyClient) QueryOperation(operation string, variables map[string]interface{}) (map[string]interface{}, error) {
+	body, err := c.queryOperationRaw(operation, variables)
 	if err != nil {
 		return nil, err
 	}
 
+	var result map[string]interface{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// QueryOperationInto runs a persisted GraphQL operation and decodes the response
+// into out. Fields whose type changed are reported as a *SchemaError.
+func (c *SpotifyClient) QueryOperationInto(operation string, variables map[string]interface{}, out interface{}) error {
+	body, err := c.queryOperationRaw(operation, variables)
+	if err != nil {
+		return err
+	}
+	return decodeResponse(operation, body, out)
+}
+
+// queryOperationRaw tries each configured hash for the operation (the last
+// accepted one first) until Spotify recognizes one.
+func (c *SpotifyClient) queryOperationRaw(operation string, variables map[string]interface{}) ([]byte, error) {
+	hashes := configs.hashOrder(CurrentConfig(), operation)
+	if len(hashes) == 0 {
+		return nil, fmt.Errorf("%w: no persisted query hash configured for %s", SpotifyError, operation)
+	}
+
+	var failures []string
+	for _, hash := range hashes {
+		payload := map[string]interface{}{
+			"variables":     variables,
+			"operationName": operation,
+			"extensions": map[string]interface{}{
+				"persistedQuery": map[string]interface{}{
+					"version":    1,
+					"sha256Hash": hash,
+				},
+			},
+		}
+
+		body, err := c.queryRaw(payload)
+		var reason string
+		var qe *QueryError
+		switch {
+		case err == nil && !persistedQueryMissing(body):
+			configs.markHash(operation, hash)
+			return body, nil
+		case err == nil:
+			reason = "PersistedQueryNotFound"
+		case errors.As(err, &qe) && qe.Status >= 400 && qe.Status < 500 && qe.Status != http.StatusTooManyRequests:
+			reason = fmt.Sprintf("HTTP %d", qe.Status)
+		default:
+			return nil, err
+		}
+
+		log.Printf("[SPOTIFETCH] %s hash %s rejected: %s", operation, shortHash(hash), reason)
+		failures = append(failures, shortHash(hash)+": "+reason)
+	}
+
+	return nil, fmt.Errorf("%w: no persisted query hash accepted for %s (%s)", SpotifyError, operation, strings.Join(failures, "; "))
+}
+
+// persistedQueryMissing detects the 200 response Spotify sends for unknown hashes.
+func persistedQueryMissing(body []byte) bool {
+	var resp struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if json.Unmarshal(body, &resp) != nil || (len(resp.Data) > 0 && string(resp.Data) != "null") {
+		return false
+	}
+	for _, e := range resp.Errors {
+		if strings.Contains(e.Message, "PersistedQuery") {
+			return true
+		}
+	}
+	return false
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
+
+func (c *SpotifyClient) query(sess tokenSet, jsonData []byte) ([]byte, int, error) {
 	req, err := http.NewRequest("POST", "https://api-partner.spotify.com/pathfinder/v2/query", bytes.NewBuffer(jsonData))
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	req.Header.Set("Authorization", "Bearer "+c.accessToken)
-	req.Header.Set("Client-Token", c.clientToken)
-	req.Header.Set("Spotify-App-Version", c.clientVersion)
+	req.Header.Set("Authorization", "Bearer "+sess.accessToken)
+	req.Header.Set("Client-Token", sess.clientToken)
+	req.Header.Set("Spotify-App-Version", sess.clientVersion)
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
 
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, resp.StatusCode, err
 	}
 
 	if resp.StatusCode != 200 {
@@ -306,15 +468,10 @@ func (c *SpotifyClient) Query(payload map[string]interface{}) (map[string]interf
 		if len(errorText) > 200 {
 			errorText = errorText[:200]
 		}
-		return nil, fmt.Errorf("%w: API query failed: HTTP %d | %s", SpotifyError, resp.StatusCode, errorText)
-	}
-
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, err
+		return nil, resp.StatusCode, &QueryError{Status: resp.StatusCode, Body: errorText}
 	}
 
-	return result, nil
+	return body, resp.StatusCode, nil
 }
 
 func getString(m map[string]interface{}, key string) string {
@@ -384,129 +541,40 @@ func extractArtists(artistsData map[string]interface{}) []map[string]interface{}
 }
 
 func extractCoverImage(coverData map[string]interface{}) map[string]interface{} {
-	if coverData == nil || len(coverData) == 0 {
+	if len(coverData) == 0 {
 		return nil
 	}
 
-	var sources []interface{}
-	if srcs, ok := coverData["sources"].([]interface{}); ok {
-		sources = srcs
-	} else if squareImg, ok := coverData["squareCoverImage"].(map[string]interface{}); ok {
-		if img, ok := squareImg["image"].(map[string]interface{}); ok {
-			if data, ok := img["data"].(map[string]interface{}); ok {
-				if srcs, ok := data["sources"].([]interface{}); ok {
-					sources = srcs
-				}
-			}
-		}
+	sources := getSlice(coverData, "sources")
+	if sources == nil {
+		sources = getSlice(getMap(getMap(getMap(coverData, "squareCoverImage"), "image"), "data"), "sources")
 	}
 
-	if sources == nil || len(sources) == 0 {
-		return nil
-	}
-
-	type sourceInfo struct {
-		url    string
-		width  float64
-		height float64
-	}
-
-	filteredSources := []sourceInfo{}
-	for _, s := range sources {
-		sMap, ok := s.(map[string]interface{})
+	typed := make([]gqlImageSource, 0, len(sources))
+	for _, src := range sources {
+		sMap, ok := src.(map[string]interface{})
 		if !ok {
 			continue
 		}
-		url := getString(sMap, "url")
-		if url == "" {
-			continue
-		}
-
-		width := getFloat64(sMap, "width")
-		if width == 0 {
-			width = getFloat64(sMap, "maxWidth")
-		}
-		height := getFloat64(sMap, "height")
-		if height == 0 {
-			height = getFloat64(sMap, "maxHeight")
-		}
-
-		if (width > 64 && height > 64) || (width == 0 && height == 0 && url != "") {
-			filteredSources = append(filteredSources, sourceInfo{url: url, width: width, height: height})
-		}
-	}
-
-	if len(filteredSources) == 0 {
-		return nil
-	}
-
-	sort.Slice(filteredSources, func(i, j int) bool {
-		return filteredSources[i].width < filteredSources[j].width
-	})
-
-	var smallURL, mediumURL, imageID, fallbackURL string
-
-	for _, source := range filteredSources {
-		if source.width == 300 {
-			smallURL = source.url
-		} else if source.width == 640 {
-			mediumURL = source.url
-		} else if source.width == 0 {
-			fallbackURL = source.url
-		}
-
-		if imageID == "" && source.url != "" {
-			if strings.Contains(source.url, "ab67616d0000b273") {
-				parts := strings.Split(source.url, "ab67616d0000b273")
-				if len(parts) > 1 {
-					imageID = parts[len(parts)-1]
-				}
-			} else if strings.Contains(source.url, "ab67616d00001e02") {
-				parts := strings.Split(source.url, "ab67616d00001e02")
-				if len(parts) > 1 {
-					imageID = parts[len(parts)-1]
-				}
-			} else if strings.Contains(source.url, "/image/") {
-				parts := strings.Split(source.url, "/image/")
-				if len(parts) > 1 {
-					imagePart := strings.Split(parts[len(parts)-1], "?")[0]
-					if len(imagePart) > 20 {
-						prefixes := []string{"ab67616d0000b273", "ab67616d00001e02", "ab67616d00004851"}
-						for _, prefix := range prefixes {
-							if strings.Contains(imagePart, prefix) {
-								subParts := strings.Split(imagePart, prefix)
-								if len(subParts) > 1 {
-									imageID = subParts[len(subParts)-1]
-									break
-								}
-							}
-						}
-					}
-				}
-			}
-		}
-	}
-
-	largeURL := ""
-	if imageID != "" {
-		largeURL = "https://i.scdn.co/image/ab67616d000082c1" + imageID
+		typed = append(typed, gqlImageSource{
+			URL:       getString(sMap, "url"),
+			Width:     getFloat64(sMap, "width"),
+			Height:    getFloat64(sMap, "height"),
+			MaxWidth:  getFloat64(sMap, "maxWidth"),
+			MaxHeight: getFloat64(sMap, "maxHeight"),
+		})
 	}
 
+	cover := coverFromSources(typed)
 	result := map[string]interface{}{}
-	if smallURL != "" {
-		result["small"] = smallURL
+	if cover.Small != "" {
+		result["small"] = cover.Small
 	}
-	if mediumURL != "" {
-		result["medium"] = mediumURL
+	if cover.Medium != "" {
+		result["medium"] = cover.Medium
 	}
-	if largeURL != "" {
-		result["large"] = largeURL
-	}
-
-	if len(result) == 0 && fallbackURL != "" {
-		result["small"] = fallbackURL
-		result["medium"] = fallbackURL
-		result["large"] = fallbackURL
+	if cover.Large != "" {
+		result["large"] = cover.Large
 	}
 
 	if len(result) == 0 {
@@ -524,828 +592,6 @@ func extractDuration(ms float64) map[string]interface{} {
 	}
 }
 
-func FilterTrack(data map[string]interface{}, albumFetchData ...map[string]interface{}) map[string]interface{} {
-	dataMap := getMap(data, "data")
-	trackData := getMap(dataMap, "trackUnion")
-	if len(trackData) == 0 {
-		return make(map[string]interface{})
-	}
-
-	var albumFetchDataMap map[string]interface{}
-	if len(albumFetchData) > 0 && albumFetchData[0] != nil {
-		albumFetchDataMap = albumFetchData[0]
-	}
-
-	artists := extractArtists(getMap(trackData, "artists"))
-
-	if len(artists) == 0 {
-		artists = []map[string]interface{}{}
-		firstArtistItems := getSlice(getMap(trackData, "firstArtist"), "items")
-		if firstArtistItems != nil {
-			for _, item := range firstArtistItems {
-				itemMap, ok := item.(map[string]interface{})
-				if !ok {
-					continue
-				}
-				if profile, exists := itemMap["profile"]; exists {
-					profileMap, ok := profile.(map[string]interface{})
-					if ok {
-						artistInfo := map[string]interface{}{
-							"name": getString(profileMap, "name"),
-						}
-						artists = append(artists, artistInfo)
-					}
-				}
-			}
-		}
-
-		otherArtistItems := getSlice(getMap(trackData, "otherArtists"), "items")
-		if otherArtistItems != nil {
-			for _, item := range otherArtistItems {
-				itemMap, ok := item.(map[string]interface{})
-				if !ok {
-					continue
-				}
-				if profile, exists := itemMap["profile"]; exists {
-					profileMap, ok := profile.(map[string]interface{})
-					if ok {
-						artistInfo := map[string]interface{}{
-							"name": getString(profileMap, "name"),
-						}
-						artists = append(artists, artistInfo)
-					}
-				}
-			}
-		}
-	}
-
-	if len(artists) == 0 {
-		albumData := getMap(trackData, "albumOfTrack")
-		if len(albumData) > 0 {
-			artists = extractArtists(getMap(albumData, "artists"))
-		}
-	}
-
-	albumData := getMap(trackData, "albumOfTrack")
-	var albumInfo map[string]interface{}
-	copyrightInfo := []map[string]interface{}{}
-	discInfo := map[string]interface{}{
-		"discNumber": getFloat64(trackData, "discNumber"),
-		"totalDiscs": nil,
-	}
-
-	if len(albumData) > 0 {
-		copyrightData := getMap(albumData, "copyright")
-		if len(copyrightData) > 0 {
-			copyrightItems := getSlice(copyrightData, "items")
-			if copyrightItems != nil {
-				for _, item := range copyrightItems {
-					itemMap, ok := item.(map[string]interface{})
-					if !ok {
-						continue
-					}
-					if getString(itemMap, "type") != "P" {
-						copyrightInfo = append(copyrightInfo, map[string]interface{}{
-							"text": getString(itemMap, "text"),
-						})
-					}
-				}
-			}
-		}
-
-		tracksData := getMap(albumData, "tracks")
-		if len(tracksData) > 0 {
-			discNumbers := make(map[int]bool)
-			trackItems := getSlice(tracksData, "items")
-			if trackItems != nil {
-				for _, item := range trackItems {
-					itemMap, ok := item.(map[string]interface{})
-					if !ok {
-						continue
-					}
-					trackItem := getMap(itemMap, "track")
-					if len(trackItem) > 0 {
-						discNum := int(getFloat64(trackItem, "discNumber"))
-						if discNum == 0 {
-							discNum = 1
-						}
-						discNumbers[discNum] = true
-					}
-				}
-			}
-			if len(discNumbers) > 0 {
-				maxDisc := 1
-				for discNum := range discNumbers {
-					if discNum > maxDisc {
-						maxDisc = discNum
-					}
-				}
-				discInfo["totalDiscs"] = maxDisc
-			}
-		}
-
-		dateInfo := getMap(albumData, "date")
-		releaseDate := getString(dateInfo, "isoString")
-		var releaseYear interface{}
-		if releaseDate == "" && len(dateInfo) > 0 {
-			yearStr := getString(dateInfo, "year")
-			monthStr := getString(dateInfo, "month")
-			dayStr := getString(dateInfo, "day")
-			if yearStr != "" {
-				year, err := strconv.Atoi(yearStr)
-				if err == nil {
-					releaseYear = year
-					if monthStr != "" && dayStr != "" {
-						month, _ := strconv.Atoi(monthStr)
-						day, _ := strconv.Atoi(dayStr)
-						releaseDate = fmt.Sprintf("%s-%02d-%02d", yearStr, month, day)
-					} else {
-						releaseDate = yearStr
-					}
-				}
-			}
-		} else if releaseDate != "" {
-			parts := strings.Split(releaseDate, "T")
-			if len(parts) > 0 {
-				releaseDate = parts[0]
-			} else {
-				parts = strings.Split(releaseDate, " ")
-				if len(parts) > 0 {
-					releaseDate = parts[0]
-				}
-			}
-			dateParts := strings.Split(releaseDate, "-")
-			if len(dateParts) > 0 && dateParts[0] != "" {
-				year, err := strconv.Atoi(dateParts[0])
-				if err == nil {
-					releaseYear = year
-				}
-			}
-		}
-
-		tracksTotalCount := float64(0)
-		if len(tracksData) > 0 {
-			tracksTotalCount = getFloat64(tracksData, "totalCount")
-		}
-
-		albumID := getString(albumData, "id")
-		if albumID == "" {
-			albumURI := getString(albumData, "uri")
-			if strings.Contains(albumURI, ":") {
-				parts := strings.Split(albumURI, ":")
-				albumID = parts[len(parts)-1]
-			}
-		}
-
-		albumArtistsString := ""
-		albumLabel := ""
-		if albumFetchDataMap != nil && len(albumFetchDataMap) > 0 {
-			albumUnionData := getMap(getMap(albumFetchDataMap, "data"), "albumUnion")
-			if len(albumUnionData) > 0 {
-				albumArtists := extractArtists(getMap(albumUnionData, "artists"))
-				if len(albumArtists) > 0 {
-					albumArtistNames := []string{}
-					for _, artist := range albumArtists {
-						albumArtistNames = append(albumArtistNames, getString(artist, "name"))
-					}
-					albumArtistsString = strings.Join(albumArtistNames, ", ")
-				}
-				albumLabel = getString(albumUnionData, "label")
-			}
-		}
-
-		albumInfo = map[string]interface{}{
-			"id":       albumID,
-			"name":     getString(albumData, "name"),
-			"released": releaseDate,
-			"year":     releaseYear,
-			"tracks":   int(tracksTotalCount),
-		}
-
-		if albumArtistsString != "" {
-			albumInfo["artists"] = albumArtistsString
-		}
-
-		if albumLabel != "" {
-			albumInfo["label"] = albumLabel
-		}
-	}
-
-	cover := extractCoverImage(getMap(trackData, "visualIdentity"))
-	if cover == nil && len(albumData) > 0 {
-		cover = extractCoverImage(getMap(albumData, "coverArt"))
-	}
-
-	durationMs := getFloat64(getMap(trackData, "duration"), "totalMilliseconds")
-	durationObj := extractDuration(durationMs)
-	durationString := getString(durationObj, "formatted")
-
-	artistNames := []string{}
-	for _, artist := range artists {
-		artistNames = append(artistNames, getString(artist, "name"))
-	}
-	artistsString := strings.Join(artistNames, ", ")
-
-	copyrightTexts := []string{}
-	for _, item := range copyrightInfo {
-		copyrightTexts = append(copyrightTexts, getString(item, "text"))
-	}
-	copyrightString := strings.Join(copyrightTexts, ", ")
-
-	discNumber := int(getFloat64(trackData, "discNumber"))
-	if discNumber == 0 {
-		discNumber = 1
-	}
-	totalDiscs := 1
-	if discInfo["totalDiscs"] != nil {
-		totalDiscs = discInfo["totalDiscs"].(int)
-	}
-
-	filtered := map[string]interface{}{
-		"id":        getString(trackData, "id"),
-		"name":      getString(trackData, "name"),
-		"artists":   artistsString,
-		"album":     albumInfo,
-		"duration":  durationString,
-		"track":     int(getFloat64(trackData, "trackNumber")),
-		"disc":      discNumber,
-		"discs":     totalDiscs,
-		"copyright": copyrightString,
-		"plays":     getString(trackData, "playcount"),
-		"cover":     cover,
-	}
-
-	return filtered
-}
-
-func FilterAlbum(data map[string]interface{}) map[string]interface{} {
-	dataMap := getMap(data, "data")
-	albumData := getMap(dataMap, "albumUnion")
-	if len(albumData) == 0 {
-		return make(map[string]interface{})
-	}
-
-	artists := extractArtists(getMap(albumData, "artists"))
-	artistNames := []string{}
-	for _, artist := range artists {
-		artistNames = append(artistNames, getString(artist, "name"))
-	}
-	albumArtistsString := strings.Join(artistNames, ", ")
-
-	coverObj := extractCoverImage(getMap(albumData, "coverArt"))
-	var cover interface{}
-	if coverObj != nil {
-
-		cover = getString(coverObj, "small")
-		if cover == "" {
-			cover = getString(coverObj, "medium")
-		}
-		if cover == "" {
-			cover = getString(coverObj, "large")
-		}
-	}
-
-	tracks := []map[string]interface{}{}
-	tracksData := getMap(albumData, "tracksV2")
-	trackItems := getSlice(tracksData, "items")
-	if trackItems != nil {
-		for _, item := range trackItems {
-			itemMap, ok := item.(map[string]interface{})
-			if !ok {
-				continue
-			}
-			track := getMap(itemMap, "track")
-			if len(track) == 0 {
-				continue
-			}
-
-			artistsData := getMap(track, "artists")
-			trackArtists := extractArtists(artistsData)
-			trackDurationMs := getFloat64(getMap(track, "duration"), "totalMilliseconds")
-			durationObj := extractDuration(trackDurationMs)
-			durationString := getString(durationObj, "formatted")
-
-			trackArtistNames := []string{}
-			artistIDs := []string{}
-
-			artistItems := getSlice(artistsData, "items")
-			if artistItems != nil {
-				for _, artistItem := range artistItems {
-					artistItemMap, ok := artistItem.(map[string]interface{})
-					if !ok {
-						continue
-					}
-					artistURI := getString(artistItemMap, "uri")
-					if artistURI != "" && strings.Contains(artistURI, ":") {
-						parts := strings.Split(artistURI, ":")
-						if len(parts) > 0 {
-							artistID := parts[len(parts)-1]
-							if artistID != "" {
-								artistIDs = append(artistIDs, artistID)
-							}
-						}
-					}
-				}
-			}
-
-			for _, artist := range trackArtists {
-				trackArtistNames = append(trackArtistNames, getString(artist, "name"))
-			}
-			trackArtistsString := strings.Join(trackArtistNames, ", ")
-
-			trackURI := getString(track, "uri")
-			trackID := ""
-			if strings.Contains(trackURI, ":") {
-				parts := strings.Split(trackURI, ":")
-				trackID = parts[len(parts)-1]
-			}
-
-			trackInfo := map[string]interface{}{
-				"id":        trackID,
-				"name":      getString(track, "name"),
-				"artists":   trackArtistsString,
-				"artistIds": artistIDs,
-				"duration":  durationString,
-				"plays":     getString(track, "playcount"),
-			}
-			tracks = append(tracks, trackInfo)
-		}
-	}
-
-	dateInfo := getMap(albumData, "date")
-	releaseDate := getString(dateInfo, "isoString")
-	if releaseDate != "" && strings.Contains(releaseDate, "T") {
-		parts := strings.Split(releaseDate, "T")
-		releaseDate = parts[0]
-	}
-
-	albumURI := getString(albumData, "uri")
-	albumID := ""
-	if strings.Contains(albumURI, ":") {
-		parts := strings.Split(albumURI, ":")
-		albumID = parts[len(parts)-1]
-	}
-
-	filtered := map[string]interface{}{
-		"id":          albumID,
-		"name":        getString(albumData, "name"),
-		"artists":     albumArtistsString,
-		"cover":       cover,
-		"releaseDate": releaseDate,
-		"count":       len(tracks),
-		"tracks":      tracks,
-	}
-
-	return filtered
-}
-
-func FilterPlaylist(data map[string]interface{}) map[string]interface{} {
-	dataMap := getMap(data, "data")
-	playlistData := getMap(dataMap, "playlistV2")
-	if len(playlistData) == 0 {
-		return make(map[string]interface{})
-	}
-
-	ownerData := getMap(getMap(playlistData, "ownerV2"), "data")
-	var ownerInfo map[string]interface{}
-	if len(ownerData) > 0 {
-		var avatarURL interface{}
-		avatarData := getMap(ownerData, "avatar")
-		if len(avatarData) > 0 {
-			sources := getSlice(avatarData, "sources")
-			if sources != nil {
-				for _, source := range sources {
-					sourceMap, ok := source.(map[string]interface{})
-					if !ok {
-						continue
-					}
-					if getFloat64(sourceMap, "width") == 300 {
-						avatarURL = getString(sourceMap, "url")
-						break
-					}
-				}
-				if avatarURL == nil && len(sources) > 0 {
-					if firstSource, ok := sources[0].(map[string]interface{}); ok {
-						avatarURL = getString(firstSource, "url")
-					}
-				}
-			}
-		}
-
-		ownerInfo = map[string]interface{}{
-			"name":   getString(ownerData, "name"),
-			"avatar": avatarURL,
-		}
-	}
-
-	imagesData := getMap(playlistData, "images")
-	if len(imagesData) == 0 {
-		imagesData = getMap(playlistData, "imagesV2")
-	}
-	var cover interface{}
-	if len(imagesData) > 0 {
-		imageItems := getSlice(imagesData, "items")
-		if imageItems != nil && len(imageItems) > 0 {
-			if firstImage, ok := imageItems[0].(map[string]interface{}); ok {
-				firstSources := getSlice(firstImage, "sources")
-				if firstSources != nil && len(firstSources) > 0 {
-					if firstSource, ok := firstSources[0].(map[string]interface{}); ok {
-						sourceURL := getString(firstSource, "url")
-						if sourceURL != "" {
-							cover = sourceURL
-						}
-					}
-				}
-			}
-		}
-		if cover == nil {
-			imageSources := getSlice(imagesData, "sources")
-			if imageSources != nil && len(imageSources) > 0 {
-				if firstSource, ok := imageSources[0].(map[string]interface{}); ok {
-					sourceURL := getString(firstSource, "url")
-					if sourceURL != "" {
-						cover = sourceURL
-					}
-				}
-			}
-		}
-	}
-
-	tracks := []map[string]interface{}{}
-	content := getMap(playlistData, "content")
-	contentItems := getSlice(content, "items")
-	if contentItems != nil {
-		for _, item := range contentItems {
-			itemMap, ok := item.(map[string]interface{})
-			if !ok {
-				continue
-			}
-			trackData := getMap(getMap(itemMap, "itemV2"), "data")
-			if len(trackData) == 0 {
-				continue
-			}
-
-			var rank interface{}
-			var status interface{}
-			attributes := getSlice(itemMap, "attributes")
-			if attributes != nil {
-				for _, attr := range attributes {
-					attrMap, ok := attr.(map[string]interface{})
-					if !ok {
-						continue
-					}
-					key := getString(attrMap, "key")
-					if key == "rank" {
-						rank = getString(attrMap, "value")
-					} else if key == "status" {
-						status = getString(attrMap, "value")
-					}
-				}
-			}
-
-			artistsData := getMap(trackData, "artists")
-			trackArtists := extractArtists(artistsData)
-			trackArtistNames := []string{}
-			artistIDs := []string{}
-
-			artistItems := getSlice(artistsData, "items")
-			if artistItems != nil {
-				for _, artistItem := range artistItems {
-					artistItemMap, ok := artistItem.(map[string]interface{})
-					if !ok {
-						continue
-					}
-					artistURI := getString(artistItemMap, "uri")
-					if artistURI != "" && strings.Contains(artistURI, ":") {
-						parts := strings.Split(artistURI, ":")
-						if len(parts) > 0 {
-							artistID := parts[len(parts)-1]
-							if artistID != "" {
-								artistIDs = append(artistIDs, artistID)
-							}
-						}
-					}
-				}
-			}
-
-			for _, artist := range trackArtists {
-				trackArtistNames = append(trackArtistNames, getString(artist, "name"))
-			}
-			artistsString := strings.Join(trackArtistNames, ", ")
-
-			trackDurationMs := getFloat64(getMap(trackData, "trackDuration"), "totalMilliseconds")
-			durationObj := extractDuration(trackDurationMs)
-			durationString := getString(durationObj, "formatted")
-
-			trackURI := getString(trackData, "uri")
-			trackID := getString(trackData, "id")
-			if trackID == "" {
-				if strings.Contains(trackURI, ":") {
-					parts := strings.Split(trackURI, ":")
-					trackID = parts[len(parts)-1]
-				}
-			}
-
-			albumData := getMap(trackData, "albumOfTrack")
-			albumName := ""
-			albumID := ""
-			var trackCover interface{}
-
-			if len(albumData) > 0 {
-				albumName = getString(albumData, "name")
-				albumURI := getString(albumData, "uri")
-				if strings.Contains(albumURI, ":") {
-					parts := strings.Split(albumURI, ":")
-					albumID = parts[len(parts)-1]
-				}
-				coverObj := extractCoverImage(getMap(albumData, "coverArt"))
-				if coverObj != nil {
-
-					trackCover = getString(coverObj, "small")
-					if trackCover == "" {
-						trackCover = getString(coverObj, "medium")
-					}
-					if trackCover == "" {
-						trackCover = getString(coverObj, "large")
-					}
-				}
-			}
-
-			trackInfo := map[string]interface{}{
-				"id":        trackID,
-				"cover":     trackCover,
-				"title":     getString(trackData, "name"),
-				"artist":    artistsString,
-				"artistIds": artistIDs,
-				"plays":     rank,
-				"status":    status,
-				"album":     albumName,
-				"albumId":   albumID,
-				"duration":  durationString,
-			}
-			tracks = append(tracks, trackInfo)
-		}
-	}
-
-	followersData, exists := playlistData["followers"]
-	var followersCount interface{}
-	if exists {
-		if followersMap, ok := followersData.(map[string]interface{}); ok {
-			followersCount = getFloat64(followersMap, "totalCount")
-		} else if count, ok := followersData.(float64); ok {
-			followersCount = count
-		} else if count, ok := followersData.(int); ok {
-			followersCount = float64(count)
-		} else {
-			followersCount = float64(0)
-		}
-	} else {
-		followersCount = float64(0)
-	}
-
-	playlistURI := getString(playlistData, "uri")
-	playlistID := ""
-	if strings.Contains(playlistURI, ":") {
-		parts := strings.Split(playlistURI, ":")
-		playlistID = parts[len(parts)-1]
-	}
-
-	totalCount := getFloat64(content, "totalCount")
-	count := len(tracks)
-	if totalCount > 0 {
-		count = int(totalCount)
-	}
-
-	filtered := map[string]interface{}{
-		"id":          playlistID,
-		"name":        getString(playlistData, "name"),
-		"description": getString(playlistData, "description"),
-		"owner":       ownerInfo,
-		"cover":       cover,
-		"count":       count,
-		"tracks":      tracks,
-		"followers":   followersCount,
-	}
-
-	return filtered
-}
-
-func extractRelease(release map[string]interface{}) map[string]interface{} {
-	if len(release) == 0 {
-		return nil
-	}
-
-	dateInfo := getMap(release, "date")
-	releaseDate := getString(dateInfo, "isoString")
-	if releaseDate == "" && len(dateInfo) > 0 {
-		yearStr := getString(dateInfo, "year")
-		monthStr := getString(dateInfo, "month")
-		dayStr := getString(dateInfo, "day")
-		if yearStr != "" {
-			if monthStr != "" && dayStr != "" {
-				month, _ := strconv.Atoi(monthStr)
-				day, _ := strconv.Atoi(dayStr)
-				releaseDate = fmt.Sprintf("%s-%02d-%02d", yearStr, month, day)
-			} else {
-				releaseDate = yearStr
-			}
-		}
-	} else if releaseDate != "" && strings.Contains(releaseDate, "T") {
-		parts := strings.Split(releaseDate, "T")
-		releaseDate = parts[0]
-	}
-
-	coverObj := extractCoverImage(getMap(release, "coverArt"))
-	var cover interface{}
-	if coverObj != nil {
-		cover = getString(coverObj, "medium")
-	}
-
-	releaseID := getString(release, "id")
-	if releaseID == "" {
-		releaseURI := getString(release, "uri")
-		if strings.Contains(releaseURI, ":") {
-			parts := strings.Split(releaseURI, ":")
-			releaseID = parts[len(parts)-1]
-		}
-	}
-
-	var year interface{}
-	if yearVal, exists := dateInfo["year"]; exists {
-		year = yearVal
-	}
-
-	return map[string]interface{}{
-		"id":    releaseID,
-		"name":  getString(release, "name"),
-		"cover": cover,
-		"date":  releaseDate,
-		"year":  year,
-	}
-}
-
-func extractDiscographyItems(itemsData map[string]interface{}) []map[string]interface{} {
-	items := []map[string]interface{}{}
-	dataItems := getSlice(itemsData, "items")
-	if dataItems != nil {
-		for _, item := range dataItems {
-			itemMap, ok := item.(map[string]interface{})
-			if !ok {
-				continue
-			}
-			releases := getMap(itemMap, "releases")
-			var release map[string]interface{}
-			if len(releases) > 0 {
-				releaseItems := getSlice(releases, "items")
-				if releaseItems != nil && len(releaseItems) > 0 {
-					if releaseMap, ok := releaseItems[0].(map[string]interface{}); ok {
-						release = releaseMap
-					}
-				}
-			} else {
-				release = getMap(itemMap, "album")
-			}
-
-			if len(release) > 0 {
-				extracted := extractRelease(release)
-				if extracted != nil {
-					items = append(items, extracted)
-				}
-			}
-		}
-	}
-	return items
-}
-
-func FilterArtist(data map[string]interface{}) map[string]interface{} {
-	dataMap := getMap(data, "data")
-	artistData := getMap(dataMap, "artistUnion")
-	if len(artistData) == 0 {
-		return make(map[string]interface{})
-	}
-
-	profileRaw := getMap(artistData, "profile")
-	profile := make(map[string]interface{})
-	if len(profileRaw) > 0 {
-		if biography, exists := profileRaw["biography"]; exists {
-			biographyMap, ok := biography.(map[string]interface{})
-			if ok {
-				biographyText := getString(biographyMap, "text")
-				if biographyText != "" {
-					profile["biography"] = html.UnescapeString(biographyText)
-				}
-			}
-		}
-		if _, exists := profileRaw["name"]; exists {
-			profile["name"] = getString(profileRaw, "name")
-		}
-		if _, exists := profileRaw["verified"]; exists {
-			profile["verified"] = getBool(profileRaw, "verified")
-		}
-	}
-
-	headerImageData := getMap(artistData, "headerImage")
-	var headerImage interface{}
-	if len(headerImageData) > 0 {
-		headerData := getMap(headerImageData, "data")
-		if len(headerData) > 0 {
-			sources := getSlice(headerData, "sources")
-			if sources != nil && len(sources) > 0 {
-				if firstSource, ok := sources[0].(map[string]interface{}); ok {
-					headerImage = getString(firstSource, "url")
-				}
-			}
-		}
-	}
-
-	statsRaw := getMap(artistData, "stats")
-	stats := make(map[string]interface{})
-	if len(statsRaw) > 0 {
-		if _, exists := statsRaw["followers"]; exists {
-			stats["followers"] = getFloat64(statsRaw, "followers")
-		}
-		if _, exists := statsRaw["monthlyListeners"]; exists {
-			stats["listeners"] = getFloat64(statsRaw, "monthlyListeners")
-		}
-		if _, exists := statsRaw["worldRank"]; exists {
-			stats["rank"] = getFloat64(statsRaw, "worldRank")
-		}
-	}
-
-	discography := getMap(artistData, "discography")
-	discographyResult := make(map[string]interface{})
-
-	allData := getMap(discography, "all")
-	if len(allData) > 0 {
-		discographyResult["all"] = extractDiscographyItems(allData)
-		if totalCount, exists := allData["totalCount"]; exists {
-			var total float64
-			if tc, ok := totalCount.(float64); ok {
-				total = tc
-			} else if tc, ok := totalCount.(int); ok {
-				total = float64(tc)
-			} else if tc, ok := totalCount.(int64); ok {
-				total = float64(tc)
-			}
-			discographyResult["total"] = total
-		}
-	}
-
-	visualsData := getMap(artistData, "visuals")
-	galleryData := getMap(visualsData, "gallery")
-	gallery := []interface{}{}
-	if len(galleryData) > 0 {
-		galleryItems := getSlice(galleryData, "items")
-		if galleryItems != nil {
-			for _, item := range galleryItems {
-				itemMap, ok := item.(map[string]interface{})
-				if !ok {
-					continue
-				}
-				sources := getSlice(itemMap, "sources")
-				if sources != nil && len(sources) > 0 {
-					if firstSource, ok := sources[0].(map[string]interface{}); ok {
-						galleryURL := getString(firstSource, "url")
-						if galleryURL != "" {
-							gallery = append(gallery, galleryURL)
-						}
-					}
-				}
-			}
-		}
-	}
-
-	avatarObj := extractCoverImage(getMap(visualsData, "avatarImage"))
-	var avatar interface{}
-	if avatarObj != nil {
-		if mediumURL, ok := avatarObj["medium"].(string); ok && mediumURL != "" {
-			avatar = mediumURL
-		} else if smallURL, ok := avatarObj["small"].(string); ok && smallURL != "" {
-			avatar = smallURL
-		}
-	}
-
-	artistURI := getString(artistData, "uri")
-	artistID := ""
-	if strings.Contains(artistURI, ":") {
-		parts := strings.Split(artistURI, ":")
-		artistID = parts[len(parts)-1]
-	}
-
-	filtered := map[string]interface{}{
-		"id":          artistID,
-		"name":        getString(profile, "name"),
-		"profile":     profile,
-		"avatar":      avatar,
-		"header":      headerImage,
-		"stats":       stats,
-		"gallery":     gallery,
-		"discography": discographyResult,
-	}
-
-	return filtered
-}
-
 func FilterSearch(data map[string]interface{}) map[string]interface{} {
 	dataMap := getMap(data, "data")
 	searchData := getMap(dataMap, "searchV2")
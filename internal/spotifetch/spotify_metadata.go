@@ -18,6 +18,10 @@ var (
 
 type SpotifyMetadataClient struct {
 	httpClient *http.Client
+
+	// ReleaseFilter, when set, decides which releases of an artist discography are
+	// expanded into tracks. Rejected releases are not fetched at all.
+	ReleaseFilter func(DiscographyAlbumMetadata) bool
 }
 
 func NewSpotifyMetadataClient() *SpotifyMetadataClient {
@@ -197,20 +201,22 @@ type apiTrackResponse struct {
 }
 
 type apiAlbumResponse struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	Artists     string `json:"artists"`
-	Cover       string `json:"cover"`
-	ReleaseDate string `json:"releaseDate"`
-	Count       int    `json:"count"`
-	Tracks      []struct {
-		ID        string   `json:"id"`
-		Name      string   `json:"name"`
-		Artists   string   `json:"artists"`
-		ArtistIds []string `json:"artistIds"`
-		Duration  string   `json:"duration"`
-		Plays     string   `json:"plays"`
-	} `json:"tracks"`
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`
+	Artists     string          `json:"artists"`
+	Cover       string          `json:"cover"`
+	ReleaseDate string          `json:"releaseDate"`
+	Count       int             `json:"count"`
+	Tracks      []apiAlbumTrack `json:"tracks"`
+}
+
+type apiAlbumTrack struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Artists   string   `json:"artists"`
+	ArtistIds []string `json:"artistIds"`
+	Duration  string   `json:"duration"`
+	Plays     string   `json:"plays"`
 }
 
 type apiPlaylistResponse struct {
@@ -221,21 +227,23 @@ type apiPlaylistResponse struct {
 		Name   string `json:"name"`
 		Avatar string `json:"avatar"`
 	} `json:"owner"`
-	Cover     string `json:"cover"`
-	Count     int    `json:"count"`
-	Followers int    `json:"followers"`
-	Tracks    []struct {
-		ID        string   `json:"id"`
-		Cover     string   `json:"cover"`
-		Title     string   `json:"title"`
-		Artist    string   `json:"artist"`
-		ArtistIds []string `json:"artistIds"`
-		Plays     string   `json:"plays"`
-		Status    string   `json:"status"`
-		Album     string   `json:"album"`
-		AlbumID   string   `json:"albumId"`
-		Duration  string   `json:"duration"`
-	} `json:"tracks"`
+	Cover     string             `json:"cover"`
+	Count     int                `json:"count"`
+	Followers int                `json:"followers"`
+	Tracks    []apiPlaylistTrack `json:"tracks"`
+}
+
+type apiPlaylistTrack struct {
+	ID        string   `json:"id"`
+	Cover     string   `json:"cover"`
+	Title     string   `json:"title"`
+	Artist    string   `json:"artist"`
+	ArtistIds []string `json:"artistIds"`
+	Plays     string   `json:"plays"`
+	Status    string   `json:"status"`
+	Album     string   `json:"album"`
+	AlbumID   string   `json:"albumId"`
+	Duration  string   `json:"duration"`
 }
 
 type apiArtistResponse struct {
@@ -255,15 +263,21 @@ type apiArtistResponse struct {
 	} `json:"stats"`
 	Gallery     []string `json:"gallery"`
 	Discography struct {
-		All []struct {
-			ID    string `json:"id"`
-			Name  string `json:"name"`
-			Cover string `json:"cover"`
-			Date  string `json:"date"`
-			Year  int    `json:"year"`
-		} `json:"all"`
-		Total int `json:"total"`
+		All   []apiArtistRelease `json:"all"`
+		Total int                `json:"total"`
 	} `json:"discography"`
+
+	// group is the discography tab from the URL: all, album, single or compilation
+	group string
+}
+
+type apiArtistRelease struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Cover string `json:"cover"`
+	Date  string `json:"date"`
+	Year  int    `json:"year"`
 }
 
 type apiSearchResponse struct {
@@ -378,429 +392,209 @@ func (c *SpotifyMetadataClient) processSpotifyData(ctx context.Context, raw inte
 }
 
 func (c *SpotifyMetadataClient) fetchTrack(ctx context.Context, trackID string) (*apiTrackResponse, error) {
-	client := NewSpotifyClient()
-	if err := client.Initialize(); err != nil {
+	client := DefaultClient()
+	if err := client.EnsureSession(); err != nil {
 		return nil, fmt.Errorf("failed to initialize spotify client: %w", err)
 	}
 
-	payload := map[string]interface{}{
-		"variables": map[string]interface{}{
-			"uri": fmt.Sprintf("spotify:track:%s", trackID),
-		},
-		"operationName": "getTrack",
-		"extensions": map[string]interface{}{
-			"persistedQuery": map[string]interface{}{
-				"version":    1,
-				"sha256Hash": "612585ae06ba435ad26369870deaae23b5c8800a256cd8a57e08eddc25a37294",
-			},
-		},
+	variables := map[string]interface{}{
+		"uri": fmt.Sprintf("spotify:track:%s", trackID),
 	}
 
-	data, err := client.Query(payload)
-	if err != nil {
+	var resp getTrackResponse
+	if err := client.QueryOperationInto("getTrack", variables, &resp); err != nil {
 		return nil, fmt.Errorf("failed to query track: %w", err)
 	}
-
-	var albumFetchData map[string]interface{}
-	if trackData, ok := data["data"].(map[string]interface{}); ok {
-		if trackUnion, ok := trackData["trackUnion"].(map[string]interface{}); ok {
-			if albumOfTrack, ok := trackUnion["albumOfTrack"].(map[string]interface{}); ok {
-				albumID := ""
-				if id, ok := albumOfTrack["id"].(string); ok && id != "" {
-					albumID = id
-				} else if uri, ok := albumOfTrack["uri"].(string); ok && uri != "" {
-					if strings.Contains(uri, ":") {
-						parts := strings.Split(uri, ":")
-						if len(parts) > 0 {
-							albumID = parts[len(parts)-1]
-						}
-					}
-				}
-
-				if albumID != "" {
-					albumPayload := map[string]interface{}{
-						"variables": map[string]interface{}{
-							"uri":    fmt.Sprintf("spotify:album:%s", albumID),
-							"locale": "",
-							"offset": 0,
-							"limit":  1,
-						},
-						"operationName": "getAlbum",
-						"extensions": map[string]interface{}{
-							"persistedQuery": map[string]interface{}{
-								"version":    1,
-								"sha256Hash": "b9bfabef66ed756e5e13f68a942deb60bd4125ec1f1be8cc42769dc0259b4b10",
-							},
-						},
-					}
-					albumFetchData, _ = client.Query(albumPayload)
-				}
-			}
-		}
-	}
-
-	filteredData := FilterTrack(data, albumFetchData)
-
-	jsonData, err := json.Marshal(filteredData)
+	track, err := resp.track()
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal filtered data: %w", err)
+		return nil, err
 	}
 
-	var result apiTrackResponse
-	if err := json.Unmarshal(jsonData, &result); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal to apiTrackResponse: %w", err)
+	// The album query only adds album artists and label, so its failure is not fatal
+	var album *gqlAlbum
+	if a := track.AlbumOfTrack; a != nil {
+		albumID := a.ID
+		if albumID == "" {
+			albumID = idFromURI(a.URI)
+		}
+		if albumID != "" {
+			albumVariables := map[string]interface{}{
+				"uri":    fmt.Sprintf("spotify:album:%s", albumID),
+				"locale": "",
+				"offset": 0,
+				"limit":  1,
+			}
+			var albumResp getAlbumResponse
+			if client.QueryOperationInto("getAlbum", albumVariables, &albumResp) == nil {
+				album, _ = albumResp.album()
+			}
+		}
 	}
 
-	return &result, nil
+	return track.toAPITrack(album), nil
 }
 
 func (c *SpotifyMetadataClient) fetchAlbum(ctx context.Context, albumID string) (*apiAlbumResponse, error) {
-	client := NewSpotifyClient()
-	if err := client.Initialize(); err != nil {
+	client := DefaultClient()
+	if err := client.EnsureSession(); err != nil {
 		return nil, fmt.Errorf("failed to initialize spotify client: %w", err)
 	}
 
-	allItems := []interface{}{}
 	offset := 0
 	limit := 1000
-	var totalCount interface{}
-	var data map[string]interface{}
+	totalCount := 0
+	var album *gqlAlbum
 
 	for {
-		payload := map[string]interface{}{
-			"variables": map[string]interface{}{
-				"uri":    fmt.Sprintf("spotify:album:%s", albumID),
-				"locale": "",
-				"offset": offset,
-				"limit":  limit,
-			},
-			"operationName": "getAlbum",
-			"extensions": map[string]interface{}{
-				"persistedQuery": map[string]interface{}{
-					"version":    1,
-					"sha256Hash": "b9bfabef66ed756e5e13f68a942deb60bd4125ec1f1be8cc42769dc0259b4b10",
-				},
-			},
+		variables := map[string]interface{}{
+			"uri":    fmt.Sprintf("spotify:album:%s", albumID),
+			"locale": "",
+			"offset": offset,
+			"limit":  limit,
 		}
 
-		response, err := client.Query(payload)
-		if err != nil {
+		var resp getAlbumResponse
+		if err := client.QueryOperationInto("getAlbum", variables, &resp); err != nil {
 			return nil, fmt.Errorf("failed to query album: %w", err)
 		}
-
-		if data == nil {
-			data = response
-		}
-
-		albumData := getMap(getMap(response, "data"), "albumUnion")
-		tracksData := getMap(albumData, "tracksV2")
-		items := getSlice(tracksData, "items")
-
-		if items == nil || len(items) == 0 {
-			break
+		page, err := resp.album()
+		if err != nil {
+			return nil, err
 		}
 
-		allItems = append(allItems, items...)
-
-		if totalCount == nil {
-			if tc, ok := tracksData["totalCount"].(float64); ok {
-				totalCount = int(tc)
-			} else {
+		items := page.TracksV2.Items
+		if album == nil {
+			album = page
+			totalCount = page.TracksV2.TotalCount
+			if totalCount == 0 {
 				totalCount = len(items)
 			}
+		} else {
+			album.TracksV2.Items = append(album.TracksV2.Items, items...)
 		}
 
-		tcInt := 0
-		if tc, ok := totalCount.(int); ok {
-			tcInt = tc
-		} else if tc, ok := totalCount.(float64); ok {
-			tcInt = int(tc)
-		}
-
-		if len(allItems) >= tcInt || len(items) < limit {
+		if len(items) == 0 || len(album.TracksV2.Items) >= totalCount || len(items) < limit {
 			break
 		}
 
 		offset += limit
 	}
 
-	if data != nil && len(allItems) > 0 {
-		dataMap := getMap(data, "data")
-		albumUnion := getMap(dataMap, "albumUnion")
-		tracksV2 := getMap(albumUnion, "tracksV2")
-		tracksV2["items"] = allItems
-		tracksV2["totalCount"] = len(allItems)
-	}
-
-	filteredData := FilterAlbum(data)
-
-	jsonData, err := json.Marshal(filteredData)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal filtered data: %w", err)
-	}
-
-	var result apiAlbumResponse
-	if err := json.Unmarshal(jsonData, &result); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal to apiAlbumResponse: %w", err)
-	}
-
-	return &result, nil
+	return album.toAPIAlbum(), nil
 }
 
 func (c *SpotifyMetadataClient) fetchPlaylist(ctx context.Context, playlistID string) (*apiPlaylistResponse, error) {
-	client := NewSpotifyClient()
-	if err := client.Initialize(); err != nil {
+	client := DefaultClient()
+	if err := client.EnsureSession(); err != nil {
 		return nil, fmt.Errorf("failed to initialize spotify client: %w", err)
 	}
 
-	allItems := []interface{}{}
 	offset := 0
 	limit := 1000
-	var totalCount interface{}
-	var data map[string]interface{}
+	totalCount := 0
+	var playlist *gqlPlaylist
 
 	for {
-		payload := map[string]interface{}{
-			"variables": map[string]interface{}{
-				"uri":                       fmt.Sprintf("spotify:playlist:%s", playlistID),
-				"offset":                    offset,
-				"limit":                     limit,
-				"enableWatchFeedEntrypoint": false,
-			},
-			"operationName": "fetchPlaylist",
-			"extensions": map[string]interface{}{
-				"persistedQuery": map[string]interface{}{
-					"version":    1,
-					"sha256Hash": "bb67e0af06e8d6f52b531f97468ee4acd44cd0f82b988e15c2ea47b1148efc77",
-				},
-			},
+		variables := map[string]interface{}{
+			"uri":                       fmt.Sprintf("spotify:playlist:%s", playlistID),
+			"offset":                    offset,
+			"limit":                     limit,
+			"enableWatchFeedEntrypoint": false,
 		}
 
-		response, err := client.Query(payload)
-		if err != nil {
+		var resp fetchPlaylistResponse
+		if err := client.QueryOperationInto("fetchPlaylist", variables, &resp); err != nil {
 			return nil, fmt.Errorf("failed to query playlist: %w", err)
 		}
-
-		if data == nil {
-			data = response
-		}
-
-		playlistData := getMap(getMap(response, "data"), "playlistV2")
-		content := getMap(playlistData, "content")
-		items := getSlice(content, "items")
-
-		if items == nil || len(items) == 0 {
-			break
+		page, err := resp.playlist()
+		if err != nil {
+			return nil, err
 		}
 
-		allItems = append(allItems, items...)
-
-		if totalCount == nil {
-			if tc, ok := content["totalCount"].(float64); ok {
-				totalCount = int(tc)
-			} else {
+		items := page.Content.Items
+		if playlist == nil {
+			playlist = page
+			totalCount = page.Content.TotalCount
+			if totalCount == 0 {
 				totalCount = len(items)
 			}
+		} else {
+			playlist.Content.Items = append(playlist.Content.Items, items...)
 		}
 
-		tcInt := 0
-		if tc, ok := totalCount.(int); ok {
-			tcInt = tc
-		} else if tc, ok := totalCount.(float64); ok {
-			tcInt = int(tc)
-		}
-
-		if len(allItems) >= tcInt || len(items) < limit {
+		if len(items) == 0 || len(playlist.Content.Items) >= totalCount || len(items) < limit {
 			break
 		}
 
 		offset += limit
 	}
 
-	if data != nil && len(allItems) > 0 {
-		dataMap := getMap(data, "data")
-		playlistV2 := getMap(dataMap, "playlistV2")
-		content := getMap(playlistV2, "content")
-		content["items"] = allItems
-		content["totalCount"] = len(allItems)
-	}
-
-	filteredData := FilterPlaylist(data)
-
-	jsonData, err := json.Marshal(filteredData)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal filtered data: %w", err)
-	}
-
-	var result apiPlaylistResponse
-	if err := json.Unmarshal(jsonData, &result); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal to apiPlaylistResponse: %w", err)
-	}
-
-	return &result, nil
+	return playlist.toAPIPlaylist(), nil
 }
 
 func (c *SpotifyMetadataClient) fetchArtistDiscography(ctx context.Context, parsed spotifyURI) (*apiArtistResponse, error) {
-	client := NewSpotifyClient()
-	if err := client.Initialize(); err != nil {
+	client := DefaultClient()
+	if err := client.EnsureSession(); err != nil {
 		return nil, fmt.Errorf("failed to initialize spotify client: %w", err)
 	}
 
-	overviewPayload := map[string]interface{}{
-		"variables": map[string]interface{}{
-			"uri":    fmt.Sprintf("spotify:artist:%s", parsed.ID),
-			"locale": "",
-		},
-		"operationName": "queryArtistOverview",
-		"extensions": map[string]interface{}{
-			"persistedQuery": map[string]interface{}{
-				"version":    1,
-				"sha256Hash": "446130b4a0aa6522a686aafccddb0ae849165b5e0436fd802f96e0243617b5d8",
-			},
-		},
+	overviewVariables := map[string]interface{}{
+		"uri":    fmt.Sprintf("spotify:artist:%s", parsed.ID),
+		"locale": "",
 	}
 
-	data, err := client.Query(overviewPayload)
-	if err != nil {
+	var overview artistOverviewResponse
+	if err := client.QueryOperationInto("queryArtistOverview", overviewVariables, &overview); err != nil {
 		return nil, fmt.Errorf("failed to query artist overview: %w", err)
 	}
+	artist, err := overview.artist()
+	if err != nil {
+		return nil, err
+	}
 
-	allDiscographyItems := []interface{}{}
+	// The overview only carries the first few releases; page in the rest. A failed
+	// page keeps what was fetched so far.
+	var releases []gqlDiscographyItem
 	offset := 0
 	limit := 50
-	var totalCount interface{}
+	totalCount := 0
 
 	for {
-		discographyPayload := map[string]interface{}{
-			"variables": map[string]interface{}{
-				"uri":    fmt.Sprintf("spotify:artist:%s", parsed.ID),
-				"offset": offset,
-				"limit":  limit,
-				"order":  "DATE_DESC",
-			},
-			"operationName": "queryArtistDiscographyAll",
-			"extensions": map[string]interface{}{
-				"persistedQuery": map[string]interface{}{
-					"version":    1,
-					"sha256Hash": "5e07d323febb57b4a56a42abbf781490e58764aa45feb6e3dc0591564fc56599",
-				},
-			},
+		discographyVariables := map[string]interface{}{
+			"uri":    fmt.Sprintf("spotify:artist:%s", parsed.ID),
+			"offset": offset,
+			"limit":  limit,
+			"order":  "DATE_DESC",
 		}
 
-		response, err := client.Query(discographyPayload)
-		if err != nil {
+		var resp artistDiscographyResponse
+		if err := client.QueryOperationInto("queryArtistDiscographyAll", discographyVariables, &resp); err != nil {
 			break
 		}
-
-		discographyData := getMap(getMap(getMap(response, "data"), "artistUnion"), "discography")
-		allData := getMap(discographyData, "all")
-		items := getSlice(allData, "items")
-
-		if items == nil || len(items) == 0 {
+		page, err := resp.releases()
+		if err != nil || len(page.Items) == 0 {
 			break
 		}
 
-		allDiscographyItems = append(allDiscographyItems, items...)
+		releases = append(releases, page.Items...)
 
-		if totalCount == nil {
-			if tc, ok := allData["totalCount"].(float64); ok {
-				totalCount = int(tc)
-			} else {
-				totalCount = len(items)
+		if totalCount == 0 {
+			totalCount = page.TotalCount
+			if totalCount == 0 {
+				totalCount = len(page.Items)
 			}
 		}
 
-		tcInt := 0
-		if tc, ok := totalCount.(int); ok {
-			tcInt = tc
-		} else if tc, ok := totalCount.(float64); ok {
-			tcInt = int(tc)
-		}
-
-		if len(allDiscographyItems) >= tcInt || len(items) < limit {
+		if len(releases) >= totalCount || len(page.Items) < limit {
 			break
 		}
 
 		offset += limit
 	}
 
-	albumsItems := []interface{}{}
-	compilationsItems := []interface{}{}
-	singlesItems := []interface{}{}
-
-	for _, item := range allDiscographyItems {
-		itemMap, ok := item.(map[string]interface{})
-		if !ok {
-			continue
-		}
-
-		releases := getMap(itemMap, "releases")
-		releaseItems := getSlice(releases, "items")
-		var release map[string]interface{}
-		if len(releaseItems) > 0 {
-			if r, ok := releaseItems[0].(map[string]interface{}); ok {
-				release = r
-			}
-		}
-
-		if release != nil {
-			releaseType := getString(release, "type")
-			switch releaseType {
-			case "ALBUM":
-				albumsItems = append(albumsItems, item)
-			case "COMPILATION":
-				compilationsItems = append(compilationsItems, item)
-			case "SINGLE":
-				singlesItems = append(singlesItems, item)
-			default:
-				singlesItems = append(singlesItems, item)
-			}
-		}
-	}
-
-	if len(allDiscographyItems) > 0 {
-		dataMap := getMap(data, "data")
-		artistUnion := getMap(dataMap, "artistUnion")
-		discographyMap := getMap(artistUnion, "discography")
-
-		if len(albumsItems) > 0 {
-			discographyMap["albums"] = map[string]interface{}{
-				"items":      albumsItems,
-				"totalCount": len(albumsItems),
-			}
-		}
-		if len(compilationsItems) > 0 {
-			discographyMap["compilations"] = map[string]interface{}{
-				"items":      compilationsItems,
-				"totalCount": len(compilationsItems),
-			}
-		}
-		if len(singlesItems) > 0 {
-			discographyMap["singles"] = map[string]interface{}{
-				"items":      singlesItems,
-				"totalCount": len(singlesItems),
-			}
-		}
-
-		discographyMap["all"] = map[string]interface{}{
-			"items":      allDiscographyItems,
-			"totalCount": len(allDiscographyItems),
-		}
-	}
-
-	filteredData := FilterArtist(data)
-
-	jsonData, err := json.Marshal(filteredData)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal filtered data: %w", err)
-	}
-
-	var result apiArtistResponse
-	if err := json.Unmarshal(jsonData, &result); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal to apiArtistResponse: %w", err)
-	}
-
-	return &result, nil
+	res := artist.toAPIArtist(releases)
+	res.group = parsed.DiscographyGroup
+	return res, nil
 }
 
 func (c *SpotifyMetadataClient) formatTrackData(raw *apiTrackResponse) TrackResponse {
@@ -834,7 +628,6 @@ func (c *SpotifyMetadataClient) formatTrackData(raw *apiTrackResponse) TrackResp
 		DiscNumber:  raw.Disc,
 		TotalDiscs:  raw.Discs,
 		ExternalURL: externalURL,
-		ISRC:        raw.ID,
 		Copyright:   raw.Copyright,
 		Publisher:   raw.Album.Label,
 		Plays:       raw.Plays,
@@ -892,7 +685,6 @@ func (c *SpotifyMetadataClient) formatAlbumData(raw *apiAlbumResponse) (*AlbumRe
 			DiscNumber:  1,
 			TotalDiscs:  0,
 			ExternalURL: fmt.Sprintf("https://open.spotify.com/track/%s", item.ID),
-			ISRC:        item.ID,
 			AlbumID:     raw.ID,
 			AlbumURL:    fmt.Sprintf("https://open.spotify.com/album/%s", raw.ID),
 			ArtistID:    artistID,
@@ -951,7 +743,6 @@ func (c *SpotifyMetadataClient) formatPlaylistData(raw *apiPlaylistResponse) Pla
 			DiscNumber:  1,
 			TotalDiscs:  0,
 			ExternalURL: fmt.Sprintf("https://open.spotify.com/track/%s", item.ID),
-			ISRC:        item.ID,
 			AlbumID:     item.AlbumID,
 			AlbumURL:    fmt.Sprintf("https://open.spotify.com/album/%s", item.AlbumID),
 			ArtistID:    artistID,
@@ -969,7 +760,10 @@ func (c *SpotifyMetadataClient) formatPlaylistData(raw *apiPlaylistResponse) Pla
 }
 
 func (c *SpotifyMetadataClient) formatArtistDiscographyData(ctx context.Context, raw *apiArtistResponse) (*ArtistDiscographyPayload, error) {
-	discType := "all"
+	discType := raw.group
+	if discType == "" {
+		discType = "all"
+	}
 
 	info := ArtistInfoMetadata{
 		Name:            raw.Name,
@@ -1004,16 +798,28 @@ func (c *SpotifyMetadataClient) formatArtistDiscographyData(ctx context.Context,
 
 		}
 
-		albumList = append(albumList, DiscographyAlbumMetadata{
+		albumType := alb.Type
+		if albumType == "" {
+			albumType = "album"
+		}
+		if discType != "all" && albumType != discType {
+			continue
+		}
+
+		release := DiscographyAlbumMetadata{
 			ID:          alb.ID,
 			Name:        alb.Name,
-			AlbumType:   "album",
+			AlbumType:   albumType,
 			ReleaseDate: alb.Date,
 			TotalTracks: 0,
 			Artists:     raw.Name,
 			Images:      alb.Cover,
 			ExternalURL: fmt.Sprintf("https://open.spotify.com/album/%s", alb.ID),
-		})
+		}
+		if c.ReleaseFilter != nil && !c.ReleaseFilter(release) {
+			continue
+		}
+		albumList = append(albumList, release)
 
 		albumData, err := c.fetchAlbum(ctx, alb.ID)
 		if err != nil {
@@ -1046,7 +852,7 @@ func (c *SpotifyMetadataClient) formatArtistDiscographyData(ctx context.Context,
 				Name:        tr.Name,
 				AlbumName:   albumData.Name,
 				AlbumArtist: albumData.Artists,
-				AlbumType:   "album",
+				AlbumType:   albumType,
 				DurationMS:  durationMS,
 				Images:      albumData.Cover,
 				ReleaseDate: albumData.ReleaseDate,
@@ -1054,7 +860,6 @@ func (c *SpotifyMetadataClient) formatArtistDiscographyData(ctx context.Context,
 				TotalTracks: albumData.Count,
 				DiscNumber:  1,
 				ExternalURL: fmt.Sprintf("https://open.spotify.com/track/%s", tr.ID),
-				ISRC:        tr.ID,
 				AlbumID:     alb.ID,
 				AlbumURL:    fmt.Sprintf("https://open.spotify.com/album/%s", alb.ID),
 				ArtistID:    artistID,
@@ -1182,32 +987,23 @@ func (c *SpotifyMetadataClient) Search(ctx context.Context, query string, limit
 		limit = 50
 	}
 
-	client := NewSpotifyClient()
-	if err := client.Initialize(); err != nil {
+	client := DefaultClient()
+	if err := client.EnsureSession(); err != nil {
 		return nil, fmt.Errorf("failed to initialize spotify client: %w", err)
 	}
 
-	payload := map[string]interface{}{
-		"variables": map[string]interface{}{
-			"searchTerm":                    query,
-			"offset":                        0,
-			"limit":                         limit,
-			"numberOfTopResults":            5,
-			"includeAudiobooks":             true,
-			"includeArtistHasConcertsField": false,
-			"includePreReleases":            true,
-			"includeAuthors":                false,
-		},
-		"operationName": "searchDesktop",
-		"extensions": map[string]interface{}{
-			"persistedQuery": map[string]interface{}{
-				"version":    1,
-				"sha256Hash": "fcad5a3e0d5af727fb76966f06971c19cfa2275e6ff7671196753e008611873c",
-			},
-		},
-	}
-
-	data, err := client.Query(payload)
+	variables := map[string]interface{}{
+		"searchTerm":                    query,
+		"offset":                        0,
+		"limit":                         limit,
+		"numberOfTopResults":            5,
+		"includeAudiobooks":             true,
+		"includeArtistHasConcertsField": false,
+		"includePreReleases":            true,
+		"includeAuthors":                false,
+	}
+
+	data, err := client.QueryOperation("searchDesktop", variables)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query search: %w", err)
 	}
@@ -1298,32 +1094,23 @@ func (c *SpotifyMetadataClient) SearchByType(ctx context.Context, query string,
 		offset = 0
 	}
 
-	client := NewSpotifyClient()
-	if err := client.Initialize(); err != nil {
+	client := DefaultClient()
+	if err := client.EnsureSession(); err != nil {
 		return nil, fmt.Errorf("failed to initialize spotify client: %w", err)
 	}
 
-	payload := map[string]interface{}{
-		"variables": map[string]interface{}{
-			"searchTerm":                    query,
-			"offset":                        offset,
-			"limit":                         limit,
-			"numberOfTopResults":            5,
-			"includeAudiobooks":             true,
-			"includeArtistHasConcertsField": false,
-			"includePreReleases":            true,
-			"includeAuthors":                false,
-		},
-		"operationName": "searchDesktop",
-		"extensions": map[string]interface{}{
-			"persistedQuery": map[string]interface{}{
-				"version":    1,
-				"sha256Hash": "fcad5a3e0d5af727fb76966f06971c19cfa2275e6ff7671196753e008611873c",
-			},
-		},
-	}
-
-	data, err := client.Query(payload)
+	variables := map[string]interface{}{
+		"searchTerm":                    query,
+		"offset":                        offset,
+		"limit":                         limit,
+		"numberOfTopResults":            5,
+		"includeAudiobooks":             true,
+		"includeArtistHasConcertsField": false,
+		"includePreReleases":            true,
+		"includeAuthors":                false,
+	}
+
+	data, err := client.QueryOperation("searchDesktop", variables)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query search: %w", err)
 	}
@@ -0,0 +1,238 @@
+// Package spotifyoauth links Spotify accounts to DAB users through the OAuth
+// authorization-code flow, so that a user's own library can be read.
+package spotifyoauth
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"dbh-go-srv/internal/database"
+	"github.com/zmb3/spotify/v2"
+	spotifyauth "github.com/zmb3/spotify/v2/auth"
+	"golang.org/x/oauth2"
+)
+
+// stateTTL bounds how long a user has to finish the consent screen.
+const stateTTL = 10 * time.Minute
+
+// Scopes are requested on every login: private and collaborative playlists,
+// saved tracks and albums, followed artists, and the account's country for
+// top-track lookups.
+var Scopes = []string{
+	spotifyauth.ScopePlaylistReadPrivate,
+	spotifyauth.ScopePlaylistReadCollaborative,
+	spotifyauth.ScopeUserLibraryRead,
+	spotifyauth.ScopeUserFollowRead,
+	spotifyauth.ScopeUserReadPrivate,
+}
+
+var (
+	// ErrNotConnected is returned when the user has not linked a Spotify account.
+	ErrNotConnected = errors.New("no Spotify account linked; connect one via /api/v1/spotify/login")
+	// ErrInvalidState is returned for callbacks with an unknown or expired state.
+	ErrInvalidState = errors.New("unknown or expired login state")
+)
+
+// Accounts runs the OAuth flow and hands out Spotify clients that act as a
+// linked user. Tokens live in the spotify_user_tokens table and are refreshed
+// transparently; refreshed tokens are written back.
+type Accounts struct {
+	auth      *spotifyauth.Authenticator
+	db        *sql.DB
+	debugMode bool
+
+	mu     sync.Mutex
+	states map[string]pendingLogin
+}
+
+type pendingLogin struct {
+	userID  string
+	expires time.Time
+}
+
+func New(db *sql.DB, clientID, clientSecret, redirectURL string, debugMode bool) *Accounts {
+	return &Accounts{
+		auth: spotifyauth.New(
+			spotifyauth.WithClientID(clientID),
+			spotifyauth.WithClientSecret(clientSecret),
+			spotifyauth.WithRedirectURL(redirectURL),
+			spotifyauth.WithScopes(Scopes...),
+		),
+		db:        db,
+		debugMode: debugMode,
+		states:    make(map[string]pendingLogin),
+	}
+}
+
+// AuthURL starts a login for userID and returns the Spotify consent URL.
+func (a *Accounts) AuthURL(userID string) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	state := hex.EncodeToString(buf)
+
+	a.mu.Lock()
+	now := time.Now()
+	for s, p := range a.states {
+		if now.After(p.expires) {
+			delete(a.states, s)
+		}
+	}
+	a.states[state] = pendingLogin{userID: userID, expires: now.Add(stateTTL)}
+	a.mu.Unlock()
+
+	return a.auth.AuthURL(state), nil
+}
+
+// Complete handles the redirect back from Spotify: it checks the state,
+// exchanges the code and stores the token. It returns the DAB user the account
+// was linked to.
+func (a *Accounts) Complete(ctx context.Context, r *http.Request) (string, error) {
+	q := r.URL.Query()
+	if reason := q.Get("error"); reason != "" {
+		a.takeState(q.Get("state"))
+		return "", fmt.Errorf("spotify authorization denied: %s", reason)
+	}
+
+	state := q.Get("state")
+	userID, ok := a.takeState(state)
+	if !ok {
+		return "", ErrInvalidState
+	}
+
+	tok, err := a.auth.Token(ctx, state, r)
+	if err != nil {
+		return "", fmt.Errorf("exchange code: %w", err)
+	}
+
+	record := database.SpotifyToken{
+		UserID:       userID,
+		AccessToken:  tok.AccessToken,
+		RefreshToken: tok.RefreshToken,
+		TokenType:    tok.TokenType,
+		Expiry:       tok.Expiry,
+	}
+	if scope, ok := tok.Extra("scope").(string); ok {
+		record.Scope = scope
+	}
+
+	// The profile is informational; a failure here should not undo the link
+	me, err := spotify.New(a.auth.Client(ctx, tok)).CurrentUser(ctx)
+	if err == nil {
+		record.SpotifyUserID = me.ID
+		record.DisplayName = me.DisplayName
+	} else if a.debugMode {
+		log.Printf("[SPOTIFY-OAUTH] profile lookup failed for %s: %v", userID, err)
+	}
+
+	if err := database.SaveSpotifyToken(a.db, record); err != nil {
+		return "", fmt.Errorf("store token: %w", err)
+	}
+
+	if a.debugMode {
+		log.Printf("[SPOTIFY-OAUTH] linked user=%s spotify=%s", userID, record.SpotifyUserID)
+	}
+	return userID, nil
+}
+
+func (a *Accounts) takeState(state string) (string, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	p, ok := a.states[state]
+	delete(a.states, state)
+	if !ok || time.Now().After(p.expires) {
+		return "", false
+	}
+	return p.userID, true
+}
+
+// Linked returns the stored account of userID, or ErrNotConnected. A link whose
+// tokens no longer decrypt (the encryption key changed) counts as not connected,
+// so the user is asked to link the account again.
+func (a *Accounts) Linked(userID string) (*database.SpotifyToken, error) {
+	t, err := database.GetSpotifyToken(a.db, userID)
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, database.ErrUnreadableToken) {
+		return nil, ErrNotConnected
+	}
+	return t, err
+}
+
+// Disconnect forgets the linked account of userID. The grant itself can only be
+// revoked by the user on Spotify's account page.
+func (a *Accounts) Disconnect(userID string) error {
+	return database.DeleteSpotifyToken(a.db, userID)
+}
+
+// Client returns a Web API client acting as userID, or ErrNotConnected.
+func (a *Accounts) Client(ctx context.Context, userID string) (*spotify.Client, error) {
+	stored, err := a.Linked(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	src := &storedTokenSource{
+		ctx:    ctx,
+		accts:  a,
+		stored: *stored,
+		tok: &oauth2.Token{
+			AccessToken:  stored.AccessToken,
+			RefreshToken: stored.RefreshToken,
+			TokenType:    stored.TokenType,
+			Expiry:       stored.Expiry,
+		},
+	}
+	return spotify.New(oauth2.NewClient(ctx, src)), nil
+}
+
+// storedTokenSource refreshes an expired token and writes the new one back, so
+// later requests and other processes start from a valid access token.
+type storedTokenSource struct {
+	ctx    context.Context
+	accts  *Accounts
+	stored database.SpotifyToken
+
+	mu  sync.Mutex
+	tok *oauth2.Token
+}
+
+func (s *storedTokenSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tok.Valid() {
+		return s.tok, nil
+	}
+
+	fresh, err := s.accts.auth.RefreshToken(s.ctx, s.tok)
+	if err != nil {
+		// A revoked grant cannot recover on its own; make the user link again
+		if strings.Contains(err.Error(), "invalid_grant") {
+			_ = s.accts.Disconnect(s.stored.UserID)
+			return nil, fmt.Errorf("%w (the previous authorization was revoked)", ErrNotConnected)
+		}
+		return nil, fmt.Errorf("refresh spotify token: %w", err)
+	}
+
+	record := s.stored
+	record.AccessToken = fresh.AccessToken
+	record.RefreshToken = fresh.RefreshToken
+	record.TokenType = fresh.TokenType
+	record.Expiry = fresh.Expiry
+	if err := database.SaveSpotifyToken(s.accts.db, record); err != nil {
+		log.Printf("[SPOTIFY-OAUTH] saving refreshed token for %s failed: %v", s.stored.UserID, err)
+	}
+
+	s.tok = fresh
+	return fresh, nil
+}
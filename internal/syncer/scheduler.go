@@ -0,0 +1,63 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"dbh-go-srv/internal/database"
+)
+
+// DefaultPollInterval is how often the scheduler looks for due syncs.
+const DefaultPollInterval = 10 * time.Minute
+
+// Schedule runs due syncs every poll interval until ctx is cancelled. Syncs run one
+// at a time so scheduled work never competes with itself for the DAB rate limit.
+func (s *Syncer) Schedule(ctx context.Context, poll time.Duration) {
+	if poll <= 0 {
+		poll = DefaultPollInterval
+	}
+
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+
+	for {
+		s.runDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Syncer) runDue(ctx context.Context) {
+	syncs, err := database.ListSyncs(s.DB, "")
+	if err != nil {
+		log.Printf("[SYNC] list syncs failed: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for _, st := range syncs {
+		if ctx.Err() != nil {
+			return
+		}
+		if !st.Due(now) {
+			continue
+		}
+
+		res, err := s.Run(ctx, st.ID)
+		switch {
+		case errors.Is(err, ErrSyncRunning):
+			continue
+		case err != nil:
+			log.Printf("[SYNC] %s (%s): %v", st.ID, st.SourceURL, err)
+		default:
+			log.Printf("[SYNC] %s (%s): added=%d removed=%d unmatched=%d failed=%d",
+				st.ID, st.SourceURL, res.Added, res.Removed, len(res.Unmatched), len(res.Failures))
+		}
+	}
+}
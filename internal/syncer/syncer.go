@@ -0,0 +1,267 @@
+package syncer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"dbh-go-srv/internal/dab"
+	"dbh-go-srv/internal/database"
+	"dbh-go-srv/internal/matcher"
+	"dbh-go-srv/internal/models"
+)
+
+var ErrSyncRunning = errors.New("sync already running")
+
+// Extractor pulls the current track list of a source playlist on behalf of userID.
+type Extractor func(ctx context.Context, userID, sourceType, url string) ([]models.Track, string, error)
+
+// Result summarizes one sync run.
+type Result struct {
+	SyncID     string                 `json:"sync_id"`
+	SourceName string                 `json:"source_name"`
+	LibraryID  string                 `json:"library_id"`
+	Total      int                    `json:"total"`
+	Added      int                    `json:"added"`
+	Removed    int                    `json:"removed"`
+	Unmatched  []models.Track         `json:"unmatched"`
+	Failures   []dab.LibraryAddResult `json:"failures"`
+
+	// PendingRemovals counts dropped tracks DAB did not remove; the next run retries them.
+	PendingRemovals int `json:"pending_removals"`
+}
+
+// Syncer applies source playlist changes to linked DAB libraries.
+type Syncer struct {
+	DB      *sql.DB
+	Extract Extractor
+	// Client returns the DAB client for a sync's token; nil uses dab.GetClient.
+	Client    func(token string) *dab.Client
+	DebugMode bool
+
+	running sync.Map // sync ID -> struct{}
+}
+
+// TrackKey identifies a source track across runs: its platform ID when it has one,
+// otherwise its normalized artist and title.
+func TrackKey(t models.Track) string {
+	if t.SourceID != "" {
+		return t.Type + ":" + t.SourceID
+	}
+	return "meta:" + strings.ToLower(strings.TrimSpace(t.Artist)) + "|" + strings.ToLower(strings.TrimSpace(t.Title))
+}
+
+// Diff splits the current track list into tracks that were not seen on the
+// previous run and keys of previously seen tracks that are gone.
+func Diff(previous map[string]database.SyncTrack, current []models.Track) (added []models.Track, removed []string) {
+	currentKeys := make(map[string]bool, len(current))
+	for _, t := range current {
+		key := TrackKey(t)
+		if currentKeys[key] {
+			continue
+		}
+		currentKeys[key] = true
+		if _, ok := previous[key]; !ok {
+			added = append(added, t)
+		}
+	}
+
+	for key := range previous {
+		if !currentKeys[key] {
+			removed = append(removed, key)
+		}
+	}
+	return added, removed
+}
+
+// Run performs one sync: extract, diff against the remembered track set, match
+// only the new tracks, and apply additions and removals to the linked library.
+func (s *Syncer) Run(ctx context.Context, syncID string) (*Result, error) {
+	if _, busy := s.running.LoadOrStore(syncID, struct{}{}); busy {
+		return nil, ErrSyncRunning
+	}
+	defer s.running.Delete(syncID)
+
+	st, err := database.GetSync(s.DB, syncID)
+	if err != nil {
+		return nil, fmt.Errorf("load sync: %w", err)
+	}
+
+	res, err := s.run(ctx, st)
+	if recErr := database.RecordSyncRun(s.DB, st.ID, res.SourceName, res.LibraryID, time.Now(), err); recErr != nil {
+		log.Printf("[SYNC] %s: record run failed: %v", st.ID, recErr)
+	}
+	return res, err
+}
+
+func (s *Syncer) run(ctx context.Context, st *database.Sync) (*Result, error) {
+	res := &Result{SyncID: st.ID, LibraryID: st.LibraryID}
+	if st.DabToken == "" {
+		return res, errors.New("the stored DAB token cannot be read; register the sync again")
+	}
+	var client *dab.Client
+	if s.Client != nil {
+		client = s.Client(st.DabToken)
+	} else {
+		client = dab.GetClient(st.DabToken, s.DebugMode)
+	}
+
+	tracks, sourceName, err := s.Extract(ctx, st.UserID, st.SourceType, st.SourceURL)
+	if err != nil {
+		return res, fmt.Errorf("extract: %w", err)
+	}
+	res.SourceName = sourceName
+	res.Total = len(tracks)
+
+	previous, err := database.GetSyncTracks(s.DB, st.ID)
+	if err != nil {
+		return res, fmt.Errorf("load previous tracks: %w", err)
+	}
+
+	added, removed := Diff(previous, tracks)
+	if s.DebugMode {
+		log.Printf("[SYNC] %s: total=%d new=%d removed=%d", st.ID, len(tracks), len(added), len(removed))
+	}
+
+	if res.LibraryID == "" {
+		name := st.LibraryName
+		if name == "" {
+			name = sourceName
+		}
+		lib, err := client.CreateLibrary(name, "Synced from "+st.SourceURL, false)
+		if err != nil {
+			return res, err
+		}
+		res.LibraryID = string(lib.ID)
+	}
+
+	// Start from the remembered state, then apply removals and layer in the new tracks
+	next := make(map[string]database.SyncTrack, len(previous)+len(added))
+	for key, t := range previous {
+		// A track awaiting removal that is back in the source stays
+		t.PendingRemoval = false
+		next[key] = t
+	}
+
+	// Removals: only drop a DAB track if no remaining source track still maps to it
+	gone := make(map[string]bool, len(removed))
+	for _, key := range removed {
+		gone[key] = true
+	}
+	stillUsed := make(map[string]bool)
+	for key, t := range next {
+		if t.InLibrary && !gone[key] {
+			stillUsed[t.DabTrackID] = true
+		}
+	}
+	removals := make(map[string]error) // DAB track ID -> outcome, when several keys share one
+	for _, key := range removed {
+		old := previous[key]
+		id, err := strconv.Atoi(old.DabTrackID)
+		if !old.InLibrary || stillUsed[old.DabTrackID] || err != nil {
+			delete(next, key)
+			continue
+		}
+		err, tried := removals[old.DabTrackID]
+		if !tried {
+			if err = client.RemoveTrack(res.LibraryID, id); err != nil {
+				log.Printf("[SYNC] %s: remove %s failed: %v", st.ID, old.DabTrackID, err)
+			} else {
+				res.Removed++
+			}
+			removals[old.DabTrackID] = err
+		}
+		if err != nil {
+			// Keep the track in the state so the next run retries the removal
+			old.PendingRemoval = true
+			next[key] = old
+			res.PendingRemovals++
+			continue
+		}
+		delete(next, key)
+	}
+
+	var toAdd []dab.DabTrack
+	keyByDabID := make(map[int][]string)
+
+	// Retry matched tracks from earlier runs that DAB refused to add
+	for key, t := range next {
+		if t.InLibrary || t.PendingRemoval || t.MatchStatus != "FOUND" || stillUsed[t.DabTrackID] {
+			continue
+		}
+		id, err := strconv.Atoi(t.DabTrackID)
+		if err != nil {
+			continue
+		}
+		if len(keyByDabID[id]) == 0 {
+			toAdd = append(toAdd, dab.DabTrack{ID: id, Title: t.Title, Artist: t.Artist})
+		}
+		keyByDabID[id] = append(keyByDabID[id], key)
+	}
+
+	// Additions: match only what is new
+	for _, t := range added {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+
+		match := matcher.MatchTrack(ctx, s.DB, client, t, st.MatchingMode, s.DebugMode)
+		key := TrackKey(t)
+		entry := database.SyncTrack{
+			Key:         key,
+			SourceID:    t.SourceID,
+			Title:       t.Title,
+			Artist:      t.Artist,
+			MatchStatus: match.MatchStatus,
+		}
+		if match.DabTrackID != nil {
+			entry.DabTrackID = *match.DabTrackID
+		}
+		next[key] = entry
+
+		dt, ok := dab.TrackFromResult(*match)
+		if !ok {
+			res.Unmatched = append(res.Unmatched, t)
+			continue
+		}
+		if stillUsed[entry.DabTrackID] {
+			// Already in the library through another source track
+			entry.InLibrary = true
+			next[key] = entry
+			continue
+		}
+		if len(keyByDabID[dt.ID]) == 0 {
+			toAdd = append(toAdd, dt)
+		}
+		keyByDabID[dt.ID] = append(keyByDabID[dt.ID], key)
+	}
+
+	client.AddTracks(res.LibraryID, toAdd, func(r dab.LibraryAddResult) {
+		if r.Error != "" {
+			res.Failures = append(res.Failures, r)
+			return
+		}
+		res.Added++
+		for _, key := range keyByDabID[r.TrackID] {
+			entry := next[key]
+			entry.InLibrary = true
+			next[key] = entry
+		}
+	})
+
+	state := make([]database.SyncTrack, 0, len(next))
+	for _, t := range next {
+		state = append(state, t)
+	}
+	if err := database.ReplaceSyncTracks(s.DB, st.ID, state); err != nil {
+		return res, fmt.Errorf("save track state: %w", err)
+	}
+
+	return res, nil
+}
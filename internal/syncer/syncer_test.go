@@ -0,0 +1,194 @@
+package syncer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"dbh-go-srv/internal/dab"
+	"dbh-go-srv/internal/database"
+	"dbh-go-srv/internal/models"
+)
+
+func TestTrackKey(t *testing.T) {
+	tests := []struct {
+		track models.Track
+		want  string
+	}{
+		{models.Track{SourceID: "4uLU6hMCjMI75M1A2tKUQC", Type: "spotify", Title: "Never Gonna Give You Up"}, "spotify:4uLU6hMCjMI75M1A2tKUQC"},
+		// The same ID on another platform is another track
+		{models.Track{SourceID: "4uLU6hMCjMI75M1A2tKUQC", Type: "deezer"}, "deezer:4uLU6hMCjMI75M1A2tKUQC"},
+		{models.Track{Title: " Never Gonna Give You Up ", Artist: "Rick ASTLEY"}, "meta:rick astley|never gonna give you up"},
+	}
+	for _, tt := range tests {
+		if got := TrackKey(tt.track); got != tt.want {
+			t.Errorf("TrackKey(%+v) = %q, want %q", tt.track, got, tt.want)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := models.Track{SourceID: "a", Type: "spotify", Title: "A"}
+	b := models.Track{SourceID: "b", Type: "spotify", Title: "B"}
+	c := models.Track{SourceID: "c", Type: "spotify", Title: "C"}
+	d := models.Track{Title: "D", Artist: "Band"}
+	seen := func(tracks ...models.Track) map[string]database.SyncTrack {
+		out := make(map[string]database.SyncTrack)
+		for _, t := range tracks {
+			out[TrackKey(t)] = database.SyncTrack{Key: TrackKey(t)}
+		}
+		return out
+	}
+
+	tests := []struct {
+		name     string
+		previous map[string]database.SyncTrack
+		current  []models.Track
+		added    []string // titles
+		removed  []string // keys
+	}{
+		{"first run", nil, []models.Track{a, b}, []string{"A", "B"}, nil},
+		{"unchanged", seen(a, b), []models.Track{a, b}, nil, nil},
+		{"added", seen(a), []models.Track{a, b, d}, []string{"B", "D"}, nil},
+		{"removed", seen(a, b, d), []models.Track{b}, nil, []string{"meta:band|d", "spotify:a"}},
+		// Order is not a change
+		{"reordered", seen(a, b, c), []models.Track{c, a, b}, nil, nil},
+		// A track listed twice is added once, and is not gone while one copy is left
+		{"duplicates", seen(b), []models.Track{a, b, a, b}, []string{"A"}, nil},
+		{"replaced", seen(a, b), []models.Track{c, b}, []string{"C"}, []string{"spotify:a"}},
+	}
+	for _, tt := range tests {
+		added, removed := Diff(tt.previous, tt.current)
+		var titles []string
+		for _, t := range added {
+			titles = append(titles, t.Title)
+		}
+		slices.Sort(removed)
+		if !slices.Equal(titles, tt.added) || !slices.Equal(removed, tt.removed) {
+			t.Errorf("%s: added %v, removed %v; want %v, %v", tt.name, titles, removed, tt.added, tt.removed)
+		}
+	}
+}
+
+// fakeLibrary is a stand-in for the DAB library API whose track removals fail
+// while failing is set.
+type fakeLibrary struct {
+	mu      sync.Mutex
+	failing bool
+	removed []string
+}
+
+func newTestSyncer(t *testing.T, extract Extractor) (*fakeLibrary, *Syncer) {
+	t.Helper()
+	f := &fakeLibrary{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("DELETE /libraries/{lib}/tracks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if f.failing {
+			http.Error(w, "try again later", http.StatusServiceUnavailable)
+			return
+		}
+		f.removed = append(f.removed, r.PathValue("lib")+"/"+r.PathValue("id"))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "registry.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := database.InitDatabase(db); err != nil {
+		t.Fatal(err)
+	}
+
+	return f, &Syncer{
+		DB:      db,
+		Extract: extract,
+		Client: func(token string) *dab.Client {
+			return &dab.Client{HTTPClient: srv.Client(), Limiter: rate.NewLimiter(rate.Inf, 1), APIBase: srv.URL, Token: token}
+		},
+	}
+}
+
+func TestRunKeepsFailedRemovals(t *testing.T) {
+	a := models.Track{SourceID: "a", Type: "spotify", Title: "A", Artist: "Band"}
+	var extractErr error
+	extract := func(ctx context.Context, userID, sourceType, url string) ([]models.Track, string, error) {
+		return []models.Track{a}, "Mix", extractErr
+	}
+	f, s := newTestSyncer(t, extract)
+
+	id, err := database.UpsertSync(s.DB, database.Sync{ID: "sync-1", UserID: "u1", DabToken: "tok", SourceType: "spotify", SourceURL: "https://open.spotify.com/playlist/x", IntervalHours: 24})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.RecordSyncRun(s.DB, id, "Mix", "lib-1", time.Now(), nil); err != nil {
+		t.Fatal(err)
+	}
+	// A was matched to DAB track 1 and B, now dropped from the source, to 2
+	err = database.ReplaceSyncTracks(s.DB, id, []database.SyncTrack{
+		{Key: "spotify:a", SourceID: "a", Title: "A", DabTrackID: "1", MatchStatus: "FOUND", InLibrary: true},
+		{Key: "spotify:b", SourceID: "b", Title: "B", DabTrackID: "2", MatchStatus: "FOUND", InLibrary: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pendingB := func() bool {
+		t.Helper()
+		state, err := database.GetSyncTracks(s.DB, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, ok := state["spotify:b"]
+		if !ok {
+			return false
+		}
+		if !b.PendingRemoval || b.DabTrackID != "2" {
+			t.Errorf("kept track = %+v, want a pending removal of DAB track 2", b)
+		}
+		return true
+	}
+
+	// DAB refuses the removal, so B stays in the state
+	f.failing = true
+	res, err := s.Run(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Removed != 0 || res.PendingRemovals != 1 || !pendingB() {
+		t.Fatalf("first run: removed %d, pending %d; want the removal kept for later", res.Removed, res.PendingRemovals)
+	}
+
+	// A run that fails before applying changes leaves the state alone
+	extractErr = errors.New("source is down")
+	if _, err := s.Run(context.Background(), id); err == nil {
+		t.Fatal("run with a failing source succeeded")
+	}
+	if !pendingB() {
+		t.Fatal("failed run dropped the pending removal")
+	}
+
+	// The next good run retries it
+	extractErr, f.failing = nil, false
+	res, err = s.Run(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Removed != 1 || res.PendingRemovals != 0 || pendingB() {
+		t.Errorf("retry: removed %d, pending %d", res.Removed, res.PendingRemovals)
+	}
+	if want := []string{"lib-1/2"}; !slices.Equal(f.removed, want) {
+		t.Errorf("DAB removals = %v, want %v", f.removed, want)
+	}
+}
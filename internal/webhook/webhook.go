@@ -0,0 +1,165 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"dbh-go-srv/internal/models"
+	"dbh-go-srv/internal/netguard"
+)
+
+const (
+	EventCompleted = "job.completed"
+	EventFailed    = "job.failed"
+
+	SignatureHeader = "X-DBH-Signature"
+	TimestampHeader = "X-DBH-Timestamp"
+	EventHeader     = "X-DBH-Event"
+)
+
+var (
+	ErrInvalidCallbackURL = errors.New("callback_url must be an absolute http(s) URL")
+	ErrPrivateCallbackURL = errors.New("callback_url must not point to a loopback, private or link-local address")
+)
+
+// Payload is the JSON body POSTed to a job's callback URL.
+type Payload struct {
+	Event      string               `json:"event"`
+	JobID      string               `json:"job_id"`
+	UserID     string               `json:"user_id"`
+	SourceType string               `json:"source_type"`
+	SourceName string               `json:"source_name,omitempty"`
+	Status     string               `json:"status"`
+	Error      string               `json:"error,omitempty"`
+	Timestamp  string               `json:"timestamp"`
+	Summary    any                  `json:"summary,omitempty"`
+	Tracks     []models.MatchResult `json:"tracks"`
+}
+
+// Sender delivers signed callbacks with retries and exponential backoff.
+type Sender struct {
+	HTTPClient  *http.Client
+	Secret      []byte
+	MaxAttempts int
+	BaseDelay   time.Duration
+	Debug       bool
+}
+
+func NewSender(secret string, debugMode bool) *Sender {
+	return &Sender{
+		HTTPClient:  netguard.NewClient(15 * time.Second),
+		Secret:      []byte(secret),
+		MaxAttempts: 5,
+		BaseDelay:   2 * time.Second,
+		Debug:       debugMode,
+	}
+}
+
+// ValidateURL checks that a callback URL is usable before the job starts: it
+// must be http(s) and its host must resolve to public addresses only. The
+// Sender's client checks the address again when it connects.
+func ValidateURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return ErrInvalidCallbackURL
+	}
+	if err := netguard.CheckHost(ctx, u.Hostname()); err != nil {
+		if errors.Is(err, netguard.ErrBlockedAddress) {
+			return ErrPrivateCallbackURL
+		}
+		return fmt.Errorf("callback_url host cannot be resolved: %s", u.Hostname())
+	}
+	return nil
+}
+
+// Sign returns the hex HMAC-SHA256 of "<timestamp>.<body>". Receivers recompute it
+// with the shared secret and compare against the X-DBH-Signature header.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Deliver POSTs the payload, retrying on network errors, 429 and 5xx responses.
+// Backoff doubles from BaseDelay between attempts.
+func (s *Sender) Deliver(ctx context.Context, callbackURL string, p Payload) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("marshal webhook payload: %w", err)
+	}
+
+	delay := s.BaseDelay
+	var lastErr error
+
+	for attempt := 1; attempt <= s.MaxAttempts; attempt++ {
+		retry, err := s.post(ctx, callbackURL, p.Event, body)
+		if err == nil {
+			if s.Debug {
+				log.Printf("[WEBHOOK] job=%s delivered to %s (attempt %d)", p.JobID, callbackURL, attempt)
+			}
+			return nil
+		}
+		lastErr = err
+
+		if !retry || attempt == s.MaxAttempts {
+			break
+		}
+
+		if s.Debug {
+			log.Printf("[WEBHOOK] job=%s attempt %d failed: %v — retrying in %s", p.JobID, attempt, err, delay)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+
+	return fmt.Errorf("webhook delivery to %s failed: %w", callbackURL, lastErr)
+}
+
+func (s *Sender) post(ctx context.Context, callbackURL, event string, body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "DBH-GO-SRV-Webhook/1.0")
+	req.Header.Set(EventHeader, event)
+	req.Header.Set(TimestampHeader, ts)
+	req.Header.Set(SignatureHeader, "sha256="+Sign(s.Secret, ts, body))
+
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("callback status %s", resp.Status)
+	default:
+		return false, fmt.Errorf("callback status %s", resp.Status)
+	}
+}
@@ -2,15 +2,19 @@ package main
 
 import (
     "context"
+	"crypto/rand"
 	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
 	"runtime/debug"
+	"strconv"
 	"strings"
 	"time"
 
@@ -24,6 +28,9 @@ import (
 	"dbh-go-srv/internal/matcher"
 	"dbh-go-srv/internal/models"
 	"dbh-go-srv/internal/parser"
+	"dbh-go-srv/internal/spotifyoauth"
+	"dbh-go-srv/internal/syncer"
+	"dbh-go-srv/internal/webhook"
 )
 
 /* =========================
@@ -47,9 +54,118 @@ func RecoveryMiddleware(next http.HandlerFunc) http.HandlerFunc {
    ========================= */
 
 type ConversionRequest struct {
-	URL          string `json:"url"`
-	Type         string `json:"type"`
-	MatchingMode string `json:"matching_mode"`
+	URL          string            `json:"url"`
+	Type         string            `json:"type"`
+	MatchingMode string            `json:"matching_mode"`
+	Target       *ConversionTarget `json:"target,omitempty"`
+	CallbackURL  string            `json:"callback_url,omitempty"`
+	// Discography filters Spotify artist and discography URLs
+	Discography parser.DiscographyFilter `json:"discography"`
+}
+
+// ConversionTarget asks for the matched tracks to be pushed into a new DAB library.
+type ConversionTarget struct {
+	LibraryName string `json:"library_name"`
+}
+
+/* =========================
+   Jobs
+   ========================= */
+
+func newJobID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func saveJob(db *sql.DB, job database.Job) {
+	if err := database.SaveJob(db, job); err != nil {
+		log.Printf("job %s: save failed: %v", job.ID, err)
+	}
+}
+
+/* =========================
+   Library Target
+   ========================= */
+
+// pushToLibrary creates the target library and adds every matched track to it,
+// streaming per-track failures. It returns the summary for the complete event.
+func pushToLibrary(client *dab.Client, target *ConversionTarget, sourceName string, results []models.MatchResult, send func(any)) map[string]any {
+	var tracks []dab.DabTrack
+	seen := make(map[int]bool)
+
+	for _, res := range results {
+		t, ok := dab.TrackFromResult(res)
+		if !ok || seen[t.ID] {
+			continue
+		}
+		seen[t.ID] = true
+		tracks = append(tracks, t)
+	}
+
+	summary := map[string]any{"name": target.LibraryName}
+
+	if len(tracks) == 0 {
+		summary["error"] = "no matched tracks"
+		return summary
+	}
+
+	send(map[string]string{
+		"status":  "library",
+		"message": "Creating library " + target.LibraryName,
+	})
+
+	lib, err := client.CreateLibrary(target.LibraryName, "Imported from "+sourceName, false)
+	if err != nil {
+		send(map[string]string{
+			"status":  "library_error",
+			"message": err.Error(),
+		})
+		summary["error"] = err.Error()
+		return summary
+	}
+
+	added, failed := client.AddTracks(string(lib.ID), tracks, func(r dab.LibraryAddResult) {
+		if r.Error != "" {
+			send(map[string]any{
+				"status": "library_error",
+				"track":  r,
+			})
+		}
+	})
+
+	summary["id"] = lib.ID
+	summary["added"] = added
+	summary["failed"] = failed
+	return summary
+}
+
+/* =========================
+   HTTP Helpers
+   ========================= */
+
+// writeJSON writes v as a JSON response with the given status.
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// authenticate validates X-DAB-Token and returns the DAB client and user ID.
+func authenticate(r *http.Request, debugMode bool) (*dab.Client, string, error) {
+	token := r.Header.Get("X-DAB-Token")
+	if token == "" {
+		return nil, "", errors.New("Missing X-DAB-Token")
+	}
+
+	client := dab.GetClient(token, debugMode)
+	userID, err := client.ValidateToken()
+	if err != nil {
+		return nil, "", errors.New("Auth failed: " + err.Error())
+	}
+	return client, userID, nil
 }
 
 /* =========================
@@ -81,11 +197,43 @@ func sendEvent(w http.ResponseWriter, flusher http.Flusher, payload any) {
 	flusher.Flush()
 }
 
+/* =========================
+   Extraction
+   ========================= */
+
+// extractTracks resolves the source of a request, by type or by URL detection when
+// reqType is empty, and pulls its tracks. Request problems come back as *parser.InputError.
+func extractTracks(ctx context.Context, sources *parser.Registry, reqType string, req parser.Request) (parser.Source, *parser.Result, error) {
+	src, err := sources.Resolve(reqType, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	res, err := src.Extract(ctx, req)
+	if err != nil {
+		return src, nil, err
+	}
+	return src, res, nil
+}
+
+// openSource is extractTracks for conversions: streaming sources hand tracks
+// over while they are still reading.
+func openSource(ctx context.Context, sources *parser.Registry, reqType string, req parser.Request) (parser.Source, *parser.Stream, error) {
+	src, err := sources.Resolve(reqType, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	stream, err := parser.OpenStream(ctx, src, req)
+	if err != nil {
+		return src, nil, err
+	}
+	return src, stream, nil
+}
+
 /* =========================
    Handler
    ========================= */
 
-func handleConvert(db *sql.DB, sp *parser.SpotifyParser, debugMode bool, w http.ResponseWriter, r *http.Request) {
+func handleConvert(db *sql.DB, sources *parser.Registry, hooks *webhook.Sender, uploadLimit int64, debugMode bool, w http.ResponseWriter, r *http.Request) {
 	/* =========================
 	   CORS Preflight
 	   ========================= */
@@ -108,156 +256,208 @@ func handleConvert(db *sql.DB, sp *parser.SpotifyParser, debugMode bool, w http.
 	   Auth (NO SSE YET)
 	   ========================= */
 
-	token := r.Header.Get("X-DAB-Token")
-	if token == "" {
-		earlyFail("Missing X-DAB-Token", http.StatusUnauthorized)
-		return
-	}
-
-	client := dab.GetClient(token, debugMode)
-
-	userID, err := client.ValidateToken()
+	client, userID, err := authenticate(r, debugMode)
 	if err != nil {
-		earlyFail("Auth failed: "+err.Error(), http.StatusUnauthorized)
+		earlyFail(err.Error(), http.StatusUnauthorized)
 		return
 	}
 
+	jobID := newJobID()
+
 	/* =========================
 	   Parse Request (NO SSE)
 	   ========================= */
 
 	var (
-		tracks     []models.Track
 		results    []models.MatchResult
 		sourceName string
+		sourceCols []string
 		reqType    string
 		matchMode  string
-	)
+		target     *ConversionTarget
+		tracker    *matcher.Tracker
 
-	contentType := r.Header.Get("Content-Type")
+		callbackURL string
+	)
 
-	// ---------- CSV (multipart) ----------
-	if strings.HasPrefix(contentType, "multipart/form-data") {
-		if err := r.ParseMultipartForm(32 << 20); err != nil {
-			earlyFail("Invalid multipart form", http.StatusBadRequest)
-			return
+	summary := func() matcher.Summary {
+		if tracker != nil {
+			return tracker.Summary()
 		}
+		return matcher.Summarize(results)
+	}
 
-		reqType = r.FormValue("type")
-		matchMode = r.FormValue("matching_mode")
+	// finishJob persists the job and, if the caller asked for one, fires the completion webhook
+	finishJob := func(status, errMsg string) {
+		saveJob(db, database.Job{
+			ID:         jobID,
+			UserID:     userID,
+			SourceType: reqType,
+			SourceName: sourceName,
+			Status:     status,
+			Results:    results,
+
+			SourceColumns: sourceCols,
+		})
 
-		if reqType != "csv" {
-			earlyFail("multipart only supported for type=csv", http.StatusBadRequest)
+		if callbackURL == "" {
 			return
 		}
 
-		// NOTE: CSV parsing does matching internally
-		// SSE will be initialized JUST BEFORE calling it
+		event := webhook.EventCompleted
+		if status != "complete" {
+			event = webhook.EventFailed
+		}
+		payload := webhook.Payload{
+			Event:      event,
+			JobID:      jobID,
+			UserID:     userID,
+			SourceType: reqType,
+			SourceName: sourceName,
+			Status:     status,
+			Error:      errMsg,
+			Timestamp:  time.Now().Format(time.RFC3339),
+			Summary:    summary(),
+			Tracks:     results,
+		}
+		go func() {
+			if err := hooks.Deliver(context.Background(), callbackURL, payload); err != nil {
+				log.Printf("job %s: %v", jobID, err)
+			}
+		}()
+	}
 
-		// defer SSE setup until just before ParseCSV
+	checkCallback := func() bool {
+		if callbackURL == "" {
+			return true
+		}
+		if hooks == nil {
+			earlyFail("callback_url requires WEBHOOK_SECRET to be configured", http.StatusBadRequest)
+			return false
+		}
+		if err := webhook.ValidateURL(r.Context(), callbackURL); err != nil {
+			earlyFail(err.Error(), http.StatusBadRequest)
+			return false
+		}
+		return true
+	}
 
-		/* =========================
-		   SSE Setup (SAFE POINT)
-		   ========================= */
+	var sourceReq parser.Request
+	contentType := r.Header.Get("Content-Type")
 
-		flusher, err := setupSSE(w)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+	if strings.HasPrefix(contentType, "multipart/form-data") {
+		// ---------- File upload (CSV) ----------
+		// Uploads beyond a few MB are spooled to disk rather than held in memory;
+		// the form overhead gets some slack on top of the file limit
+		if uploadLimit > 0 {
+			r.Body = http.MaxBytesReader(w, r.Body, uploadLimit+1<<20)
+		}
+		if err := r.ParseMultipartForm(8 << 20); err != nil {
+			var tooBig *http.MaxBytesError
+			if errors.As(err, &tooBig) {
+				earlyFail(fmt.Sprintf("Upload exceeds the %d MB limit", uploadLimit>>20), http.StatusRequestEntityTooLarge)
+				return
+			}
+			earlyFail("Invalid multipart form", http.StatusBadRequest)
 			return
 		}
 
-		send := func(v any) { sendEvent(w, flusher, v) }
-
-		send(map[string]string{
-			"status":  "info",
-			"message": "Authenticated",
-		})
-
-		onProgress := func(index, total int, res *models.MatchResult) {
-			send(map[string]any{
-				"status": "processing",
-				"index":  index,
-				"total":  total,
-				"result": res,
-			})
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			earlyFail("Missing file", http.StatusBadRequest)
+			return
 		}
+		defer file.Close()
 
-		results, sourceName, err = parser.ParseCSV(
-			r,
-			db,
-			client,
-			matchMode,
-            debugMode,
-			onProgress,
-		)
-		if err != nil {
-			send(map[string]string{
-				"status":  "error",
-				"message": "CSV parse failed: " + err.Error(),
-			})
+		reqType = r.FormValue("type")
+		matchMode = r.FormValue("matching_mode")
+		if name := strings.TrimSpace(r.FormValue("library_name")); name != "" {
+			target = &ConversionTarget{LibraryName: name}
+		}
+		callbackURL = strings.TrimSpace(r.FormValue("callback_url"))
+
+		sourceReq = parser.Request{
+			UserID: userID,
+			Upload: &parser.Upload{
+				Filename: header.Filename,
+				Body:     file,
+				Size:     header.Size,
+				Profile:  strings.TrimSpace(r.FormValue("csv_profile")),
+			},
+		}
+	} else {
+		// ---------- JSON (URL sources) ----------
+		var req ConversionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			earlyFail("Invalid JSON body", http.StatusBadRequest)
 			return
 		}
 
-		/* ---------- Final ---------- */
+		reqType = req.Type
+		matchMode = req.MatchingMode
+		target = req.Target
+		callbackURL = strings.TrimSpace(req.CallbackURL)
 
-		send(map[string]any{
-			"status": "complete",
-			"meta": map[string]any{
-				"user_id":     userID,
-				"source_name": sourceName,
-				"timestamp":   time.Now().Format(time.RFC3339),
-			},
-			"tracks": results,
-		})
-		return
-	}
+		if target != nil {
+			target.LibraryName = strings.TrimSpace(target.LibraryName)
+			if target.LibraryName == "" {
+				earlyFail("target.library_name is required", http.StatusBadRequest)
+				return
+			}
+		}
 
-	// ---------- JSON (Spotify / YouTube) ----------
+		sourceReq = parser.Request{
+			URL:         strings.TrimSpace(req.URL),
+			UserID:      userID,
+			Discography: req.Discography,
+		}
+	}
 
-	var req ConversionRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		earlyFail("Invalid JSON body", http.StatusBadRequest)
+	if !checkCallback() {
 		return
 	}
 
-	reqType = req.Type
-	matchMode = req.MatchingMode
-
-	parsedURL, err := url.Parse(req.URL)
-	if err != nil || parsedURL.Host == "" {
-		earlyFail("Invalid URL", http.StatusBadRequest)
-		return
+	// With a callback the caller may hang up; the job has to run to completion regardless
+	jobCtx := ctx
+	if callbackURL != "" {
+		jobCtx = context.WithoutCancel(ctx)
 	}
 
-	switch reqType {
-	case "spotify":
-		if !strings.Contains(parsedURL.Host, "spotify.com") &&
-			!strings.Contains(parsedURL.Host, "googleusercontent.com") {
-			earlyFail("Invalid Spotify URL", http.StatusBadRequest)
-			return
-		}
-		tracks, sourceName, err = sp.Parse(ctx, req.URL) 
+	// Stop a streaming source's reader when the job ends early
+	streamCtx, stopStream := context.WithCancel(jobCtx)
+	defer stopStream()
 
-	case "youtube":
-		if !strings.Contains(parsedURL.Host, "youtube.com") &&
-			!strings.Contains(parsedURL.Host, "youtu.be") {
-			earlyFail("Invalid YouTube URL", http.StatusBadRequest)
-			return
-		}
-		tracks, sourceName, err = parser.ParseYouTube(req.URL)
+	src, stream, err := openSource(streamCtx, sources, reqType, sourceReq)
 
-	default:
-		earlyFail("Unsupported source type", http.StatusBadRequest)
+	var inErr *parser.InputError
+	if errors.As(err, &inErr) {
+		earlyFail(inErr.Msg, http.StatusBadRequest)
 		return
 	}
-
 	if err != nil {
+		if src != nil {
+			reqType = src.Name()
+		}
 		earlyFail("Extraction failed: "+err.Error(), http.StatusInternalServerError)
+		finishJob("failed", "Extraction failed: "+err.Error())
 		return
 	}
+	reqType = src.Name()
+	sourceName = stream.Name
+	sourceCols = stream.Columns
 
-	if len(tracks) == 0 {
-		earlyFail("No tracks found", http.StatusBadRequest)
+	// Wait for the first track before committing to SSE, so an unusable source
+	// still gets a plain HTTP error
+	first, ok := <-stream.Tracks
+	if !ok {
+		msg, code := "No tracks found", http.StatusBadRequest
+		if err := stream.Err(); err != nil {
+			msg, code = "Extraction failed: "+err.Error(), http.StatusInternalServerError
+		} else if warnings := stream.Warnings(); len(warnings) > 0 {
+			msg += fmt.Sprintf(" (%d rows skipped; row %d: %s)", len(warnings), warnings[0].Row, warnings[0].Message)
+		}
+		earlyFail(msg, code)
+		finishJob("failed", msg)
 		return
 	}
 
@@ -273,56 +473,117 @@ func handleConvert(db *sql.DB, sp *parser.SpotifyParser, debugMode bool, w http.
 
 	send := func(v any) { sendEvent(w, flusher, v) }
 
-	send(map[string]string{
+	send(map[string]any{
 		"status":  "extracting",
 		"message": "Parsing " + reqType,
+		"total":   stream.Total,
 	})
 
 	/* =========================
 	   Matching
 	   ========================= */
 
-	results = make([]models.MatchResult, 0, len(tracks))
+	// Tracks are matched as the source yields them; for large uploads the first
+	// results are streamed while the file is still being read
+	results = make([]models.MatchResult, 0, stream.Total)
+	tracker = matcher.NewTracker(stream.Total, client.Pace())
 
-	for i, t := range tracks {
+	for t := first; ok; t, ok = <-stream.Tracks {
 		select {
-		case <-ctx.Done():
+		case <-jobCtx.Done():
 			log.Println("Client disconnected")
+			finishJob("failed", "Client disconnected")
 			return
 		default:
 		}
 
-		res := matcher.MatchTrack(db, client, t, matchMode, debugMode)
+		res := matcher.MatchTrack(jobCtx, db, client, t, matchMode, debugMode)
 		results = append(results, *res)
 
+		// The pre-count is an estimate; never report fewer than already seen
+		total := stream.Total
+		if len(results) > total {
+			total = len(results)
+			tracker.SetTotal(total)
+		}
+
 		send(map[string]any{
 			"status": "processing",
-			"index":  i + 1,
-			"total":  len(tracks),
+			"index":  len(results),
+			"total":  total,
 			"result": res,
+			"stats":  tracker.Add(res),
+		})
+	}
+
+	if err := stream.Err(); err != nil {
+		send(map[string]string{
+			"status":  "error",
+			"message": "Extraction failed: " + err.Error(),
 		})
+		finishJob("failed", "Extraction failed: "+err.Error())
+		return
 	}
+	tracker.SetTotal(len(results))
 
 	/* =========================
 	   Final
 	   ========================= */
 
-	send(map[string]any{
-		"status": "complete",
-		"meta": map[string]any{
-			"user_id":     userID,
-			"source_name": sourceName,
-			"timestamp":   time.Now().Format(time.RFC3339),
-		},
-		"tracks": results,
-	})
+	meta := map[string]any{
+		"job_id":      jobID,
+		"user_id":     userID,
+		"source_type": reqType,
+		"source_name": sourceName,
+		"timestamp":   time.Now().Format(time.RFC3339),
+	}
+	if len(stream.Meta) > 0 {
+		meta["source"] = stream.Meta
+	}
+	if target != nil {
+		meta["library"] = pushToLibrary(client, target, sourceName, results, send)
+	}
+
+	finishJob("complete", "")
+
+	complete := map[string]any{
+		"status":  "complete",
+		"meta":    meta,
+		"summary": summary(),
+		"tracks":  results,
+	}
+	if warnings := stream.Warnings(); len(warnings) > 0 {
+		complete["warnings"] = warnings
+	}
+	send(complete)
 }
 
 /* =========================
    Main
    ========================= */
 
+const dbPath = "./data/registry.db"
+
+func openDatabase() (*sql.DB, error) {
+	_ = os.MkdirAll(filepath.Dir(dbPath), 0755)
+	db, err := sql.Open("sqlite3", dbPath+"?_journal_mode=WAL&_synchronous=NORMAL")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to connect to DB: %w", err)
+	}
+
+	if err := database.InitDatabase(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("Failed to init DB schema: %w", err)
+	}
+	return db, nil
+}
+
 func main() {
+	// Subcommands run offline against the local registry and need no API credentials
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		os.Exit(runExportCommand(os.Args[2:]))
+	}
+
 	// 1. Validate Environment Variables (Fail fast)
 	spotifyID := os.Getenv("SPOTIFY_ID")
 	spotifySecret := os.Getenv("SPOTIFY_SECRET")
@@ -344,16 +605,28 @@ func main() {
     }
 
 	// 2. Database Setup
-	dbPath := "./data/registry.db"
-	_ = os.MkdirAll(filepath.Dir(dbPath), 0755)
-	db, err := sql.Open("sqlite3", dbPath+"?_journal_mode=WAL&_synchronous=NORMAL")
+	db, err := openDatabase()
 	if err != nil {
-		log.Fatalf("Failed to connect to DB: %v", err)
+		log.Fatal(err)
 	}
 	defer db.Close()
 
-	if err := database.InitDatabase(db); err != nil {
-		log.Fatalf("Failed to init DB schema: %v", err)
+	// DAB tokens kept for scheduled syncs are encrypted at rest when a key is set
+	if raw := os.Getenv("TOKEN_ENCRYPTION_KEY"); raw != "" {
+		key, err := base64.StdEncoding.DecodeString(raw)
+		if err == nil {
+			err = database.SetEncryptionKey(key)
+		}
+		if err != nil {
+			log.Fatal("CRITICAL: TOKEN_ENCRYPTION_KEY must be 32 bytes, base64-encoded: ", err)
+		}
+		if n, err := database.SealStoredTokens(db); err != nil {
+			log.Fatal("CRITICAL: encrypt stored tokens: ", err)
+		} else if n > 0 {
+			log.Printf("Encrypted %d stored tokens", n)
+		}
+	} else {
+		log.Println("WARNING: TOKEN_ENCRYPTION_KEY is not set; sync DAB tokens and Spotify account tokens are stored in plaintext")
 	}
 
 	// 3. Initialize Long-Lived Spotify Client
@@ -367,7 +640,61 @@ func main() {
 	spotifyClient := spotify.New(httpClient)
 
 	// 4. Initialize Parsers
-    spotifyParser := parser.NewSpotifyParser(spotifyClient, debugMode)
+    spotifyParser := parser.NewSpotifyParser(spotifyClient, db, debugMode)
+
+	// YouTube tracks MusicBrainz has no ISRC for are looked up on Spotify
+	matcher.SetSpotifyISRCResolver(spotifyParser.LookupISRC)
+
+	// Account linking (liked songs, saved albums, private playlists) needs a
+	// redirect URL registered with the Spotify app
+	var accounts *spotifyoauth.Accounts
+	if redirectURL := os.Getenv("SPOTIFY_REDIRECT_URL"); redirectURL != "" {
+		accounts = spotifyoauth.New(db, spotifyID, spotifySecret, redirectURL, debugMode)
+	}
+
+	// CSV uploads are streamed row by row; the limits only guard against abuse
+	csvSource := parser.CSVSource{MaxRows: 100000, MaxBytes: 100 << 20}
+	if n, err := strconv.Atoi(os.Getenv("CSV_MAX_ROWS")); err == nil && n >= 0 {
+		csvSource.MaxRows = n
+	}
+	if n, err := strconv.Atoi(os.Getenv("CSV_MAX_MB")); err == nil && n >= 0 {
+		csvSource.MaxBytes = int64(n) << 20
+	}
+
+	// YouTube title parsing can be tuned without a rebuild
+	if path := os.Getenv("YT_TITLE_RULES"); path != "" {
+		rules, err := parser.LoadTitleRules(path)
+		if err == nil {
+			err = parser.SetTitleRules(rules)
+		}
+		if err != nil {
+			log.Fatal("CRITICAL: ", err)
+		}
+	}
+
+	// Sources are detected in this order when a request names no type
+	sources := parser.NewRegistry(parser.NewSpotifyLibrarySources(spotifyParser, accounts)...)
+	sources.Register(parser.NewSpotifySource(spotifyParser, accounts))
+	sources.Register(parser.YouTubeSource{})
+	sources.Register(parser.AppleMusicSource{})
+	sources.Register(parser.NewDeezerSource())
+	// Tidal's public API needs the web player's client token
+	if token := os.Getenv("TIDAL_TOKEN"); token != "" {
+		sources.Register(parser.NewTidalSource(token, os.Getenv("TIDAL_COUNTRY")))
+	}
+	sources.Register(parser.NewSoundCloudSource(os.Getenv("SOUNDCLOUD_CLIENT_ID")))
+	sources.Register(parser.NewBandcampSource(os.Getenv("BANDCAMP_CUSTOM_DOMAINS")))
+	sources.Register(parser.NewListenBrainzSource())
+	if key := os.Getenv("LASTFM_API_KEY"); key != "" {
+		sources.Register(parser.NewLastFMSource(key))
+	}
+	sources.Register(csvSource)
+
+	// Completion webhooks are only offered when a signing secret is configured
+	var hooks *webhook.Sender
+	if secret := os.Getenv("WEBHOOK_SECRET"); secret != "" {
+		hooks = webhook.NewSender(secret, debugMode)
+	}
 
     // 5. Routing
     http.HandleFunc("/api/v1/convert", RecoveryMiddleware(func(w http.ResponseWriter, r *http.Request) {
@@ -376,9 +703,51 @@ func main() {
             return
         }
         // PASS the parser instance here
-        handleConvert(db, spotifyParser, debugMode, w, r) 
+        handleConvert(db, sources, hooks, csvSource.MaxBytes, debugMode, w, r)
     }))
 
+	http.HandleFunc("/api/v1/jobs/{id}/export", RecoveryMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		handleExport(db, debugMode, w, r)
+	}))
+
+	// 6. Playlist Sync
+	syn := &syncer.Syncer{
+		DB:        db,
+		DebugMode: debugMode,
+		Extract: func(ctx context.Context, userID, sourceType, url string) ([]models.Track, string, error) {
+			_, res, err := extractTracks(ctx, sources, sourceType, parser.Request{URL: url, UserID: userID})
+			if err != nil {
+				return nil, "", err
+			}
+			return res.Tracks, res.Name, nil
+		},
+	}
+	syncHandler := RecoveryMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		handleSync(db, syn, sources, debugMode, w, r)
+	})
+	http.HandleFunc("/api/v1/sync", syncHandler)
+	http.HandleFunc("/api/v1/sync/{id}", syncHandler)
+	http.HandleFunc("/api/v1/sync/{id}/run", syncHandler)
+
+	syncPoll := syncer.DefaultPollInterval
+	if m, err := strconv.Atoi(os.Getenv("SYNC_POLL_MINUTES")); err == nil && m > 0 {
+		syncPoll = time.Duration(m) * time.Minute
+	}
+	go syn.Schedule(ctx, syncPoll)
+
+	// 7. Spotify Account Linking
+	if accounts != nil {
+		http.HandleFunc("/api/v1/spotify/login", RecoveryMiddleware(func(w http.ResponseWriter, r *http.Request) {
+			handleSpotifyLogin(accounts, debugMode, w, r)
+		}))
+		http.HandleFunc("GET /api/v1/spotify/callback", RecoveryMiddleware(func(w http.ResponseWriter, r *http.Request) {
+			handleSpotifyCallback(accounts, w, r)
+		}))
+		http.HandleFunc("/api/v1/spotify/account", RecoveryMiddleware(func(w http.ResponseWriter, r *http.Request) {
+			handleSpotifyAccount(accounts, debugMode, w, r)
+		}))
+	}
+
 
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+
+	"dbh-go-srv/internal/spotifyoauth"
+)
+
+/* =========================
+   Spotify Account Handlers
+   ========================= */
+
+func spotifyCORS(w http.ResponseWriter, r *http.Request, methods string) bool {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if r.Method != http.MethodOptions {
+		return false
+	}
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-DAB-Token")
+	w.Header().Set("Access-Control-Allow-Methods", methods+", OPTIONS")
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
+
+// handleSpotifyLogin starts linking a Spotify account to the caller. The consent
+// URL is returned rather than redirected to, since a browser navigation cannot
+// carry the X-DAB-Token header.
+func handleSpotifyLogin(accounts *spotifyoauth.Accounts, debugMode bool, w http.ResponseWriter, r *http.Request) {
+	if spotifyCORS(w, r, "GET") {
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	_, userID, err := authenticate(r, debugMode)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	authURL, err := accounts.AuthURL(userID)
+	if err != nil {
+		http.Error(w, "Login failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"auth_url": authURL})
+}
+
+// handleSpotifyCallback is the redirect target registered with Spotify. When
+// SPOTIFY_OAUTH_RETURN_URL is set the browser is sent back there with
+// spotify=connected or spotify=error&message=...
+func handleSpotifyCallback(accounts *spotifyoauth.Accounts, w http.ResponseWriter, r *http.Request) {
+	_, err := accounts.Complete(r.Context(), r)
+
+	if ret := os.Getenv("SPOTIFY_OAUTH_RETURN_URL"); ret != "" {
+		if u, perr := url.Parse(ret); perr == nil {
+			q := u.Query()
+			if err != nil {
+				q.Set("spotify", "error")
+				q.Set("message", err.Error())
+			} else {
+				q.Set("spotify", "connected")
+			}
+			u.RawQuery = q.Encode()
+			http.Redirect(w, r, u.String(), http.StatusFound)
+			return
+		}
+	}
+
+	if errors.Is(err, spotifyoauth.ErrInvalidState) {
+		http.Error(w, "Login expired, please start again", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Spotify login failed: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte("Spotify account linked. You can close this window.\n"))
+}
+
+// handleSpotifyAccount reports (GET) or removes (DELETE) the caller's linked account.
+func handleSpotifyAccount(accounts *spotifyoauth.Accounts, debugMode bool, w http.ResponseWriter, r *http.Request) {
+	if spotifyCORS(w, r, "GET, DELETE") {
+		return
+	}
+
+	_, userID, err := authenticate(r, debugMode)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		linked, err := accounts.Linked(userID)
+		if errors.Is(err, spotifyoauth.ErrNotConnected) {
+			writeJSON(w, http.StatusOK, map[string]any{"connected": false})
+			return
+		}
+		if err != nil {
+			http.Error(w, "Lookup failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"connected":       true,
+			"spotify_user_id": linked.SpotifyUserID,
+			"display_name":    linked.DisplayName,
+			"scope":           linked.Scope,
+			"linked_at":       linked.UpdatedAt,
+		})
+
+	case http.MethodDelete:
+		if err := accounts.Disconnect(userID); err != nil {
+			http.Error(w, "Disconnect failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
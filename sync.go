@@ -0,0 +1,196 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"dbh-go-srv/internal/database"
+	"dbh-go-srv/internal/parser"
+	"dbh-go-srv/internal/syncer"
+)
+
+/* =========================
+   Sync Types
+   ========================= */
+
+type SyncRequest struct {
+	URL           string `json:"url"`
+	Type          string `json:"type"`
+	MatchingMode  string `json:"matching_mode"`
+	LibraryName   string `json:"library_name"`
+	IntervalHours int    `json:"interval_hours"`
+}
+
+// syncView is the public shape of a sync; it never includes the stored DAB token.
+type syncView struct {
+	ID            string     `json:"sync_id"`
+	SourceType    string     `json:"type"`
+	SourceURL     string     `json:"url"`
+	SourceName    string     `json:"source_name,omitempty"`
+	LibraryID     string     `json:"library_id,omitempty"`
+	LibraryName   string     `json:"library_name,omitempty"`
+	MatchingMode  string     `json:"matching_mode,omitempty"`
+	IntervalHours int        `json:"interval_hours"`
+	LastSyncedAt  *time.Time `json:"last_synced_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	FailedRuns    int        `json:"failed_runs,omitempty"`
+	NextRunAt     *time.Time `json:"next_run_at,omitempty"`
+}
+
+func newSyncView(s database.Sync) syncView {
+	v := syncView{
+		ID:            s.ID,
+		SourceType:    s.SourceType,
+		SourceURL:     s.SourceURL,
+		SourceName:    s.SourceName,
+		LibraryID:     s.LibraryID,
+		LibraryName:   s.LibraryName,
+		MatchingMode:  s.MatchingMode,
+		IntervalHours: s.IntervalHours,
+		LastError:     s.LastError,
+		FailedRuns:    s.FailedRuns,
+	}
+	if !s.LastSyncedAt.IsZero() {
+		t := s.LastSyncedAt
+		v.LastSyncedAt = &t
+	}
+	if next := s.NextRun(); !next.IsZero() {
+		v.NextRunAt = &next
+	}
+	return v
+}
+
+const defaultSyncIntervalHours = 24 * 7
+
+/* =========================
+   Sync Handlers
+   ========================= */
+
+// handleSync serves /api/v1/sync and /api/v1/sync/{id}[/run]:
+//
+//	POST   /api/v1/sync          register (or update) a sync and run it now
+//	GET    /api/v1/sync          list the caller's syncs
+//	POST   /api/v1/sync/{id}/run run a sync now
+//	DELETE /api/v1/sync/{id}     forget a sync (the DAB library is kept)
+func handleSync(db *sql.DB, syn *syncer.Syncer, sources *parser.Registry, debugMode bool, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-DAB-Token")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	client, userID, err := authenticate(r, debugMode)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	id := r.PathValue("id")
+
+	switch {
+	case id == "" && r.Method == http.MethodGet:
+		syncs, err := database.ListSyncs(db, userID)
+		if err != nil {
+			http.Error(w, "List failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		views := make([]syncView, 0, len(syncs))
+		for _, s := range syncs {
+			views = append(views, newSyncView(s))
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"syncs": views})
+
+	case id == "" && r.Method == http.MethodPost:
+		var req SyncRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		req.URL = strings.TrimSpace(req.URL)
+		if req.URL == "" {
+			http.Error(w, "Invalid URL", http.StatusBadRequest)
+			return
+		}
+
+		// A sync re-reads its URL on every run, so the source must recognize it
+		src, err := sources.Resolve(req.Type, parser.Request{URL: req.URL})
+		if err == nil && !src.Detect(parser.Request{URL: req.URL}) {
+			err = &parser.InputError{Msg: "URL is not a " + src.Name() + " source"}
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.Type = src.Name()
+		if req.IntervalHours <= 0 {
+			req.IntervalHours = defaultSyncIntervalHours
+		}
+
+		syncID, err := database.UpsertSync(db, database.Sync{
+			ID:            newJobID(),
+			UserID:        userID,
+			DabToken:      client.Token,
+			SourceType:    req.Type,
+			SourceURL:     req.URL,
+			LibraryName:   strings.TrimSpace(req.LibraryName),
+			MatchingMode:  req.MatchingMode,
+			IntervalHours: req.IntervalHours,
+		})
+		if err != nil {
+			http.Error(w, "Sync registration failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		runSync(syn, syncID, w, r)
+
+	case id != "":
+		st, err := database.GetSync(db, id)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && st.UserID != userID) {
+			http.Error(w, "Sync not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Sync lookup failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		switch {
+		case r.Method == http.MethodDelete && !strings.HasSuffix(r.URL.Path, "/run"):
+			if err := database.DeleteSync(db, id); err != nil {
+				http.Error(w, "Delete failed: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/run"):
+			runSync(syn, id, w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func runSync(syn *syncer.Syncer, syncID string, w http.ResponseWriter, r *http.Request) {
+	res, err := syn.Run(r.Context(), syncID)
+	if errors.Is(err, syncer.ErrSyncRunning) {
+		http.Error(w, "Sync already running", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]any{
+			"sync_id": syncID,
+			"error":   err.Error(),
+			"result":  res,
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}